@@ -12,8 +12,11 @@ import (
 	"time"
 
 	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 	"github.com/allanpk716/ai-commit-hub/pkg/git"
 	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
 	appversion "github.com/allanpk716/ai-commit-hub/pkg/version"
 	"github.com/allanpk716/ai-commit-hub/pkg/pushover"
 	"github.com/allanpk716/ai-commit-hub/pkg/repository"
@@ -27,6 +30,7 @@ import (
 
 	// Provider 注册 - 匿名导入以触发 init()
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/anthropic"
+	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/azure"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/deepseek"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/google"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/ollama"
@@ -57,12 +61,15 @@ type App struct {
 	gitProjectRepo       *repository.GitProjectRepository
 	commitHistoryRepo    *repository.CommitHistoryRepository
 	configService        *service.ConfigService
+	providerService      *service.ProviderService
 	projectConfigService *service.ProjectConfigService
+	commitService        *service.CommitService
 	pushoverService      *pushover.Service
 	errorService         *service.ErrorService
 	updateService        *service.UpdateService
 	installer            *update.Installer
 	windowStateRepo      *repository.WindowStateRepository
+	auditLogRepo         *repository.AuditLogRepository
 	initError            error
 	// 系统托盘相关字段
 	systrayReady   chan struct{} // systray 就绪信号
@@ -88,6 +95,9 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	logger.Info("AI Commit Hub starting up...")
 
+	// CommitService 持有进行中的生成会话，需在整个应用生命周期内复用
+	a.commitService = service.NewCommitService(ctx)
+
 	// 窗口在启动时就是可见的（位置和大小已在 main.go 中设置）
 	a.windowVisible = true
 
@@ -118,6 +128,10 @@ func (a *App) startup(ctx context.Context) {
 	a.gitProjectRepo = repository.NewGitProjectRepository()
 	a.commitHistoryRepo = repository.NewCommitHistoryRepository()
 	a.windowStateRepo = repository.NewWindowStateRepository()
+	a.commitService.SetCache(repository.NewGenerationCacheRepository())
+	a.commitService.SetKeyHealthStore(repository.NewAPIKeyHealthRepository())
+	a.auditLogRepo = repository.NewAuditLogRepository()
+	a.commitService.SetAuditStore(a.auditLogRepo)
 
 	// Initialize config service and ensure default config exists
 	a.configService = service.NewConfigService()
@@ -126,6 +140,13 @@ func (a *App) startup(ctx context.Context) {
 		// Continue anyway - config will be created when needed
 	}
 
+	// 将 config.yaml 中的明文 API Key 移入加密存储
+	if _, err := a.configService.MigratePlaintextAPIKeys(); err != nil {
+		logger.Warnf("API Key 迁移失败，仍使用配置文件中的明文 key: %v", err)
+	}
+
+	a.providerService = service.NewProviderService()
+
 	// Initialize project config service
 	cfg, _ := a.configService.LoadConfig(ctx)
 	a.projectConfigService = service.NewProjectConfigService(a.gitProjectRepo, cfg)
@@ -1244,25 +1265,62 @@ func (a *App) GetProjectStatus(projectPath string) (map[string]interface{}, erro
 }
 
 // GenerateCommit generates a commit message using AI
-func (a *App) GenerateCommit(projectPath, provider, language string) error {
-	logger.Info("App.GenerateCommit 被调用")
-	logger.Infof("参数 - projectPath: %s, provider: %s, language: %s", projectPath, provider, language)
+// 返回本次生成的会话 ID，前端据此过滤 commit-* 事件
+func (a *App) GenerateCommit(projectPath, provider, language string) (string, error) {
+	return a.GenerateCommitWithOptions(projectPath, service.GenerateOptions{
+		Provider: provider,
+		Language: language,
+	})
+}
+
+// GenerateCommitWithOptions 按选项生成 commit 消息（如多条候选消息）
+// opts.FallbackProviders 为 nil 时使用项目级回退链
+func (a *App) GenerateCommitWithOptions(projectPath string, opts service.GenerateOptions) (string, error) {
+	logger.Info("App.GenerateCommitWithOptions 被调用")
+	logger.Infof("参数 - projectPath: %s, provider: %s, language: %s, candidates: %d", projectPath, opts.Provider, opts.Language, opts.Candidates)
 
 	if a.initError != nil {
 		errMsg := fmt.Sprintf("应用未正确初始化: %v", a.initError)
 		logger.Error(errMsg)
-		return a.initError
+		return "", a.initError
 	}
 
-	commitService := service.NewCommitService(a.ctx)
-	logger.Info("CommitService 创建成功，开始生成...")
-	err := commitService.GenerateCommit(projectPath, provider, language)
+	// 项目级回退链和生成参数（未配置时由 CommitService 使用全局配置）
+	if opts.FallbackProviders == nil || opts.Params == nil {
+		if project, err := a.gitProjectRepo.GetByPath(projectPath); err == nil {
+			if aiConfig, err := a.projectConfigService.GetProjectAIConfig(project.ID); err == nil {
+				if opts.FallbackProviders == nil {
+					opts.FallbackProviders = aiConfig.FallbackProviders
+				}
+				if opts.Params == nil {
+					opts.Params = aiConfig.GenerationParams
+				}
+			}
+		}
+	}
+
+	logger.Info("开始生成...")
+	sessionID, err := a.commitService.GenerateCommitWithOptions(projectPath, opts)
 	if err != nil {
-		logger.Errorf("CommitService.GenerateCommit 返回错误: %v", err)
+		logger.Errorf("CommitService.GenerateCommitWithOptions 返回错误: %v", err)
 	} else {
-		logger.Info("CommitService.GenerateCommit 执行完成（已启动异步生成）")
+		logger.Infof("CommitService.GenerateCommitWithOptions 执行完成（已启动异步生成，会话: %s）", sessionID)
 	}
-	return err
+	return sessionID, err
+}
+
+// CancelGeneration 取消项目正在进行的 commit 消息生成
+// 取消后前端会收到 commit-cancelled 事件，不再收到该次生成的 commit-delta
+func (a *App) CancelGeneration(projectPath string) error {
+	logger.Infof("App.CancelGeneration 被调用 - projectPath: %s", projectPath)
+
+	if a.initError != nil {
+		return a.initError
+	}
+
+	// 生成可能刚好已结束，此时无需处理
+	a.commitService.CancelGeneration(projectPath)
+	return nil
 }
 
 // CommitLocally commits changes to local git repository
@@ -1280,25 +1338,10 @@ func (a *App) CommitLocally(projectPath, message string) error {
 		return err
 	}
 
-	// Save current directory and change to project path
-	originalDir, err := os.Getwd()
-	if err != nil {
-		err := fmt.Errorf("failed to get current directory: %w", err)
-		logger.Errorf("获取当前目录失败: %v", err)
-		return err
-	}
-
-	if err := os.Chdir(projectPath); err != nil {
-		err := fmt.Errorf("failed to change directory: %w", err)
-		logger.Errorf("切换到项目目录失败: %v", err)
-		return err
-	}
-	defer os.Chdir(originalDir)
-
 	logger.Infof("准备提交 - 目录: %s", projectPath)
 
 	// Use the existing CommitChanges function from git package
-	if err := git.CommitChanges(context.Background(), message); err != nil {
+	if err := git.CommitChanges(context.Background(), projectPath, message); err != nil {
 		logger.Errorf("CommitChanges 失败: %v", err)
 		return err
 	}
@@ -1317,15 +1360,27 @@ func (a *App) CommitLocally(projectPath, message string) error {
 
 // SaveCommitHistory saves a generated commit message to history
 func (a *App) SaveCommitHistory(projectID uint, message, provider, language string) error {
+	return a.SaveCommitHistoryWithCandidates(projectID, message, provider, language, nil, nil)
+}
+
+// SaveCommitHistoryWithCandidates 保存选中的 commit 消息、未被选中的候选消息以及本次生成的 token 用量
+// usage 为 nil 表示 provider 未返回用量
+func (a *App) SaveCommitHistoryWithCandidates(projectID uint, message, provider, language string, rejectedCandidates []string, usage *ai.Usage) error {
 	if a.initError != nil {
 		return a.initError
 	}
 
 	history := &models.CommitHistory{
-		ProjectID: projectID,
-		Message:   message,
-		Provider:  provider,
-		Language:  language,
+		ProjectID:          projectID,
+		Message:            message,
+		Provider:           provider,
+		Language:           language,
+		RejectedCandidates: rejectedCandidates,
+	}
+	if usage != nil {
+		history.Model = usage.Model
+		history.PromptTokens = usage.PromptTokens
+		history.CompletionTokens = usage.CompletionTokens
 	}
 
 	if err := a.commitHistoryRepo.Create(history); err != nil {
@@ -1334,6 +1389,24 @@ func (a *App) SaveCommitHistory(projectID uint, message, provider, language stri
 	return nil
 }
 
+// GetUsageCostSummary 按项目、provider、月份汇总 token 用量，并使用配置中的价格表估算费用
+func (a *App) GetUsageCostSummary() ([]service.UsageCostSummary, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+
+	cfg, err := a.configService.LoadConfig(a.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	histories, err := a.commitHistoryRepo.GetWithUsage()
+	if err != nil {
+		return nil, fmt.Errorf("获取用量记录失败: %w", err)
+	}
+	return service.SummarizeUsage(histories, cfg.Pricing), nil
+}
+
 // GetProjectHistory retrieves commit history for a project
 func (a *App) GetProjectHistory(projectID uint) ([]models.CommitHistory, error) {
 	if a.initError != nil {
@@ -1347,6 +1420,60 @@ func (a *App) GetProjectHistory(projectID uint) ([]models.CommitHistory, error)
 	return histories, nil
 }
 
+// AuditLogPage 是一页审计日志（不包含 prompt 和原始响应）及符合条件的总条数
+type AuditLogPage struct {
+	Entries []models.AuditLog `json:"entries"`
+	Total   int64             `json:"total"`
+}
+
+// GetAuditLogs 按项目、provider 或会话筛选审计日志，按时间倒序分页返回。
+// 审计日志需要在 config.yaml 中设置 audit.enabled: true 才会记录。
+func (a *App) GetAuditLogs(filter repository.AuditLogFilter) (*AuditLogPage, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+
+	entries, total, err := a.auditLogRepo.List(filter)
+	if err != nil {
+		return nil, fmt.Errorf("获取审计日志失败: %w", err)
+	}
+	return &AuditLogPage{Entries: entries, Total: total}, nil
+}
+
+// GetAuditLog 返回一条审计日志的完整内容，包括发送的 prompt 和 provider 的原始响应
+func (a *App) GetAuditLog(id uint) (*models.AuditLog, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+
+	entry, err := a.auditLogRepo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("获取审计日志失败: %w", err)
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("审计日志不存在: %d", id)
+	}
+	return entry, nil
+}
+
+// PurgeAuditLogs 删除 olderThanDays 天之前的审计日志，olderThanDays <= 0 时删除全部。返回删除的条数。
+func (a *App) PurgeAuditLogs(olderThanDays int) (int64, error) {
+	if a.initError != nil {
+		return 0, a.initError
+	}
+
+	var before time.Time
+	if olderThanDays > 0 {
+		before = time.Now().AddDate(0, 0, -olderThanDays)
+	}
+	deleted, err := a.auditLogRepo.Purge(before)
+	if err != nil {
+		return 0, fmt.Errorf("清除审计日志失败: %w", err)
+	}
+	logger.Infof("已清除 %d 条审计日志", deleted)
+	return deleted, nil
+}
+
 // GetProjectAIConfig 获取项目的 AI 配置
 func (a *App) GetProjectAIConfig(projectID int) (*service.ProjectAIConfig, error) {
 	if a.initError != nil {
@@ -1397,6 +1524,60 @@ func (a *App) UpdateProjectAIConfig(projectID int, provider, language, model str
 	return nil
 }
 
+// UpdateProjectFallbackProviders 更新项目的 Provider 回退链
+// providers 为空数组表示不回退，传 nil 表示使用配置文件中的 fallbackProviders
+func (a *App) UpdateProjectFallbackProviders(projectID int, providers []string) error {
+	if a.initError != nil {
+		return a.initError
+	}
+
+	project, err := a.gitProjectRepo.GetByID(uint(projectID))
+	if err != nil {
+		return fmt.Errorf("获取项目失败: %w", err)
+	}
+
+	project.FallbackProviders = providers
+
+	if err := a.gitProjectRepo.Update(project); err != nil {
+		return fmt.Errorf("更新项目回退链失败: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateProjectGenerationParams 更新项目的生成参数（温度、最大输出 token、top-p、系统提示词、超时）
+// 参数按项目的 Provider 及回退链中各 Provider 的取值范围校验，传 nil 表示使用配置文件中的 params
+func (a *App) UpdateProjectGenerationParams(projectID int, params *aicommitconfig.GenerationParams) error {
+	if a.initError != nil {
+		return a.initError
+	}
+
+	project, err := a.gitProjectRepo.GetByID(uint(projectID))
+	if err != nil {
+		return fmt.Errorf("获取项目失败: %w", err)
+	}
+
+	if params != nil {
+		aiConfig, err := a.projectConfigService.GetProjectAIConfig(project.ID)
+		if err != nil {
+			return err
+		}
+		for _, name := range append([]string{aiConfig.Provider}, aiConfig.FallbackProviders...) {
+			if err := registry.ValidateParams(name, *params); err != nil {
+				return fmt.Errorf("生成参数无效: %w", err)
+			}
+		}
+	}
+
+	project.GenerationParams = params
+
+	if err := a.gitProjectRepo.Update(project); err != nil {
+		return fmt.Errorf("更新项目生成参数失败: %w", err)
+	}
+
+	return nil
+}
+
 // ValidateProjectConfig 验证项目配置
 func (a *App) ValidateProjectConfig(projectID int) (valid bool, resetFields []string, suggestedConfig map[string]interface{}, err error) {
 	if a.initError != nil {
@@ -1447,6 +1628,81 @@ func (a *App) GetConfiguredProviders() ([]models.ProviderInfo, error) {
 	return providers, nil
 }
 
+// SetProviderAPIKey 将 provider 的 API Key 保存到加密存储，config.yaml 中只保存对它的引用
+func (a *App) SetProviderAPIKey(provider, apiKey string) error {
+	if a.initError != nil {
+		return a.initError
+	}
+	if err := a.configService.SetAPIKey(provider, apiKey); err != nil {
+		return err
+	}
+	logger.Infof("已保存 Provider %s 的 API Key", provider)
+	return nil
+}
+
+// RotateProviderAPIKey 替换 provider 已保存的 API Key，并重新加密整个存储
+func (a *App) RotateProviderAPIKey(provider, apiKey string) error {
+	if a.initError != nil {
+		return a.initError
+	}
+	if err := a.configService.RotateAPIKey(provider, apiKey); err != nil {
+		return err
+	}
+	logger.Infof("已轮换 Provider %s 的 API Key", provider)
+	return nil
+}
+
+// DeleteProviderAPIKey 删除 provider 保存在加密存储中的 API Key
+func (a *App) DeleteProviderAPIKey(provider string) error {
+	if a.initError != nil {
+		return a.initError
+	}
+	if err := a.configService.DeleteAPIKey(provider); err != nil {
+		return err
+	}
+	logger.Infof("已删除 Provider %s 的 API Key", provider)
+	return nil
+}
+
+// GetStoredAPIKeys 返回加密存储中保存了 API Key 的 provider（不返回 key 本身）
+func (a *App) GetStoredAPIKeys() ([]service.StoredAPIKey, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+	return a.configService.StoredAPIKeys()
+}
+
+// GetProviderKeyHealth 返回 provider 配置的各个 API Key 的使用情况和当前是否可用
+func (a *App) GetProviderKeyHealth(provider string) ([]service.APIKeyStatus, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+	return a.commitService.KeyHealth(provider)
+}
+
+// RenderStructuredCommit 将前端编辑后的结构化字段渲染为 commit 消息文本
+func (a *App) RenderStructuredCommit(commit ai.StructuredCommit) string {
+	commit.Normalize()
+	return commit.Render()
+}
+
+// TestProvider 向 provider 发送一次最小请求，检查配置是否可用
+// 返回请求耗时，失败时返回分类后的错误（auth / network / model_not_found / quota 等）
+func (a *App) TestProvider(name string) (*service.ProviderTestResult, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+	return a.providerService.TestProvider(a.ctx, name)
+}
+
+// ListModels 查询 provider 可用的模型列表
+func (a *App) ListModels(name string) ([]string, error) {
+	if a.initError != nil {
+		return nil, a.initError
+	}
+	return a.providerService.ListModels(a.ctx, name)
+}
+
 // GetPushoverHookStatus 获取项目的 Pushover Hook 状态
 func (a *App) GetPushoverHookStatus(projectPath string) (*pushover.HookStatus, error) {
 	if a.initError != nil {
@@ -1937,25 +2193,10 @@ func (a *App) PushToRemote(projectPath string) error {
 		return a.initError
 	}
 
-	// 保存当前目录并切换到项目路径
-	originalDir, err := os.Getwd()
-	if err != nil {
-		err = fmt.Errorf("failed to get current directory: %w", err)
-		logger.Errorf("获取当前目录失败: %v", err)
-		return err
-	}
-
-	if err := os.Chdir(projectPath); err != nil {
-		err = fmt.Errorf("failed to change directory: %w", err)
-		logger.Errorf("切换到项目目录失败: %v", err)
-		return err
-	}
-	defer os.Chdir(originalDir)
-
 	logger.Infof("准备推送 - 目录: %s", projectPath)
 
 	// 调用 git 包执行推送
-	if err := git.PushToRemote(context.Background()); err != nil {
+	if err := git.PushToRemote(context.Background(), projectPath); err != nil {
 		logger.Errorf("PushToRemote 失败: %v", err)
 		return err
 	}
@@ -1,9 +1,11 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import type { ProjectStatus, ProjectAIConfig, ProviderInfo, StagingStatus, StagedFile, UntrackedFile } from '../types'
+import type { ProjectStatus, ProjectAIConfig, ProviderInfo, StagingStatus, StagedFile, UntrackedFile, SessionInfo, CommitDeltaEvent, CommitReasoningEvent, CommitCandidate, CommitCandidateEvent, CommitResult, Usage, CommitFallbackEvent, CommitRetryEvent, CommitSummaryProgressEvent, CommitDiffTrimmedEvent, DiffTrimReport, CommitErrorEvent, StructuredCommit } from '../types'
 import {
   GetProjectStatus,
-  GenerateCommit,
+  GenerateCommitWithOptions,
+  CancelGeneration,
+  RenderStructuredCommit,
   GetProjectAIConfig,
   UpdateProjectAIConfig,
   ValidateProjectConfig,
@@ -21,17 +23,50 @@ import {
   AddToGitIgnore,
   DiscardFileChanges
 } from '../../wailsjs/go/main/App'
+import { service } from '../../wailsjs/go/models'
 import { EventsEmit } from '../../wailsjs/runtime'
 
+// 单个项目的生成状态（按项目路径保存，切换项目时不会互相串扰）
+interface GenerationState {
+  sessionId: string
+  isGenerating: boolean
+  streamingMessage: string
+  generatedMessage: string
+  generatedProvider: string
+  generatedUsage: Usage | null   // 本次生成的 token 用量（provider 未返回时为 null）
+  candidateMessages: string[]    // 按候选序号保存的流式输出
+  candidateReasoning: string[]   // 按候选序号保存的推理模型思考过程
+  reasoningMessage: string       // 当前选中候选的思考过程
+  candidates: CommitCandidate[]  // 已生成完成的候选消息
+  selectedCandidate: number      // 当前选中的候选序号
+  statusNotice: string           // 重试/回退等生成过程提示，收到新内容后清除
+  diffTrimReport: DiffTrimReport | null  // 本次生成时 diff 被裁剪的内容
+  generatedStructured: StructuredCommit | null  // 结构化模式下当前消息的各字段
+}
+
 export const useCommitStore = defineStore('commit', () => {
   const selectedProjectPath = ref<string>('')
   const selectedProjectId = ref<number>(0)
   const projectStatus = ref<ProjectStatus | null>(null)
   const isGenerating = ref(false)
   const streamingMessage = ref('')
+  const reasoningMessage = ref('')  // 推理模型的思考过程，单独显示
   const generatedMessage = ref('')
+  const generatedProvider = ref('')  // 实际生成消息的 provider（可能来自回退链）
+  const generatedUsage = ref<Usage | null>(null)
+  const candidates = ref<CommitCandidate[]>([])  // 多候选模式下已完成的候选
+  const selectedCandidate = ref(0)
+  const statusNotice = ref('')
+  const diffTrimReport = ref<DiffTrimReport | null>(null)
+  const generatedStructured = ref<StructuredCommit | null>(null)
+  const candidateCount = ref(1)  // 每次生成的候选数量
+  const structuredOutput = ref(false)  // 按字段生成（type/scope/subject/...），可逐项编辑
+  const raceProviders = ref(false)  // 同时请求 provider 及其回退链，采用最先返回的结果
   const error = ref<string | null>(null)
 
+  // 各项目的生成状态，上面的 isGenerating / streamingMessage 等为当前选中项目的镜像
+  const generations: Record<string, GenerationState> = {}
+
   // Provider 列表
   const availableProviders = ref<ProviderInfo[]>([])
 
@@ -184,53 +219,333 @@ export const useCommitStore = defineStore('commit', () => {
     }
   }
 
-  async function generateCommit() {
+  function getGeneration(path: string): GenerationState {
+    if (!generations[path]) {
+      generations[path] = {
+        sessionId: '',
+        isGenerating: false,
+        streamingMessage: '',
+        generatedMessage: '',
+        generatedProvider: '',
+        generatedUsage: null,
+        candidateMessages: [],
+        candidateReasoning: [],
+        reasoningMessage: '',
+        candidates: [],
+        selectedCandidate: 0,
+        statusNotice: '',
+        diffTrimReport: null,
+        generatedStructured: null
+      }
+    }
+    return generations[path]
+  }
+
+  function resetGeneration(gen: GenerationState) {
+    gen.streamingMessage = ''
+    gen.generatedMessage = ''
+    gen.generatedProvider = ''
+    gen.generatedUsage = null
+    gen.candidateMessages = []
+    gen.candidateReasoning = []
+    gen.reasoningMessage = ''
+    gen.candidates = []
+    gen.selectedCandidate = 0
+    gen.statusNotice = ''
+    gen.diffTrimReport = null
+    gen.generatedStructured = null
+  }
+
+  function applyGeneration(gen: GenerationState) {
+    isGenerating.value = gen.isGenerating
+    streamingMessage.value = gen.streamingMessage
+    reasoningMessage.value = gen.reasoningMessage
+    generatedMessage.value = gen.generatedMessage
+    generatedProvider.value = gen.generatedProvider
+    generatedUsage.value = gen.generatedUsage
+    candidates.value = [...gen.candidates]
+    selectedCandidate.value = gen.selectedCandidate
+    statusNotice.value = gen.statusNotice
+    diffTrimReport.value = gen.diffTrimReport
+    generatedStructured.value = gen.generatedStructured
+  }
+
+  // 将项目的生成状态同步到界面（仅当该项目为当前选中项目）
+  function syncGeneration(path: string) {
+    if (path !== selectedProjectPath.value) {
+      return
+    }
+    applyGeneration(getGeneration(path))
+  }
+
+  // 切换项目时恢复该项目的生成状态（后台生成的结果不会丢失）
+  function restoreGeneration(path: string) {
+    applyGeneration(getGeneration(path))
+  }
+
+  // 只处理项目当前会话的事件，忽略已被替换的旧会话
+  function currentGeneration(info: SessionInfo): GenerationState | null {
+    const gen = generations[info.projectPath]
+    if (!gen || gen.sessionId !== info.sessionId) {
+      console.log('[commit] 忽略过期会话的事件:', info.sessionId, info.projectPath)
+      return null
+    }
+    return gen
+  }
+
+  // regenerate 为 true 时跳过后端缓存，重新调用 provider
+  async function generateCommit(regenerate = false) {
     if (!selectedProjectPath.value) {
       error.value = '请先选择项目'
       return
     }
 
-    isGenerating.value = true
-    streamingMessage.value = ''
-    generatedMessage.value = ''
+    const path = selectedProjectPath.value
+    const gen = getGeneration(path)
+    gen.sessionId = ''
+    gen.isGenerating = true
+    resetGeneration(gen)
+    syncGeneration(path)
     error.value = null
 
     try {
-      await GenerateCommit(
-        selectedProjectPath.value,
-        provider.value,
-        language.value
-      )
+      gen.sessionId = await GenerateCommitWithOptions(path, service.GenerateOptions.createFrom({
+        provider: provider.value,
+        language: language.value,
+        candidates: candidateCount.value,
+        regenerate,
+        structured: structuredOutput.value,
+        strategy: raceProviders.value ? 'race' : 'fallback'
+      }))
     } catch (e: unknown) {
       const message = e instanceof Error ? e.message : '生成失败'
       error.value = message
-      isGenerating.value = false
+      gen.isGenerating = false
+      syncGeneration(path)
+    }
+  }
+
+  // 选择候选消息（生成过程中切换查看对应的流式输出）
+  function selectCandidate(index: number) {
+    if (!selectedProjectPath.value) return
+    const gen = getGeneration(selectedProjectPath.value)
+    gen.selectedCandidate = index
+    const candidate = gen.candidates.find(c => c.index === index)
+    if (candidate) {
+      gen.generatedMessage = candidate.message
+      gen.generatedProvider = candidate.provider
+      gen.generatedStructured = candidate.structured ?? null
+      gen.streamingMessage = candidate.message
+    } else {
+      gen.streamingMessage = gen.candidateMessages[index] || ''
+    }
+    gen.reasoningMessage = gen.candidateReasoning[index] || ''
+    syncGeneration(selectedProjectPath.value)
+  }
+
+  // 编辑结构化字段后重新渲染消息（渲染规则与后端一致）
+  async function updateStructured(fields: StructuredCommit) {
+    if (!selectedProjectPath.value) return
+    const path = selectedProjectPath.value
+    const gen = getGeneration(path)
+    try {
+      const message = await RenderStructuredCommit(fields as any)
+      gen.generatedStructured = fields
+      gen.generatedMessage = message
+      gen.streamingMessage = message
+      const candidate = gen.candidates.find(c => c.index === gen.selectedCandidate)
+      if (candidate) {
+        candidate.message = message
+        candidate.structured = fields
+      }
+      syncGeneration(path)
+    } catch (e: unknown) {
+      const message = e instanceof Error ? e.message : '渲染 commit 消息失败'
+      error.value = message
+    }
+  }
+
+  // 未被选中的候选消息，提交时与选中的消息一起记录到历史
+  const rejectedCandidates = computed(() =>
+    candidates.value
+      .filter(c => c.index !== selectedCandidate.value)
+      .map(c => c.message)
+  )
+
+  async function cancelGeneration() {
+    if (!selectedProjectPath.value || !isGenerating.value) {
+      return
+    }
+
+    try {
+      await CancelGeneration(selectedProjectPath.value)
+    } catch (e: unknown) {
+      const message = e instanceof Error ? e.message : '取消生成失败'
+      error.value = message
     }
   }
 
   function clearMessage() {
     streamingMessage.value = ''
+    reasoningMessage.value = ''
     generatedMessage.value = ''
+    generatedProvider.value = ''
+    generatedUsage.value = null
+    generatedStructured.value = null
+    candidates.value = []
+    selectedCandidate.value = 0
+    if (selectedProjectPath.value && generations[selectedProjectPath.value]) {
+      resetGeneration(generations[selectedProjectPath.value])
+    }
   }
 
   // 事件处理函数（供组件调用）
-  function handleDelta(delta: string) {
-    console.log('[commit-delta] 收到 delta:', delta.substring(0, 50) + '...')
-    streamingMessage.value += delta
-    console.log('[commit-delta] 当前 streamingMessage 长度:', streamingMessage.value.length)
+  // 后端开始生成，记录会话 ID（先于该会话的其它事件到达）
+  function handleStarted(info: SessionInfo) {
+    console.log('[commit-started] 会话:', info.sessionId, info.projectPath)
+    const gen = getGeneration(info.projectPath)
+    gen.sessionId = info.sessionId
+    gen.isGenerating = true
+    resetGeneration(gen)
+    syncGeneration(info.projectPath)
   }
 
-  function handleComplete(message: string) {
-    console.log('[commit-complete] 收到完整消息:', message.substring(0, 50) + '...')
-    generatedMessage.value = message
-    streamingMessage.value = message
-    isGenerating.value = false
+  function handleDelta(event: CommitDeltaEvent) {
+    const gen = currentGeneration(event)
+    if (!gen) return
+    const index = event.index ?? 0
+    gen.candidateMessages[index] = (gen.candidateMessages[index] || '') + event.delta
+    gen.statusNotice = ''
+    if (index === gen.selectedCandidate) {
+      gen.streamingMessage = gen.candidateMessages[index]
+    }
+    syncGeneration(event.projectPath)
+  }
+
+  // 推理模型的思考过程，与消息内容分开保存
+  function handleReasoning(event: CommitReasoningEvent) {
+    const gen = currentGeneration(event)
+    if (!gen) return
+    const index = event.index ?? 0
+    gen.candidateReasoning[index] = (gen.candidateReasoning[index] || '') + event.delta
+    gen.statusNotice = ''
+    if (index === gen.selectedCandidate) {
+      gen.reasoningMessage = gen.candidateReasoning[index]
+    }
+    syncGeneration(event.projectPath)
+  }
+
+  // 多候选模式下单条候选生成结束
+  function handleCandidate(event: CommitCandidateEvent) {
+    const gen = currentGeneration(event)
+    if (!gen) return
+    if (event.error) {
+      console.log('[commit-candidate] 候选生成失败:', event.index, event.error)
+      return
+    }
+    gen.candidates = [...gen.candidates.filter(c => c.index !== event.index), {
+      index: event.index,
+      message: event.message,
+      provider: event.provider,
+      structured: event.structured
+    }].sort((a, b) => a.index - b.index)
+    syncGeneration(event.projectPath)
   }
 
-  function handleError(err: string) {
-    console.log('[commit-error] 收到错误:', err)
-    error.value = err
-    isGenerating.value = false
+  function handleComplete(result: CommitResult) {
+    console.log('[commit-complete] 收到完整消息:', result.message.substring(0, 50) + '...', 'provider:', result.provider)
+    const gen = currentGeneration(result)
+    if (!gen) return
+    gen.candidates = result.candidates ?? []
+    // 保留用户在生成过程中选中的候选，否则使用第一条
+    const chosen = gen.candidates.find(c => c.index === gen.selectedCandidate)
+    if (chosen) {
+      gen.generatedMessage = chosen.message
+      gen.generatedProvider = chosen.provider
+      gen.generatedStructured = chosen.structured ?? null
+    } else {
+      gen.selectedCandidate = gen.candidates[0]?.index ?? 0
+      gen.generatedMessage = result.message
+      gen.generatedProvider = result.provider
+      gen.generatedStructured = result.structured ?? null
+    }
+    gen.generatedUsage = result.usage ?? null
+    gen.streamingMessage = gen.generatedMessage
+    gen.statusNotice = ''
+    gen.isGenerating = false
+    syncGeneration(result.projectPath)
+  }
+
+  // 当前 provider 失败，后端切换到回退链中的下一个 provider
+  function handleFallback(event: CommitFallbackEvent) {
+    console.log('[commit-fallback] provider 失败:', event.failedProvider, '切换到:', event.nextProvider, event.error)
+    const gen = currentGeneration(event)
+    if (!gen) return
+    // 丢弃失败 provider 已输出的部分内容
+    const index = event.index ?? 0
+    gen.candidateMessages[index] = ''
+    gen.candidateReasoning[index] = ''
+    if (index === gen.selectedCandidate) {
+      gen.streamingMessage = ''
+      gen.reasoningMessage = ''
+    }
+    gen.statusNotice = `${event.failedProvider} 请求失败，已切换到 ${event.nextProvider}`
+    syncGeneration(event.projectPath)
+  }
+
+  // provider 遇到限流、超时等暂时性错误，后端将在等待后重试
+  function handleRetry(event: CommitRetryEvent) {
+    console.log('[commit-retry] provider 请求失败，准备重试:', event.provider, event.attempt, '/', event.maxAttempts, event.error)
+    const gen = currentGeneration(event)
+    if (!gen) return
+    const seconds = Math.max(1, Math.round(event.delayMs / 1000))
+    const request = event.index < 0 ? '分块总结请求' : '请求'
+    gen.statusNotice = `${event.provider} ${request}失败，${seconds} 秒后第 ${event.attempt}/${event.maxAttempts} 次重试…`
+    syncGeneration(event.projectPath)
+  }
+
+  // diff 超出限制，后端正在分块总结
+  function handleSummarizing(event: CommitSummaryProgressEvent) {
+    const gen = currentGeneration(event)
+    if (!gen) return
+    gen.statusNotice = `Diff 过大，正在分块总结 (${event.done}/${event.total})…`
+    syncGeneration(event.projectPath)
+  }
+
+  // diff 超出限制或包含锁文件，后端裁剪了部分内容
+  function handleDiffTrimmed(event: CommitDiffTrimmedEvent) {
+    console.log('[commit-diff-trimmed] diff 已裁剪:', event.originalChars, '->', event.finalChars, event.omittedFiles, event.truncatedFiles)
+    const gen = currentGeneration(event)
+    if (!gen) return
+    gen.diffTrimReport = {
+      originalChars: event.originalChars,
+      finalChars: event.finalChars,
+      omittedFiles: event.omittedFiles ?? [],
+      truncatedFiles: event.truncatedFiles ?? []
+    }
+    syncGeneration(event.projectPath)
+  }
+
+  // 生成已被取消，保留已输出的部分内容供参考
+  function handleCancelled(info: SessionInfo) {
+    console.log('[commit-cancelled] 生成已取消:', info.sessionId, info.projectPath)
+    const gen = currentGeneration(info)
+    if (!gen) return
+    gen.isGenerating = false
+    gen.statusNotice = ''
+    syncGeneration(info.projectPath)
+  }
+
+  function handleError(event: CommitErrorEvent) {
+    console.log('[commit-error] 收到错误:', event.error, event.projectPath)
+    const gen = currentGeneration(event)
+    if (!gen) return
+    gen.isGenerating = false
+    gen.statusNotice = ''
+    syncGeneration(event.projectPath)
+    if (event.projectPath === selectedProjectPath.value) {
+      error.value = event.error
+    }
   }
 
   // 通知项目列表状态已更新
@@ -792,7 +1107,19 @@ export const useCommitStore = defineStore('commit', () => {
     projectStatus,
     isGenerating,
     streamingMessage,
+    reasoningMessage,
     generatedMessage,
+    generatedProvider,
+    generatedUsage,
+    candidates,
+    selectedCandidate,
+    statusNotice,
+    diffTrimReport,
+    generatedStructured,
+    candidateCount,
+    structuredOutput,
+    raceProviders,
+    rejectedCandidates,
     error,
     availableProviders,
     provider,
@@ -817,9 +1144,21 @@ export const useCommitStore = defineStore('commit', () => {
     saveProjectConfig,
     confirmResetConfig,
     generateCommit,
+    cancelGeneration,
+    selectCandidate,
+    updateStructured,
     clearMessage,
+    restoreGeneration,
+    handleStarted,
     handleDelta,
+    handleReasoning,
+    handleCandidate,
     handleComplete,
+    handleFallback,
+    handleRetry,
+    handleSummarizing,
+    handleDiffTrimmed,
+    handleCancelled,
     handleError,
     loadStagingStatus,
     loadFileDiff,
@@ -11,6 +11,7 @@ export interface GitProject {
   language?: string | null      // null 表示使用默认
   model?: string | null         // null 表示使用默认
   use_default?: boolean         // true 表示使用默认配置
+  generation_params?: GenerationParams | null  // 项目级生成参数，null 表示使用配置文件中的 params
 
   // Pushover Hook 配置
   hook_installed?: boolean
@@ -68,15 +69,237 @@ export interface CommitHistory {
   message: string
   provider: string
   language: string
+  model?: string
+  prompt_tokens: number
+  completion_tokens: number
   created_at: string
   project?: GitProject
 }
 
+// provider 返回的 token 用量
+export interface Usage {
+  provider: string
+  model: string
+  promptTokens: number
+  completionTokens: number
+}
+
+// 按项目、provider、月份汇总的用量与费用（GetUsageCostSummary 返回）
+export interface UsageCostSummary {
+  projectId: number
+  projectName: string
+  provider: string
+  month: string  // 格式: 2006-01
+  generations: number
+  promptTokens: number
+  completionTokens: number
+  cost: number   // USD，按当前价格表计算
+  unpricedModels?: string[]  // 价格表中缺失的模型，未计入 cost
+}
+
+// 生成会话标识，所有 commit-* 事件都携带
+// commit-started / commit-cancelled 事件数据即为 SessionInfo
+export interface SessionInfo {
+  sessionId: string
+  projectPath: string
+}
+
+// commit-delta 事件数据
+export interface CommitDeltaEvent extends SessionInfo {
+  index: number  // 候选消息序号（单条生成时为 0）
+  delta: string
+}
+
+// 推理模型输出的思考过程（不包含在最终消息中）
+export interface CommitReasoningEvent extends SessionInfo {
+  index: number
+  delta: string
+}
+
+// 生成完成的候选消息
+// git trailer，如 Refs: #123
+export interface CommitFooter {
+  token: string
+  value: string
+}
+
+// 结构化模式下 commit 消息的各字段（Conventional Commits）
+export interface StructuredCommit {
+  type: string
+  scope: string
+  subject: string
+  body: string[]  // 正文要点，每条一行
+  breakingChange: string  // 为空表示没有破坏性变更
+  footers: CommitFooter[]
+}
+
+export interface CommitCandidate {
+  index: number
+  message: string
+  provider: string
+  usage?: Usage
+  structured?: StructuredCommit  // 结构化模式下的各字段，message 由其渲染得到
+}
+
+// commit-candidate 事件数据（多候选模式下每条候选结束时发送）
+export interface CommitCandidateEvent extends SessionInfo, CommitCandidate {
+  error?: string
+}
+
+// commit-complete 事件数据
+export interface CommitResult extends SessionInfo {
+  message: string
+  provider: string  // 实际生成消息的 provider
+  candidates?: CommitCandidate[]  // 多候选模式下所有成功的候选
+  usage?: Usage  // 本次生成所有候选的 token 用量之和
+  cached?: boolean  // 结果来自缓存，未调用 provider
+  structured?: StructuredCommit  // 结构化模式下 message 对应的各字段
+}
+
+// commit-fallback 事件数据
+export interface CommitFallbackEvent extends SessionInfo {
+  index: number
+  failedProvider: string
+  nextProvider: string
+  error: string
+}
+
+// commit-retry 事件数据
+export interface CommitRetryEvent extends SessionInfo {
+  index: number // -1 表示总结 diff 分块的请求
+  provider: string
+  attempt: number
+  maxAttempts: number
+  delayMs: number
+  error: string
+}
+
+// commit-summarizing 事件数据（diff 超出限制时分块总结的进度）
+export interface CommitSummaryProgressEvent extends SessionInfo {
+  done: number
+  total: number
+}
+
+// 按 lockFiles 和 limits 配置从 diff 中裁掉的内容
+export interface DiffTrimReport {
+  originalChars: number
+  finalChars: number
+  omittedFiles?: string[]    // 整体省略的文件（锁文件、生成文件、vendor 等）
+  truncatedFiles?: string[]  // 按预算截断的文件
+}
+
+// commit-diff-trimmed 事件数据
+export interface CommitDiffTrimmedEvent extends SessionInfo, DiffTrimReport {}
+
+// commit-error 事件数据
+export interface CommitErrorEvent extends SessionInfo {
+  error: string
+}
+
 // Provider 配置信息
+// Provider 注册时声明的能力
+// 生成参数，未设置的项使用 provider 默认值
+export interface GenerationParams {
+  temperature?: number
+  topP?: number
+  maxOutputTokens?: number
+  systemPrompt?: string
+  timeout?: number  // 单次请求超时，单位纳秒（Go time.Duration）
+}
+
+export interface ProviderCapabilities {
+  streaming: boolean       // 支持流式输出
+  contextTokens?: number   // 默认模型的上下文窗口（token），未知时省略
+  jsonMode: boolean        // 支持原生 JSON / 结构化输出
+  reasoning: boolean       // 可单独返回推理过程
+  local: boolean           // 本地运行
+  models?: string[]        // 常用模型
+  params: ProviderParamLimits  // 支持的生成参数
+}
+
+export interface ProviderParamLimits {
+  maxTemperature?: number  // 温度上限，省略表示不支持设置温度
+  topP: boolean            // 支持 topP
+  maxOutputTokens?: number // 最大输出 token 上限，省略表示未知
+  systemPrompt: boolean    // 支持系统提示词
+}
+
 export interface ProviderInfo {
   name: string           // provider 名称，如 'openai'
   configured: boolean    // 是否已配置
   reason?: string        // 未配置的原因
+  capabilities?: ProviderCapabilities
+  latency?: ProviderLatency  // 本次运行中观测到的生成耗时，列表按其从快到慢排列
+}
+
+// provider 成功生成消息的耗时统计
+export interface ProviderLatency {
+  avgMs: number   // 移动平均
+  lastMs: number
+  samples: number
+}
+
+// 保存在加密存储中的 API Key（不包含 key 本身）
+export interface StoredAPIKey {
+  provider: string
+  updatedAt: string
+}
+
+// provider 配置的单个 API Key 的使用情况（GetProviderKeyHealth 返回，不包含 key 本身）
+export interface APIKeyStatus {
+  keyId: string
+  label: string
+  quota: number
+  dayRequests: number
+  successes: number
+  failures: number
+  consecutiveFailures: number
+  lastStatus: number
+  lastError: string
+  lastUsedAt?: string | null
+  cooldownUntil?: string | null
+  available: boolean
+}
+
+// 审计日志条目（config.yaml 中 audit.enabled 为 true 时记录，敏感内容已屏蔽）
+// GetAuditLogs 返回的条目不包含 prompt 和 raw_response，完整内容通过 GetAuditLog 获取
+export interface AuditLog {
+  id: number
+  session_id: string
+  project_path: string
+  candidate_index: number // -1 表示总结 diff 分块的请求
+  provider: string
+  model: string
+  prompt?: string
+  raw_response?: string
+  result: string
+  error: string
+  duration_ms: number
+  prompt_tokens: number
+  completion_tokens: number
+  created_at: string
+}
+
+export interface AuditLogFilter {
+  projectPath?: string
+  provider?: string
+  sessionId?: string
+  limit?: number
+  offset?: number
+}
+
+export interface AuditLogPage {
+  entries: AuditLog[]
+  total: number
+}
+
+// Provider 连接测试结果（TestProvider 返回）
+export interface ProviderTestResult {
+  provider: string
+  success: boolean
+  latencyMs: number
+  errorKind?: 'auth' | 'network' | 'model_not_found' | 'quota' | 'server' | 'unknown'
+  error?: string
 }
 
 // 排除模式
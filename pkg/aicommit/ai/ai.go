@@ -24,6 +24,12 @@ type StreamingAIClient interface {
     StreamCommitMessage(ctx context.Context, prompt string, onDelta func(delta string)) (final string, err error)
 }
 
+// ModelLister is an optional interface for providers that can list the models
+// available to the configured account or server.
+type ModelLister interface {
+    ListModels(ctx context.Context) ([]string, error)
+}
+
 type BaseAIClient struct {
 	Provider string
 }
@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+// Reasoning models (DeepSeek-R1, QwQ, ...) either wrap their chain of thought in
+// <think>...</think> blocks inside the answer or send it in a separate field such as
+// reasoning_content. Providers report it through ReportReasoning and keep it out of
+// the deltas and the final message.
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// ReasoningHandler receives reasoning text as it is generated.
+type ReasoningHandler func(delta string)
+
+type reasoningHandlerKey struct{}
+
+// WithReasoningHandler returns a context whose provider calls report reasoning text to h.
+func WithReasoningHandler(ctx context.Context, h ReasoningHandler) context.Context {
+	return context.WithValue(ctx, reasoningHandlerKey{}, h)
+}
+
+// ReportReasoning forwards a reasoning delta to the handler attached to ctx, if any.
+func ReportReasoning(ctx context.Context, delta string) {
+	if delta == "" {
+		return
+	}
+	if h, ok := ctx.Value(reasoningHandlerKey{}).(ReasoningHandler); ok && h != nil {
+		h(delta)
+	}
+}
+
+// StripReasoning splits a complete response into the answer and the text of its
+// <think> blocks. A response that only contains the closing tag (the opening tag was
+// part of the prompt template) is treated as reasoning up to that tag.
+func StripReasoning(text string) (answer, reasoning string) {
+	if !strings.Contains(text, thinkOpen) {
+		if before, after, found := strings.Cut(text, thinkClose); found {
+			return strings.TrimSpace(after), strings.TrimSpace(before)
+		}
+	}
+	var p ThinkParser
+	answer, reasoning = p.Feed(text)
+	a, r := p.Flush()
+	return strings.TrimSpace(answer + a), strings.TrimSpace(reasoning + r)
+}
+
+// ThinkParser separates streamed text into answer and <think> reasoning. Tags may be
+// split across deltas; text that could be the start of a tag is held back until the
+// next delta or Flush.
+type ThinkParser struct {
+	inThink bool
+	pending string
+}
+
+// Feed consumes a delta and returns the answer and reasoning text it completes.
+func (p *ThinkParser) Feed(delta string) (answer, reasoning string) {
+	var ab, rb strings.Builder
+	text := p.pending + delta
+	p.pending = ""
+	for text != "" {
+		tag := thinkOpen
+		out := &ab
+		if p.inThink {
+			tag, out = thinkClose, &rb
+		}
+		if i := strings.Index(text, tag); i >= 0 {
+			out.WriteString(text[:i])
+			text = text[i+len(tag):]
+			p.inThink = !p.inThink
+			continue
+		}
+		keep := partialTagSuffix(text, tag)
+		out.WriteString(text[:len(text)-keep])
+		p.pending = text[len(text)-keep:]
+		break
+	}
+	return ab.String(), rb.String()
+}
+
+// Flush returns the text held back by Feed.
+func (p *ThinkParser) Flush() (answer, reasoning string) {
+	text := p.pending
+	p.pending = ""
+	if p.inThink {
+		return "", text
+	}
+	return text, ""
+}
+
+// partialTagSuffix returns the length of the longest suffix of text that is a proper
+// prefix of tag.
+func partialTagSuffix(text, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// ReasoningSplitter routes the deltas of a stream: answer text goes to onDelta and
+// reasoning, whether in <think> blocks or passed to Reasoning, to ReportReasoning.
+// Whitespace that separates the reasoning from the answer is not forwarded.
+type ReasoningSplitter struct {
+	ctx     context.Context
+	onDelta func(string)
+	parser  ThinkParser
+	started bool
+}
+
+// NewReasoningSplitter returns a splitter that forwards answer deltas to onDelta.
+func NewReasoningSplitter(ctx context.Context, onDelta func(string)) *ReasoningSplitter {
+	return &ReasoningSplitter{ctx: ctx, onDelta: onDelta}
+}
+
+// Content handles a delta of the answer field, which may contain <think> blocks.
+func (s *ReasoningSplitter) Content(delta string) {
+	s.emit(s.parser.Feed(delta))
+}
+
+// Reasoning handles a delta of a dedicated reasoning field.
+func (s *ReasoningSplitter) Reasoning(delta string) {
+	ReportReasoning(s.ctx, delta)
+}
+
+// Flush forwards text held back at the end of the stream.
+func (s *ReasoningSplitter) Flush() {
+	s.emit(s.parser.Flush())
+}
+
+func (s *ReasoningSplitter) emit(answer, reasoning string) {
+	ReportReasoning(s.ctx, reasoning)
+	if !s.started {
+		answer = strings.TrimLeft(answer, " \t\r\n")
+	}
+	if answer == "" {
+		return
+	}
+	s.started = true
+	s.onDelta(answer)
+}
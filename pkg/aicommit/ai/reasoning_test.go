@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripReasoning(t *testing.T) {
+	answer, reasoning := StripReasoning("<think>\nThe diff adds a flag.\n</think>\n\nfeat: add flag")
+	assert.Equal(t, "feat: add flag", answer)
+	assert.Equal(t, "The diff adds a flag.", reasoning)
+
+	// The opening tag was part of the chat template.
+	answer, reasoning = StripReasoning("The diff fixes a typo.</think>fix: typo")
+	assert.Equal(t, "fix: typo", answer)
+	assert.Equal(t, "The diff fixes a typo.", reasoning)
+
+	answer, reasoning = StripReasoning("docs: update readme")
+	assert.Equal(t, "docs: update readme", answer)
+	assert.Empty(t, reasoning)
+}
+
+func TestThinkParser_SplitTags(t *testing.T) {
+	var p ThinkParser
+	var answer, reasoning strings.Builder
+	for _, delta := range []string{"<th", "ink>plan", "ning</thi", "nk>feat: ", "a <b> tag", "<"} {
+		a, r := p.Feed(delta)
+		answer.WriteString(a)
+		reasoning.WriteString(r)
+	}
+	a, r := p.Flush()
+	answer.WriteString(a)
+	reasoning.WriteString(r)
+
+	assert.Equal(t, "feat: a <b> tag<", answer.String())
+	assert.Equal(t, "planning", reasoning.String())
+}
+
+func TestReasoningSplitter(t *testing.T) {
+	var reasoning []string
+	ctx := WithReasoningHandler(context.Background(), func(delta string) { reasoning = append(reasoning, delta) })
+
+	var deltas []string
+	s := NewReasoningSplitter(ctx, func(delta string) { deltas = append(deltas, delta) })
+	s.Reasoning("from a field")
+	s.Content("<think>inline</think>")
+	s.Content("\n\n")
+	s.Content("fix: ")
+	s.Content("bug\n")
+	s.Flush()
+
+	assert.Equal(t, []string{"from a field", "inline"}, reasoning)
+	assert.Equal(t, []string{"fix: ", "bug\n"}, deltas)
+
+	// Without a handler reasoning is dropped.
+	ReportReasoning(context.Background(), "ignored")
+}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/committypes"
+)
+
+// StructuredCommitSchemaName names the structured commit schema in provider requests
+// (response format name, tool name).
+const StructuredCommitSchemaName = "commit_message"
+
+// StructuredOutputClient is an optional interface for providers that can constrain
+// their output to a JSON schema natively (JSON schema response formats, forced tool
+// calls, JSON mode). It returns the raw JSON text; callers parse it with
+// ParseStructuredCommit. Providers without it are asked for JSON in the prompt only.
+type StructuredOutputClient interface {
+	GenerateStructured(ctx context.Context, prompt, name string, schema *Schema) (string, error)
+}
+
+// Schema is the subset of JSON Schema used to describe structured output.
+type Schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// Footer is a git trailer such as "Refs: #123" or "Co-authored-by: ...".
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// StructuredCommit is a commit message split into its Conventional Commits fields.
+type StructuredCommit struct {
+	Type    string   `json:"type"`
+	Scope   string   `json:"scope"`
+	Subject string   `json:"subject"`
+	Body    []string `json:"body"`
+	// BreakingChange describes the breaking change; empty when there is none.
+	BreakingChange string   `json:"breakingChange"`
+	Footers        []Footer `json:"footers"`
+}
+
+// StructuredCommitSchema returns the JSON schema of StructuredCommit. Every property is
+// required and unknown properties are rejected, as strict schema modes demand; optional
+// fields are expressed as empty strings and arrays.
+func StructuredCommitSchema() *Schema {
+	closed := false
+	str := func(desc string) *Schema { return &Schema{Type: "string", Description: desc} }
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":    str("Conventional Commits type, one of: " + strings.ReplaceAll(committypes.TypesRegexPattern(), "|", ", ")),
+			"scope":   str("Optional scope (module or area changed), empty string if none"),
+			"subject": str("Short imperative summary without trailing period"),
+			"body": {
+				Type:        "array",
+				Description: "Bullet points explaining what changed and why, may be empty",
+				Items:       str("One bullet point without the leading dash"),
+			},
+			"breakingChange": str("Description of the breaking change, empty string if none"),
+			"footers": {
+				Type:        "array",
+				Description: "Git trailers such as issue references, may be empty",
+				Items: &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"token": str("Trailer token, e.g. Refs"),
+						"value": str("Trailer value, e.g. #123"),
+					},
+					Required:             []string{"token", "value"},
+					AdditionalProperties: &closed,
+				},
+			},
+		},
+		Required:             []string{"type", "scope", "subject", "body", "breakingChange", "footers"},
+		AdditionalProperties: &closed,
+	}
+}
+
+// ParseStructuredCommit extracts the JSON object from a model response (tolerating
+// markdown fences and surrounding prose) and normalizes its fields.
+func ParseStructuredCommit(text string) (*StructuredCommit, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("response does not contain a JSON object")
+	}
+	var c StructuredCommit
+	if err := json.Unmarshal([]byte(text[start:end+1]), &c); err != nil {
+		return nil, fmt.Errorf("invalid structured commit JSON: %w", err)
+	}
+	c.Normalize()
+	return &c, nil
+}
+
+// Normalize trims every field, lower-cases the type, drops empty body bullets and
+// footers, and strips bullet markers and a trailing period from the subject.
+func (c *StructuredCommit) Normalize() {
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	c.Scope = strings.Trim(strings.TrimSpace(c.Scope), "()")
+	c.Subject = strings.TrimSuffix(strings.TrimSpace(c.Subject), ".")
+	c.BreakingChange = strings.TrimSpace(c.BreakingChange)
+
+	body := make([]string, 0, len(c.Body))
+	for _, line := range c.Body {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+		if line != "" {
+			body = append(body, line)
+		}
+	}
+	c.Body = body
+
+	footers := make([]Footer, 0, len(c.Footers))
+	for _, f := range c.Footers {
+		f.Token, f.Value = strings.TrimSpace(f.Token), strings.TrimSpace(f.Value)
+		if f.Token != "" && f.Value != "" {
+			footers = append(footers, f)
+		}
+	}
+	c.Footers = footers
+}
+
+var footerTokenPattern = regexp.MustCompile(`^(?:[A-Za-z][A-Za-z0-9-]*|BREAKING CHANGE)$`)
+
+// Validate checks the fields against the configured commit types (see committypes)
+// and the Conventional Commits header rules.
+func (c *StructuredCommit) Validate() error {
+	var problems []string
+	typePattern := regexp.MustCompile(`^(?:` + committypes.TypesRegexPattern() + `)$`)
+	if c.Type == "" {
+		problems = append(problems, "type is empty")
+	} else if !typePattern.MatchString(c.Type) {
+		problems = append(problems, fmt.Sprintf("unknown commit type %q", c.Type))
+	}
+	if strings.ContainsAny(c.Scope, " \t\n()") {
+		problems = append(problems, fmt.Sprintf("invalid scope %q", c.Scope))
+	}
+	if c.Subject == "" {
+		problems = append(problems, "subject is empty")
+	} else if strings.Contains(c.Subject, "\n") {
+		problems = append(problems, "subject spans multiple lines")
+	}
+	for _, f := range c.Footers {
+		if !footerTokenPattern.MatchString(f.Token) {
+			problems = append(problems, fmt.Sprintf("invalid footer token %q", f.Token))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid structured commit: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Render formats the commit as Conventional Commits text:
+//
+//	type(scope)!: subject
+//
+//	- body bullet
+//
+//	BREAKING CHANGE: description
+//	Token: value
+func (c StructuredCommit) Render() string {
+	var sb strings.Builder
+	sb.WriteString(c.Type)
+	if c.Scope != "" {
+		sb.WriteString("(" + c.Scope + ")")
+	}
+	if c.BreakingChange != "" {
+		sb.WriteString("!")
+	}
+	sb.WriteString(": " + c.Subject)
+
+	if len(c.Body) > 0 {
+		sb.WriteString("\n")
+		for _, line := range c.Body {
+			sb.WriteString("\n- " + line)
+		}
+	}
+
+	if c.BreakingChange != "" || len(c.Footers) > 0 {
+		sb.WriteString("\n")
+		if c.BreakingChange != "" {
+			sb.WriteString("\nBREAKING CHANGE: " + c.BreakingChange)
+		}
+		for _, f := range c.Footers {
+			sb.WriteString("\n" + f.Token + ": " + f.Value)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStructuredCommit(t *testing.T) {
+	text := "Sure:\n```json\n" + `{"type":" Feat ","scope":"(ui)","subject":"add dark mode.","body":["* toggle in settings",""],"breakingChange":"","footers":[{"token":"Refs","value":"#7"},{"token":"","value":"x"}]}` + "\n```"
+
+	c, err := ParseStructuredCommit(text)
+	require.NoError(t, err)
+	assert.Equal(t, "feat", c.Type)
+	assert.Equal(t, "ui", c.Scope)
+	assert.Equal(t, "add dark mode", c.Subject)
+	assert.Equal(t, []string{"toggle in settings"}, c.Body)
+	assert.Equal(t, []Footer{{Token: "Refs", Value: "#7"}}, c.Footers)
+	assert.NoError(t, c.Validate())
+
+	_, err = ParseStructuredCommit("feat: not json")
+	assert.Error(t, err)
+}
+
+func TestStructuredCommit_Validate(t *testing.T) {
+	assert.Error(t, (&StructuredCommit{Type: "feature", Subject: "x"}).Validate())
+	assert.Error(t, (&StructuredCommit{Type: "fix"}).Validate())
+	assert.Error(t, (&StructuredCommit{Type: "fix", Scope: "two words", Subject: "x"}).Validate())
+	assert.Error(t, (&StructuredCommit{Type: "fix", Subject: "x", Footers: []Footer{{Token: "Bad token", Value: "v"}}}).Validate())
+	assert.NoError(t, (&StructuredCommit{Type: "fix", Subject: "x", Footers: []Footer{{Token: "Reviewed-by", Value: "Z"}}}).Validate())
+}
+
+func TestStructuredCommit_Render(t *testing.T) {
+	assert.Equal(t, "docs: fix typo", StructuredCommit{Type: "docs", Subject: "fix typo"}.Render())
+
+	c := StructuredCommit{
+		Type:           "refactor",
+		Scope:          "config",
+		Subject:        "rename provider settings",
+		Body:           []string{"rename apiKey to key", "update examples"},
+		BreakingChange: "apiKey is no longer read",
+		Footers:        []Footer{{Token: "Refs", Value: "#42"}},
+	}
+	assert.Equal(t, "refactor(config)!: rename provider settings\n\n- rename apiKey to key\n- update examples\n\nBREAKING CHANGE: apiKey is no longer read\nRefs: #42", c.Render())
+}
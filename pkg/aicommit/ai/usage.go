@@ -0,0 +1,40 @@
+package ai
+
+import "context"
+
+// Usage is the token usage reported by a provider for a single request.
+type Usage struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"promptTokens"`
+	CompletionTokens int    `json:"completionTokens"`
+}
+
+// TotalTokens returns prompt + completion tokens.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// IsZero reports whether no tokens were recorded.
+func (u Usage) IsZero() bool {
+	return u.PromptTokens == 0 && u.CompletionTokens == 0
+}
+
+// UsageRecorder receives usage reported by providers.
+type UsageRecorder func(Usage)
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder returns a context whose provider calls report token usage to rec.
+// Providers call ReportUsage once per successful request; clients that cannot
+// determine usage simply never call it.
+func WithUsageRecorder(ctx context.Context, rec UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, rec)
+}
+
+// ReportUsage forwards usage to the recorder attached to ctx, if any.
+func ReportUsage(ctx context.Context, u Usage) {
+	if rec, ok := ctx.Value(usageRecorderKey{}).(UsageRecorder); ok && rec != nil {
+		rec(u)
+	}
+}
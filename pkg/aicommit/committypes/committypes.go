@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
@@ -13,21 +14,29 @@ type commitTypeInfo struct {
 	Emoji string
 }
 
-var commitTypeList []commitTypeInfo
+var (
+	commitTypeMu   sync.RWMutex
+	commitTypeList []commitTypeInfo
+)
 
 // InitCommitTypes resets the known commit type list.
 func InitCommitTypes(cfgTypes []config.CommitTypeConfig) {
-	commitTypeList = commitTypeList[:0]
+	list := make([]commitTypeInfo, 0, len(cfgTypes))
 	for _, t := range cfgTypes {
-		commitTypeList = append(commitTypeList, commitTypeInfo{
+		list = append(list, commitTypeInfo{
 			Type:  strings.TrimSpace(t.Type),
 			Emoji: strings.TrimSpace(t.Emoji),
 		})
 	}
+	commitTypeMu.Lock()
+	defer commitTypeMu.Unlock()
+	commitTypeList = list
 }
 
 // IsValidCommitType returns true if t is in the configured list.
 func IsValidCommitType(t string) bool {
+	commitTypeMu.RLock()
+	defer commitTypeMu.RUnlock()
 	for _, info := range commitTypeList {
 		if info.Type == t {
 			return true
@@ -37,6 +46,8 @@ func IsValidCommitType(t string) bool {
 }
 
 func GetEmojiForType(t string) string {
+	commitTypeMu.RLock()
+	defer commitTypeMu.RUnlock()
 	for _, info := range commitTypeList {
 		if info.Type == t {
 			return info.Emoji
@@ -68,6 +79,8 @@ func GuessCommitType(message string) string {
 
 // TypesRegexPattern builds a safe alternation for all configured types.
 func TypesRegexPattern() string {
+	commitTypeMu.RLock()
+	defer commitTypeMu.RUnlock()
 	if len(commitTypeList) == 0 {
 		return "feat|fix|docs|style|refactor|test|chore|perf|build|ci"
 	}
@@ -87,6 +100,8 @@ func BuildRegexPatternWithEmoji() *regexp.Regexp {
 }
 
 func GetAllTypes() []string {
+	commitTypeMu.RLock()
+	defer commitTypeMu.RUnlock()
 	var results []string
 	for _, info := range commitTypeList {
 		results = append(results, info.Type)
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
@@ -29,6 +30,45 @@ type ProviderSettings struct {
     APIKey  string `yaml:"apiKey,omitempty"`
     Model   string `yaml:"model,omitempty"`
     BaseURL string `yaml:"baseURL,omitempty"`
+    // APIVersion is the API version of providers that require one (Azure OpenAI).
+    APIVersion string           `yaml:"apiVersion,omitempty"`
+    Params     GenerationParams `yaml:"params,omitempty"`
+}
+
+// GenerationParams tunes a generation request. Zero values leave the provider default.
+type GenerationParams struct {
+    // Temperature and TopP are pointers because 0 is a meaningful value.
+    Temperature     *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+    TopP            *float64 `yaml:"topP,omitempty" json:"topP,omitempty"`
+    MaxOutputTokens int      `yaml:"maxOutputTokens,omitempty" json:"maxOutputTokens,omitempty"`
+    SystemPrompt    string   `yaml:"systemPrompt,omitempty" json:"systemPrompt,omitempty"`
+    // Timeout bounds a single request, including a streamed response.
+    Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Merge returns p with the fields set in override replacing its own.
+func (p GenerationParams) Merge(override GenerationParams) GenerationParams {
+    if override.Temperature != nil {
+        p.Temperature = override.Temperature
+    }
+    if override.TopP != nil {
+        p.TopP = override.TopP
+    }
+    if override.MaxOutputTokens > 0 {
+        p.MaxOutputTokens = override.MaxOutputTokens
+    }
+    if strings.TrimSpace(override.SystemPrompt) != "" {
+        p.SystemPrompt = override.SystemPrompt
+    }
+    if override.Timeout > 0 {
+        p.Timeout = override.Timeout
+    }
+    return p
+}
+
+// IsZero reports whether no parameter is set.
+func (p GenerationParams) IsZero() bool {
+    return p == GenerationParams{}
 }
 
 type LimitSettings struct {
@@ -0,0 +1,110 @@
+package httpx
+
+import (
+    "context"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// RetryPolicy describes how failed provider requests are retried: up to
+// MaxAttempts attempts in total, waiting an exponentially growing, jittered
+// delay between attempts.
+type RetryPolicy struct {
+    MaxAttempts int
+    BaseDelay   time.Duration
+    MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+    return RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+}
+
+// Delay returns how long to wait before retrying after the given failed attempt
+// (1-based). A positive retryAfter from the server takes precedence over the
+// computed backoff, capped at MaxDelay.
+func (p RetryPolicy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+    if retryAfter > 0 {
+        if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+            return p.MaxDelay
+        }
+        return retryAfter
+    }
+    backoff := p.BaseDelay
+    for i := 1; i < attempt && (p.MaxDelay <= 0 || backoff < p.MaxDelay); i++ {
+        backoff *= 2
+    }
+    if p.MaxDelay > 0 && backoff > p.MaxDelay {
+        backoff = p.MaxDelay
+    }
+    if backoff <= 0 {
+        return 0
+    }
+    // A random delay in [backoff/2, backoff] keeps concurrent
+    // candidates from retrying in lockstep while still backing off.
+    half := backoff / 2
+    return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// IsRetryableStatus reports whether an HTTP status code indicates a transient failure.
+func IsRetryableStatus(code int) bool {
+    switch code {
+    case http.StatusRequestTimeout, http.StatusTooManyRequests,
+        http.StatusInternalServerError, http.StatusBadGateway,
+        http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+        return true
+    }
+    return false
+}
+
+// ParseRetryAfter parses the Retry-After header (delay in seconds or an HTTP
+// date). It returns 0 when the header is missing or invalid.
+func ParseRetryAfter(h http.Header) time.Duration {
+    v := strings.TrimSpace(h.Get("Retry-After"))
+    if v == "" {
+        return 0
+    }
+    if secs, err := strconv.ParseFloat(v, 64); err == nil {
+        if secs <= 0 {
+            return 0
+        }
+        return time.Duration(secs * float64(time.Second))
+    }
+    if t, err := http.ParseTime(v); err == nil {
+        if d := time.Until(t); d > 0 {
+            return d
+        }
+    }
+    return 0
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first.
+func Sleep(ctx context.Context, d time.Duration) error {
+    if d <= 0 {
+        return ctx.Err()
+    }
+    t := time.NewTimer(d)
+    defer t.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-t.C:
+        return nil
+    }
+}
+
+// StatusError is returned by hand-written HTTP providers for non-2xx responses,
+// so callers can inspect the status code and Retry-After header.
+type StatusError struct {
+    StatusCode int
+    Header     http.Header
+    Message    string
+}
+
+func (e *StatusError) Error() string {
+    return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,56 @@
+package httpx
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+    p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+    for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+        d := p.Delay(attempt, 0)
+        assert.GreaterOrEqual(t, d, max/2, "attempt %d", attempt)
+        assert.LessOrEqual(t, d, max, "attempt %d", attempt)
+    }
+
+    // Retry-After wins over backoff but is capped at MaxDelay.
+    assert.Equal(t, 700*time.Millisecond, p.Delay(1, 700*time.Millisecond))
+    assert.Equal(t, time.Second, p.Delay(1, time.Minute))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    h := http.Header{}
+    assert.Equal(t, time.Duration(0), ParseRetryAfter(h))
+
+    h.Set("Retry-After", "3")
+    assert.Equal(t, 3*time.Second, ParseRetryAfter(h))
+
+    h.Set("Retry-After", "soon")
+    assert.Equal(t, time.Duration(0), ParseRetryAfter(h))
+
+    h.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+    d := ParseRetryAfter(h)
+    assert.Greater(t, d, 50*time.Second)
+    assert.LessOrEqual(t, d, time.Minute)
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+    for _, code := range []int{408, 429, 500, 502, 503, 504} {
+        assert.True(t, IsRetryableStatus(code), "status %d", code)
+    }
+    for _, code := range []int{400, 401, 403, 404, 501} {
+        assert.False(t, IsRetryableStatus(code), "status %d", code)
+    }
+}
+
+func TestSleep_Cancelled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    assert.ErrorIs(t, Sleep(ctx, time.Hour), context.Canceled)
+    assert.NoError(t, Sleep(context.Background(), time.Millisecond))
+}
@@ -8,16 +8,30 @@ import (
     "strings"
 )
 
+// Delta is the text decoded from one SSE chunk.
+type Delta struct {
+    // Content is the answer text.
+    Content string
+    // Reasoning is the text of a separate reasoning field sent by reasoning models.
+    Reasoning string
+}
+
 // ChunkDecoder extracts a text delta from an SSE data payload.
 // It returns (delta, done, ok).
 //  - delta: text to append to the aggregate output
 //  - done:  whether the stream signaled completion
 //  - ok:    whether this payload was recognized/consumed
-type ChunkDecoder func(data []byte) (delta string, done bool, ok bool)
+type ChunkDecoder func(data []byte) (delta Delta, done bool, ok bool)
 
 // StreamAggregate reads text/event-stream content from r, calls decode for each
-// `data:` line, and aggregates the text deltas until completion or EOF.
+// `data:` line, and aggregates the content deltas until completion or EOF.
 func StreamAggregate(ctx context.Context, r io.Reader, decode ChunkDecoder) (string, error) {
+    return StreamDeltas(ctx, r, decode, nil)
+}
+
+// StreamDeltas is StreamAggregate that also passes every decoded delta to onDelta,
+// if not nil. Only the content is aggregated.
+func StreamDeltas(ctx context.Context, r io.Reader, decode ChunkDecoder, onDelta func(Delta)) (string, error) {
     scanner := bufio.NewScanner(r)
     // Increase buffer to accommodate larger SSE chunks.
     const maxBuf = 1024 * 1024
@@ -40,8 +54,9 @@ func StreamAggregate(ctx context.Context, r io.Reader, decode ChunkDecoder) (str
             break
         }
         if delta, done, ok := decode([]byte(payload)); ok {
-            if delta != "" {
-                out.WriteString(delta)
+            out.WriteString(delta.Content)
+            if onDelta != nil && (delta.Content != "" || delta.Reasoning != "") {
+                onDelta(delta)
             }
             if done {
                 break
@@ -57,26 +72,34 @@ func StreamAggregate(ctx context.Context, r io.Reader, decode ChunkDecoder) (str
 
 // OpenAIStyleDecoder decodes typical OpenAI-like SSE chunks where the payload
 // is a JSON object with `choices[0].delta.content` and optional `type:"metadata"`.
-func OpenAIStyleDecoder(data []byte) (string, bool, bool) {
+// The reasoning of reasoning models is read from `reasoning_content` (DeepSeek,
+// vLLM) or `reasoning` (OpenRouter, Ollama).
+func OpenAIStyleDecoder(data []byte) (Delta, bool, bool) {
     var sr struct {
         Type    string `json:"type"`
         Choices []struct {
             Delta struct {
-                Content string `json:"content"`
+                Content          string `json:"content"`
+                ReasoningContent string `json:"reasoning_content"`
+                Reasoning        string `json:"reasoning"`
             } `json:"delta"`
             FinishReason *string `json:"finish_reason"`
         } `json:"choices"`
     }
     if err := json.Unmarshal(data, &sr); err != nil {
-        return "", false, false
+        return Delta{}, false, false
     }
     if sr.Type == "metadata" {
-        return "", false, true
+        return Delta{}, false, true
     }
     if len(sr.Choices) == 0 {
-        return "", false, true
+        return Delta{}, false, true
+    }
+    d := sr.Choices[0].Delta
+    delta := Delta{Content: d.Content, Reasoning: d.ReasoningContent}
+    if delta.Reasoning == "" {
+        delta.Reasoning = d.Reasoning
     }
-    delta := sr.Choices[0].Delta.Content
     done := sr.Choices[0].FinishReason != nil && *sr.Choices[0].FinishReason != ""
     return delta, done, true
 }
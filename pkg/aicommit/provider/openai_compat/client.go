@@ -2,13 +2,16 @@ package openai_compat
 
 import (
     "context"
+    "encoding/json"
     "errors"
     "fmt"
     "strings"
 
     openai "github.com/openai/openai-go/v2"
     "github.com/openai/openai-go/v2/option"
+    "github.com/openai/openai-go/v2/packages/respjson"
     "github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+    "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
 
 // Client is a reusable OpenAI-compatible client (OpenAI, DeepSeek, etc.).
@@ -17,33 +20,34 @@ type Client struct {
     ai.BaseAIClient
     client openai.Client
     model  string
+    // JSONSchema selects the json_schema response format for structured output.
+    // Otherwise the json_object format is used, which more compatible servers support.
+    JSONSchema bool
+    // MaxCompletionTokens sends the output limit as max_completion_tokens instead of
+    // the max_tokens parameter OpenAI deprecated but other compatible servers expect.
+    MaxCompletionTokens bool
+    // Params are applied to every request.
+    Params config.GenerationParams
 }
 
-func NewCompatClient(provider, apiKey, model, baseURL string) *Client {
-    // Build client with provided options.
-    switch {
-    case strings.TrimSpace(apiKey) != "" && strings.TrimSpace(baseURL) != "":
-        c := openai.NewClient(option.WithAPIKey(apiKey), option.WithBaseURL(strings.TrimRight(baseURL, "/")))
-        return &Client{BaseAIClient: ai.BaseAIClient{Provider: provider}, client: c, model: model}
-    case strings.TrimSpace(apiKey) != "":
-        c := openai.NewClient(option.WithAPIKey(apiKey))
-        return &Client{BaseAIClient: ai.BaseAIClient{Provider: provider}, client: c, model: model}
-    case strings.TrimSpace(baseURL) != "":
-        c := openai.NewClient(option.WithBaseURL(strings.TrimRight(baseURL, "/")))
-        return &Client{BaseAIClient: ai.BaseAIClient{Provider: provider}, client: c, model: model}
-    default:
-        c := openai.NewClient()
-        return &Client{BaseAIClient: ai.BaseAIClient{Provider: provider}, client: c, model: model}
+// NewCompatClient creates a client for provider. Empty apiKey or baseURL fall back to
+// the SDK defaults; extra request options (e.g. custom headers) are applied last.
+func NewCompatClient(provider, apiKey, model, baseURL string, opts ...option.RequestOption) *Client {
+    // Retries are handled by the caller's retry policy, not the SDK.
+    clientOpts := []option.RequestOption{option.WithMaxRetries(0)}
+    if strings.TrimSpace(apiKey) != "" {
+        clientOpts = append(clientOpts, option.WithAPIKey(apiKey))
     }
+    if strings.TrimSpace(baseURL) != "" {
+        clientOpts = append(clientOpts, option.WithBaseURL(strings.TrimRight(baseURL, "/")))
+    }
+    clientOpts = append(clientOpts, opts...)
+    c := openai.NewClient(clientOpts...)
+    return &Client{BaseAIClient: ai.BaseAIClient{Provider: provider}, client: c, model: model}
 }
 
 func (c *Client) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
-    params := openai.ChatCompletionNewParams{
-        Messages: []openai.ChatCompletionMessageParamUnion{
-            openai.UserMessage(prompt),
-        },
-        Model: openai.ChatModel(c.model),
-    }
+    params := c.newParams(prompt)
     resp, err := c.client.Chat.Completions.New(ctx, params)
     if err != nil {
         return "", fmt.Errorf("failed to get chat completion: %w", err)
@@ -51,39 +55,147 @@ func (c *Client) GetCommitMessage(ctx context.Context, prompt string) (string, e
     if len(resp.Choices) == 0 {
         return "", errors.New("no response from OpenAI-compatible provider")
     }
-    return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+    c.reportUsage(ctx, resp.Model, resp.Usage)
+    msg := resp.Choices[0].Message
+    return answer(ctx, msg.Content, reasoningOf(msg.JSON.ExtraFields)), nil
 }
 
 // StreamCommitMessage streams text deltas via onDelta and returns the final text.
 func (c *Client) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(string)) (string, error) {
-    params := openai.ChatCompletionNewParams{
-        Messages: []openai.ChatCompletionMessageParamUnion{
-            openai.UserMessage(prompt),
-        },
-        Model: openai.ChatModel(c.model),
-    }
+    params := c.newParams(prompt)
+    // Ask for the final usage chunk so token usage can be reported.
+    params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
     stream := c.client.Chat.Completions.NewStreaming(ctx, params)
     acc := openai.ChatCompletionAccumulator{}
+    // Reasoning models stream their reasoning apart from the answer deltas.
+    splitter := ai.NewReasoningSplitter(ctx, onDelta)
     for stream.Next() {
         chunk := stream.Current()
         acc.AddChunk(chunk)
         if len(chunk.Choices) > 0 {
-            if d := chunk.Choices[0].Delta.Content; d != "" {
-                onDelta(d)
-            }
+            delta := chunk.Choices[0].Delta
+            splitter.Reasoning(reasoningOf(delta.JSON.ExtraFields))
+            splitter.Content(delta.Content)
         }
     }
+    splitter.Flush()
     if err := stream.Err(); err != nil {
         // Return whatever was accumulated with error
         if len(acc.Choices) > 0 {
-            return acc.Choices[0].Message.Content, err
+            text, _ := ai.StripReasoning(acc.Choices[0].Message.Content)
+            return text, err
         }
         return "", err
     }
     if len(acc.Choices) == 0 {
         return "", errors.New("no response from OpenAI-compatible provider")
     }
-    return acc.Choices[0].Message.Content, nil
+    c.reportUsage(ctx, acc.Model, acc.Usage)
+    text, _ := ai.StripReasoning(acc.Choices[0].Message.Content)
+    return text, nil
+}
+
+// GenerateStructured asks for a JSON object matching schema using the response_format parameter.
+func (c *Client) GenerateStructured(ctx context.Context, prompt, name string, schema *ai.Schema) (string, error) {
+    params := c.newParams(prompt)
+    if c.JSONSchema {
+        params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+            OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+                JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+                    Name:   name,
+                    Schema: schema,
+                    Strict: openai.Bool(true),
+                },
+            },
+        }
+    } else {
+        params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+            OfJSONObject: &openai.ResponseFormatJSONObjectParam{},
+        }
+    }
+    resp, err := c.client.Chat.Completions.New(ctx, params)
+    if err != nil {
+        return "", fmt.Errorf("failed to get structured chat completion: %w", err)
+    }
+    if len(resp.Choices) == 0 {
+        return "", errors.New("no response from OpenAI-compatible provider")
+    }
+    c.reportUsage(ctx, resp.Model, resp.Usage)
+    msg := resp.Choices[0].Message
+    return answer(ctx, msg.Content, reasoningOf(msg.JSON.ExtraFields)), nil
+}
+
+// answer returns content without <think> blocks and reports them, and the reasoning
+// field of the message, as reasoning.
+func answer(ctx context.Context, content, reasoning string) string {
+    ai.ReportReasoning(ctx, reasoning)
+    text, thought := ai.StripReasoning(content)
+    ai.ReportReasoning(ctx, thought)
+    return text
+}
+
+// reasoningOf returns the reasoning that reasoning models add to a message or delta
+// as reasoning_content (DeepSeek, vLLM) or reasoning (OpenRouter, Ollama).
+func reasoningOf(extra map[string]respjson.Field) string {
+    for _, key := range []string{"reasoning_content", "reasoning"} {
+        var s string
+        if f, ok := extra[key]; ok && json.Unmarshal([]byte(f.Raw()), &s) == nil && s != "" {
+            return s
+        }
+    }
+    return ""
+}
+
+// newParams builds a chat request for prompt with the configured generation parameters.
+func (c *Client) newParams(prompt string) openai.ChatCompletionNewParams {
+    var messages []openai.ChatCompletionMessageParamUnion
+    if strings.TrimSpace(c.Params.SystemPrompt) != "" {
+        messages = append(messages, openai.SystemMessage(c.Params.SystemPrompt))
+    }
+    params := openai.ChatCompletionNewParams{
+        Messages: append(messages, openai.UserMessage(prompt)),
+        Model:    openai.ChatModel(c.model),
+    }
+    if c.Params.Temperature != nil {
+        params.Temperature = openai.Float(*c.Params.Temperature)
+    }
+    if c.Params.TopP != nil {
+        params.TopP = openai.Float(*c.Params.TopP)
+    }
+    if n := int64(c.Params.MaxOutputTokens); n > 0 {
+        if c.MaxCompletionTokens {
+            params.MaxCompletionTokens = openai.Int(n)
+        } else {
+            params.MaxTokens = openai.Int(n)
+        }
+    }
+    return params
+}
+
+// reportUsage forwards token usage from the response to the recorder in ctx.
+func (c *Client) reportUsage(ctx context.Context, model string, usage openai.CompletionUsage) {
+    if model == "" {
+        model = c.model
+    }
+    ai.ReportUsage(ctx, ai.Usage{
+        Provider:         c.ProviderName(),
+        Model:            model,
+        PromptTokens:     int(usage.PromptTokens),
+        CompletionTokens: int(usage.CompletionTokens),
+    })
+}
+
+// ListModels returns the model IDs from the provider's /models endpoint.
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+    iter := c.client.Models.ListAutoPaging(ctx)
+    var models []string
+    for iter.Next() {
+        models = append(models, iter.Current().ID)
+    }
+    if err := iter.Err(); err != nil {
+        return nil, fmt.Errorf("failed to list models: %w", err)
+    }
+    return models, nil
 }
 
 func (c *Client) SanitizeResponse(message, commitType string) string {
@@ -96,3 +208,5 @@ func (c *Client) MaybeSummarizeDiff(diff string, maxLength int) (string, bool) {
 
 var _ ai.AIClient = (*Client)(nil)
 var _ ai.StreamingAIClient = (*Client)(nil)
+var _ ai.ModelLister = (*Client)(nil)
+var _ ai.StructuredOutputClient = (*Client)(nil)
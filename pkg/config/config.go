@@ -5,13 +5,19 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
+
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
 
 const (
     DefaultProvider         = "phind"
+    DefaultCacheTTL         = 24 * time.Hour
+    DefaultCacheMaxEntries  = 200
+    DefaultAuditMaxEntries  = 1000
 )
 
 var (
@@ -27,13 +33,66 @@ type CommitTypeConfig struct {
 // ProviderSettings holds credentials and routing for a provider.
 type ProviderSettings struct {
     APIKey  string `yaml:"apiKey,omitempty"`
+    // APIKeys lists additional keys. When a key is rejected (401/403) or rate limited
+    // (429) the next usable key is tried; APIKey, if set, is tried first.
+    APIKeys []APIKeyEntry `yaml:"apiKeys,omitempty"`
     Model   string `yaml:"model,omitempty"`
     BaseURL string `yaml:"baseURL,omitempty"`
+    // APIVersion is the API version of providers that require one, such as the
+    // api-version of Azure OpenAI.
+    APIVersion string `yaml:"apiVersion,omitempty"`
+    // ContextTokens is the model's context window in tokens. Diffs that do not fit are
+    // summarized per file, in chunks sized to the window. 0 uses the window registered
+    // for the provider, or Limits.Diff.MaxChars when that is unknown too.
+    ContextTokens int `yaml:"contextTokens,omitempty"`
+    // Params sets temperature, output length, system prompt and request timeout.
+    // A project can override them; see models.GitProject.GenerationParams.
+    Params aicommitconfig.GenerationParams `yaml:"params,omitempty"`
+}
+
+// APIKeyEntry is one of several API keys of a provider.
+type APIKeyEntry struct {
+    Key   string `yaml:"key"`
+    Label string `yaml:"label,omitempty"`
+    // Quota is the number of requests per day the key may be used for; 0 is unlimited.
+    Quota int `yaml:"quota,omitempty"`
+}
+
+// Keys returns the configured keys in the order they are tried, with APIKey first.
+// Empty keys are skipped.
+func (p ProviderSettings) Keys() []APIKeyEntry {
+    keys := make([]APIKeyEntry, 0, len(p.APIKeys)+1)
+    if p.APIKey != "" {
+        keys = append(keys, APIKeyEntry{Key: p.APIKey})
+    }
+    for _, k := range p.APIKeys {
+        if k.Key != "" {
+            keys = append(keys, k)
+        }
+    }
+    return keys
+}
+
+// ModelPrice is the price of a model in USD per one million tokens.
+type ModelPrice struct {
+    Input  float64 `yaml:"input"`
+    Output float64 `yaml:"output"`
 }
 
+const (
+    // LimitStrategySummarize summarizes an oversized diff per file before generating (default).
+    LimitStrategySummarize = "summarize"
+    // LimitStrategyTrim cuts an oversized diff down to MaxChars, sharing the budget across files.
+    LimitStrategyTrim = "trim"
+)
+
+// LimitSettings caps the size of an input. For the diff, low-signal files are dropped
+// first; if it still exceeds MaxChars, Strategy decides whether it is summarized per
+// file or trimmed. An oversized prompt always has its diff trimmed to fit.
 type LimitSettings struct {
-    Enabled  bool `yaml:"enabled,omitempty"`
-    MaxChars int  `yaml:"maxChars,omitempty"`
+    Enabled  bool   `yaml:"enabled,omitempty"`
+    MaxChars int    `yaml:"maxChars,omitempty"`
+    Strategy string `yaml:"strategy,omitempty"`
 }
 
 type Limits struct {
@@ -41,6 +100,46 @@ type Limits struct {
     Prompt LimitSettings `yaml:"prompt,omitempty"`
 }
 
+// CustomProvider declares an OpenAI-compatible provider (vLLM, LiteLLM, LM Studio, ...)
+// that is registered at runtime next to the built-in providers.
+type CustomProvider struct {
+    Name           string            `yaml:"name"`
+    BaseURL        string            `yaml:"baseURL"`
+    Model          string            `yaml:"model,omitempty"`
+    APIKey         string            `yaml:"apiKey,omitempty"`
+    Headers        map[string]string `yaml:"headers,omitempty"`
+    RequiresAPIKey bool              `yaml:"requiresAPIKey,omitempty"`
+}
+
+// RetrySettings controls retries of transient provider failures (429, 5xx, timeouts).
+// MaxAttempts counts the first attempt; 1 disables retries. Zero values fall back
+// to the defaults in httpx.DefaultRetryPolicy.
+type RetrySettings struct {
+    MaxAttempts int           `yaml:"maxAttempts,omitempty"`
+    BaseDelay   time.Duration `yaml:"baseDelay,omitempty"`
+    MaxDelay    time.Duration `yaml:"maxDelay,omitempty"`
+}
+
+// CacheSettings controls the cache of generated messages for identical inputs.
+// Zero TTL and MaxEntries fall back to DefaultCacheTTL and DefaultCacheMaxEntries.
+type CacheSettings struct {
+    Disabled   bool          `yaml:"disabled,omitempty"`
+    TTL        time.Duration `yaml:"ttl,omitempty"`
+    MaxEntries int           `yaml:"maxEntries,omitempty"`
+}
+
+// AuditSettings controls the local audit log of provider requests and responses.
+// A zero MaxEntries falls back to DefaultAuditMaxEntries.
+type AuditSettings struct {
+    // Enabled records the prompt, raw response and result of every provider request
+    // in the local database. Off by default.
+    Enabled bool `yaml:"enabled,omitempty"`
+    // Redact lists regular expressions whose matches are masked before an entry is
+    // stored. Configured API keys are always masked.
+    Redact     []string `yaml:"redact,omitempty"`
+    MaxEntries int      `yaml:"maxEntries,omitempty"`
+}
+
 type PromptFiles struct {
     CommitMessage string `yaml:"commitMessage,omitempty"`
     CodeReview    string `yaml:"codeReview,omitempty"`
@@ -64,12 +163,37 @@ type Config struct {
     // Enterprise-style provider configuration. Preferred over legacy flat fields below.
     Providers map[string]ProviderSettings `yaml:"providers,omitempty"`
 
+    // CustomProviders declares additional OpenAI-compatible providers. Their settings
+    // can be overridden per name in Providers like any built-in provider.
+    CustomProviders []CustomProvider `yaml:"customProviders,omitempty"`
+
+    // FallbackProviders is the ordered list of providers tried when the selected
+    // provider fails (down, rate-limited, timed out or empty response).
+    FallbackProviders []string `yaml:"fallbackProviders,omitempty"`
+
+    // Pricing maps model name to its token price, used for cost accounting.
+    Pricing map[string]ModelPrice `yaml:"pricing,omitempty"`
+
+    // Retry configures retries of transient provider failures.
+    Retry RetrySettings `yaml:"retry,omitempty"`
+
+    // Cache configures reuse of generated messages for repeated generations on the same diff.
+    Cache CacheSettings `yaml:"cache,omitempty"`
+
+    // Audit configures the opt-in log of prompts and responses, for debugging bad messages.
+    Audit AuditSettings `yaml:"audit,omitempty"`
+
     // Prompt files configuration
     Prompts PromptFiles `yaml:"prompts,omitempty"`
 
     // Deprecated: Use Prompts.CommitMessage instead
     PromptTemplate string `yaml:"promptTemplate,omitempty"`
 
+    // UnresolvedRefs maps a provider name to the reason a reference in its settings
+    // (${ENV_VAR}, file: or a stored secret) could not be resolved. It is filled when
+    // the config is loaded and never written to the file.
+    UnresolvedRefs map[string]string `yaml:"-"`
+
 	AuthorName  string `yaml:"authorName,omitempty"`
 	AuthorEmail string `yaml:"authorEmail,omitempty"`
 }
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileRefPrefix marks a value that is read from a file, e.g. "file:/run/secrets/openai".
+// Relative paths are resolved against the config directory and "~/" against the home
+// directory. Trailing whitespace and newlines of the file are removed.
+const FileRefPrefix = "file:"
+
+// envRefPattern matches ${ENV_VAR} references, which may appear anywhere in a value,
+// e.g. "https://${AZURE_RESOURCE}.openai.azure.com".
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ErrEnvNotSet is wrapped by the error of a reference to an unset environment variable.
+var ErrEnvNotSet = errors.New("environment variable not set")
+
+// RefError describes a reference that could not be resolved.
+type RefError struct {
+	// Env is the environment variable of a ${ENV_VAR} reference; empty for file references.
+	Env string
+	// File is the path of a file: reference.
+	File string
+	Err  error
+}
+
+func (e *RefError) Error() string {
+	if e.Env != "" {
+		return fmt.Sprintf("${%s}: %v", e.Env, e.Err)
+	}
+	return fmt.Sprintf("%s%s: %v", FileRefPrefix, e.File, e.Err)
+}
+
+func (e *RefError) Unwrap() error { return e.Err }
+
+// IsRef reports whether value contains a ${ENV_VAR} or file: reference.
+func IsRef(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), FileRefPrefix) || envRefPattern.MatchString(value)
+}
+
+// ResolveRef returns value with its ${ENV_VAR} references replaced by the environment
+// variables, or the contents of the file of a file: reference. Variables that are set
+// to an empty string count as not set. baseDir is the directory relative file paths
+// are resolved against.
+func ResolveRef(value, baseDir string) (string, error) {
+	if path, ok := strings.CutPrefix(strings.TrimSpace(value), FileRefPrefix); ok {
+		return readFileRef(path, baseDir)
+	}
+	var refErr error
+	resolved := envRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envRefPattern.FindStringSubmatch(ref)[1]
+		v := os.Getenv(name)
+		if v == "" && refErr == nil {
+			refErr = &RefError{Env: name, Err: ErrEnvNotSet}
+		}
+		return v
+	})
+	if refErr != nil {
+		return "", refErr
+	}
+	return resolved, nil
+}
+
+func readFileRef(path, baseDir string) (string, error) {
+	resolved := path
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			resolved = filepath.Join(home, rest)
+		}
+	} else if !filepath.IsAbs(path) && baseDir != "" {
+		resolved = filepath.Join(baseDir, path)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", &RefError{File: path, Err: err}
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+// ResolveNodeRefs resolves the references in all scalar values under node (see
+// ResolveRef), so that references also work for numeric and boolean fields once the
+// node is decoded. Values that cannot be resolved are set to empty; the error of the
+// first one is returned.
+func ResolveNodeRefs(node *yaml.Node, baseDir string) error {
+	if node == nil {
+		return nil
+	}
+	var firstErr error
+	if node.Kind == yaml.ScalarNode && IsRef(node.Value) {
+		resolved, err := ResolveRef(node.Value, baseDir)
+		if err != nil {
+			firstErr = err
+		}
+		// Let the decoder infer the type of the resolved value, e.g. a number.
+		node.Value, node.Tag, node.Style = resolved, "", 0
+		if resolved == "" {
+			node.Tag = "!!null"
+		}
+	}
+	for _, child := range node.Content {
+		if err := ResolveNodeRefs(child, baseDir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
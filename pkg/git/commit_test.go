@@ -3,6 +3,8 @@ package git
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/allanpk716/ai-commit-hub/tests/helpers"
@@ -13,11 +15,7 @@ func TestCommitChanges_Success(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 	repo.CreateStagedChange(t, "test.txt", "content")
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	err := CommitChanges(context.Background(), "test: add file")
+	err := CommitChanges(context.Background(), repo.Path, "test: add file")
 
 	assert.NoError(t, err)
 	helpers.AssertRepoClean(t, repo)
@@ -27,12 +25,8 @@ func TestCommitChanges_EmptyMessage(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 	repo.CreateStagedChange(t, "test.txt", "content")
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
 	// 有变更但消息为空
-	err := CommitChanges(context.Background(), "")
+	err := CommitChanges(context.Background(), repo.Path, "")
 
 	// go-git 允许空消息
 	assert.NoError(t, err)
@@ -42,13 +36,9 @@ func TestGetHeadCommitMessage_Success(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 	repo.CreateStagedChange(t, "test.txt", "content")
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	CommitChanges(context.Background(), "feat: my commit message")
+	CommitChanges(context.Background(), repo.Path, "feat: my commit message")
 
-	msg, err := GetHeadCommitMessage(context.Background())
+	msg, err := GetHeadCommitMessage(context.Background(), repo.Path)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "feat: my commit message", msg)
@@ -57,11 +47,7 @@ func TestGetHeadCommitMessage_Success(t *testing.T) {
 func TestGetHeadCommitMessage_InitialCommit(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	msg, err := GetHeadCommitMessage(context.Background())
+	msg, err := GetHeadCommitMessage(context.Background(), repo.Path)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "init", msg)
@@ -70,11 +56,7 @@ func TestGetHeadCommitMessage_InitialCommit(t *testing.T) {
 func TestGetCurrentBranch_Success(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	branch, err := GetCurrentBranch(context.Background())
+	branch, err := GetCurrentBranch(context.Background(), repo.Path)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, branch)
@@ -85,22 +67,53 @@ func TestGetCurrentBranch_Success(t *testing.T) {
 func TestCommitChanges_MultipleCommits(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
 	// 第一次提交
 	repo.CreateStagedChange(t, "file1.txt", "content1")
-	err := CommitChanges(context.Background(), "feat: first commit")
+	err := CommitChanges(context.Background(), repo.Path, "feat: first commit")
 	assert.NoError(t, err)
 
 	// 第二次提交
 	repo.CreateStagedChange(t, "file2.txt", "content2")
-	err = CommitChanges(context.Background(), "feat: second commit")
+	err = CommitChanges(context.Background(), repo.Path, "feat: second commit")
 	assert.NoError(t, err)
 
 	// 验证最新的提交消息
-	msg, err := GetHeadCommitMessage(context.Background())
+	msg, err := GetHeadCommitMessage(context.Background(), repo.Path)
 	assert.NoError(t, err)
 	assert.Equal(t, "feat: second commit", msg)
 }
+
+func TestCommitChanges_ParallelRepos(t *testing.T) {
+	repoA := helpers.SetupTestRepo(t)
+	repoB := helpers.SetupTestRepo(t)
+	repoA.CreateStagedChange(t, "a.txt", "a")
+	repoB.CreateStagedChange(t, "b.txt", "b")
+
+	wd, _ := os.Getwd()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, r := range []*helpers.TestRepo{repoA, repoB} {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			errs[i] = CommitChanges(context.Background(), path, "feat: commit "+filepath.Base(path))
+		}(i, r.Path)
+	}
+	wg.Wait()
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+
+	msgA, err := GetHeadCommitMessage(context.Background(), repoA.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "feat: commit "+filepath.Base(repoA.Path), msgA)
+
+	msgB, err := GetHeadCommitMessage(context.Background(), repoB.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "feat: commit "+filepath.Base(repoB.Path), msgB)
+
+	// 不应修改进程工作目录
+	after, _ := os.Getwd()
+	assert.Equal(t, wd, after)
+}
@@ -0,0 +1,394 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DiffTrimReport describes what was cut from a diff to fit a size budget.
+type DiffTrimReport struct {
+	OriginalChars int `json:"originalChars"`
+	FinalChars    int `json:"finalChars"`
+	// OmittedFiles are files whose changes were dropped entirely (lock, generated or vendored files).
+	OmittedFiles []string `json:"omittedFiles,omitempty"`
+	// TruncatedFiles are files cut down to their share of the budget.
+	TruncatedFiles []string `json:"truncatedFiles,omitempty"`
+}
+
+// Trimmed reports whether anything was cut.
+func (r DiffTrimReport) Trimmed() bool {
+	return len(r.OmittedFiles) > 0 || len(r.TruncatedFiles) > 0 || r.FinalChars < r.OriginalChars
+}
+
+// FileDiff is the section of a unified diff that belongs to one file.
+type FileDiff struct {
+	Path string
+	// Header holds the lines before the first hunk ("diff --git", "index", "---", "+++", ...).
+	Header []string
+	Hunks  []DiffChunk
+}
+
+// String renders the file section back to unified diff text.
+func (f FileDiff) String() string {
+	var sb strings.Builder
+	for _, line := range f.Header {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	for _, h := range f.Hunks {
+		sb.WriteString(renderHunk(h))
+	}
+	return sb.String()
+}
+
+// ChangedLines returns the number of added and removed lines in the file.
+func (f FileDiff) ChangedLines() int {
+	n := 0
+	for _, h := range f.Hunks {
+		n += changedLines(h)
+	}
+	return n
+}
+
+// FileStat summarizes the changes to one file in a diff.
+type FileStat struct {
+	Path string
+	// Status is "added", "deleted", "renamed" or "modified".
+	Status  string
+	Added   int
+	Deleted int
+}
+
+// DiffFileStats returns the added and deleted line counts of each file in a unified diff.
+func DiffFileStats(diff string) []FileStat {
+	files := SplitDiffByFile(diff)
+	stats := make([]FileStat, 0, len(files))
+	for _, f := range files {
+		stat := FileStat{Path: f.Path, Status: "modified"}
+		for _, line := range f.Header {
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				stat.Status = "added"
+			case strings.HasPrefix(line, "deleted file mode"):
+				stat.Status = "deleted"
+			case strings.HasPrefix(line, "rename from"):
+				stat.Status = "renamed"
+			}
+		}
+		for _, h := range f.Hunks {
+			for _, line := range h.Lines {
+				if strings.HasPrefix(line, "+") {
+					stat.Added++
+				} else if strings.HasPrefix(line, "-") {
+					stat.Deleted++
+				}
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// SplitDiffByFile splits a unified diff into per-file sections, keeping the
+// file headers that ParseDiffToChunks drops. Text before the first file header is ignored.
+func SplitDiffByFile(diff string) []FileDiff {
+	var files []FileDiff
+	var current *FileDiff
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, FileDiff{Path: parseFilePath(line), Header: []string{line}})
+			current = &files[len(files)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.HasPrefix(line, "@@ ") {
+			current.Hunks = append(current.Hunks, DiffChunk{FilePath: current.Path, HunkHeader: line})
+			continue
+		}
+		if len(current.Hunks) == 0 {
+			current.Header = append(current.Header, line)
+			continue
+		}
+		last := &current.Hunks[len(current.Hunks)-1]
+		last.Lines = append(last.Lines, line)
+	}
+	return files
+}
+
+// lowSignalNames are lock and dependency manifest files whose diffs rarely explain a change.
+var lowSignalNames = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"Pipfile.lock":      true,
+	"composer.lock":     true,
+	"Gemfile.lock":      true,
+}
+
+// lowSignalDirs are path segments of vendored, installed or built code.
+var lowSignalDirs = []string{"vendor", "node_modules", "third_party", "dist", "wailsjs"}
+
+// lowSignalSuffixes are file name suffixes of generated or minified files.
+var lowSignalSuffixes = []string{".pb.go", "_generated.go", ".gen.go", ".min.js", ".min.css", ".map", ".snap"}
+
+// IsLowSignalFile reports whether the file is a lock, generated or vendored file,
+// the first candidates to drop when a diff is over budget.
+func IsLowSignalFile(filePath string) bool {
+	base := path.Base(filePath)
+	if lowSignalNames[base] || strings.HasPrefix(base, "zz_generated") {
+		return true
+	}
+	for _, suffix := range lowSignalSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	for _, segment := range strings.Split(path.Dir(filePath), "/") {
+		for _, dir := range lowSignalDirs {
+			if segment == dir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OmitLowSignalFiles drops low-signal files (see IsLowSignalFile), largest first, until diff
+// fits in maxChars. Dropped files keep their "diff --git" line and a marker so the model
+// still sees that they changed. The result may still exceed maxChars.
+func OmitLowSignalFiles(diff string, maxChars int) (string, DiffTrimReport) {
+	b := newDiffBudget(diff, maxChars)
+	b.omitLowSignal()
+	return b.result()
+}
+
+// TrimDiffToBudget shrinks diff to at most maxChars characters.
+// Low-signal files are dropped first as in OmitLowSignalFiles. If the diff is still too
+// large, the remaining budget is shared fairly across files: files under their share are
+// kept whole, larger files keep their hunk headers and the most-changed hunks.
+// Text that is not a diff is cut at a line boundary.
+func TrimDiffToBudget(diff string, maxChars int) (string, DiffTrimReport) {
+	b := newDiffBudget(diff, maxChars)
+	b.omitLowSignal()
+	b.shareFairly()
+	return b.result()
+}
+
+// diffBudget tracks the rendered text of each file while a diff is trimmed.
+type diffBudget struct {
+	diff     string
+	maxChars int
+	files    []FileDiff
+	texts    []string
+	omitted  map[int]bool
+	total    int
+	// strict is set once the budget must be met, not just approached.
+	strict bool
+	report DiffTrimReport
+}
+
+func newDiffBudget(diff string, maxChars int) *diffBudget {
+	b := &diffBudget{
+		diff:     diff,
+		maxChars: maxChars,
+		omitted:  make(map[int]bool),
+		report:   DiffTrimReport{OriginalChars: len(diff), FinalChars: len(diff)},
+	}
+	if !b.over(len(diff)) {
+		return b
+	}
+	b.files = SplitDiffByFile(diff)
+	b.texts = make([]string, len(b.files))
+	for i, f := range b.files {
+		b.texts[i] = f.String()
+		b.total += len(b.texts[i])
+	}
+	return b
+}
+
+func (b *diffBudget) over(size int) bool {
+	return b.maxChars > 0 && size > b.maxChars
+}
+
+func (b *diffBudget) omitLowSignal() {
+	var lowSignal []int
+	for i, f := range b.files {
+		if IsLowSignalFile(f.Path) {
+			lowSignal = append(lowSignal, i)
+		}
+	}
+	sort.SliceStable(lowSignal, func(x, y int) bool { return len(b.texts[lowSignal[x]]) > len(b.texts[lowSignal[y]]) })
+	for _, i := range lowSignal {
+		if !b.over(b.total) {
+			return
+		}
+		stub := omittedFileStub(b.files[i])
+		b.total += len(stub) - len(b.texts[i])
+		b.texts[i] = stub
+		b.omitted[i] = true
+		b.report.OmittedFiles = append(b.report.OmittedFiles, b.files[i].Path)
+	}
+}
+
+func (b *diffBudget) shareFairly() {
+	b.strict = true
+	if len(b.files) == 0 || !b.over(b.total) {
+		return
+	}
+	var kept []int
+	budget := b.maxChars
+	for i := range b.files {
+		if b.omitted[i] {
+			budget -= len(b.texts[i])
+		} else {
+			kept = append(kept, i)
+		}
+	}
+	// Smallest files first: each takes at most an equal share of what is left,
+	// so the unused share of small files goes to the larger ones.
+	sort.SliceStable(kept, func(x, y int) bool { return len(b.texts[kept[x]]) < len(b.texts[kept[y]]) })
+	for n, i := range kept {
+		share := 0
+		if budget > 0 {
+			share = budget / (len(kept) - n)
+		}
+		if len(b.texts[i]) > share {
+			b.total -= len(b.texts[i])
+			b.texts[i] = trimFileDiff(b.files[i], share)
+			b.total += len(b.texts[i])
+			b.report.TruncatedFiles = append(b.report.TruncatedFiles, b.files[i].Path)
+		}
+		budget -= len(b.texts[i])
+	}
+	sort.Strings(b.report.TruncatedFiles)
+}
+
+func (b *diffBudget) result() (string, DiffTrimReport) {
+	trimmed := b.diff
+	if len(b.report.OmittedFiles) > 0 || len(b.report.TruncatedFiles) > 0 {
+		trimmed = strings.Join(b.texts, "")
+	}
+	// Stubs and hunk headers may still overflow, and text that is not a diff has no files to share.
+	if b.strict && b.over(len(trimmed)) {
+		trimmed = truncateText(trimmed, b.maxChars)
+	}
+	b.report.FinalChars = len(trimmed)
+	return trimmed, b.report
+}
+
+// trimFileDiff renders f in at most maxChars characters: the file header, every hunk
+// header, and as many hunk bodies as fit, preferring the hunks with the most changed lines.
+func trimFileDiff(f FileDiff, maxChars int) string {
+	header := FileDiff{Path: f.Path, Header: f.Header}.String()
+	omittedHunks := make([]string, len(f.Hunks))
+	size := len(header)
+	for i, h := range f.Hunks {
+		omittedHunks[i] = fmt.Sprintf("%s\n[... %d lines omitted ...]\n", h.HunkHeader, len(h.Lines))
+		size += len(omittedHunks[i])
+	}
+	if size > maxChars {
+		// Not even the hunk headers fit: keep the file header only.
+		return omittedFileStub(f)
+	}
+
+	order := make([]int, len(f.Hunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return changedLines(f.Hunks[order[a]]) > changedLines(f.Hunks[order[b]]) })
+
+	rendered := append([]string(nil), omittedHunks...)
+	remaining := maxChars - size
+	partial := -1
+	for _, i := range order {
+		full := renderHunk(f.Hunks[i])
+		if cost := len(full) - len(omittedHunks[i]); cost <= remaining {
+			rendered[i] = full
+			remaining -= cost
+		} else if partial < 0 {
+			partial = i
+		}
+	}
+	// Spend what is left on the most-changed hunk that did not fit.
+	if partial >= 0 && remaining > 0 {
+		h := f.Hunks[partial]
+		rendered[partial] = partialHunk(h, len(omittedHunks[partial])+remaining)
+	}
+	return header + strings.Join(rendered, "")
+}
+
+// partialHunk renders the leading lines of h that fit in maxChars, followed by a marker.
+func partialHunk(h DiffChunk, maxChars int) string {
+	var sb strings.Builder
+	sb.WriteString(h.HunkHeader)
+	sb.WriteString("\n")
+	for i, line := range h.Lines {
+		marker := fmt.Sprintf("[... %d lines omitted ...]\n", len(h.Lines)-i)
+		if sb.Len()+len(line)+1+len(marker) > maxChars {
+			sb.WriteString(marker)
+			return sb.String()
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// omittedFileStub keeps only the "diff --git" line of f so the model still sees that the file changed.
+func omittedFileStub(f FileDiff) string {
+	first := "diff --git a/" + f.Path + " b/" + f.Path
+	if len(f.Header) > 0 {
+		first = f.Header[0]
+	}
+	return fmt.Sprintf("%s\n[... %d changed lines omitted ...]\n", first, f.ChangedLines())
+}
+
+func renderHunk(h DiffChunk) string {
+	var sb strings.Builder
+	sb.WriteString(h.HunkHeader)
+	sb.WriteString("\n")
+	for _, line := range h.Lines {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func changedLines(h DiffChunk) int {
+	n := 0
+	for _, line := range h.Lines {
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			n++
+		}
+	}
+	return n
+}
+
+// TruncateText cuts text that is not a unified diff, such as a summary of one, to at
+// most maxChars characters at a line boundary, marking the cut.
+func TruncateText(text string, maxChars int) string {
+	return truncateText(text, maxChars)
+}
+
+// truncateText cuts text to at most maxChars characters at a line boundary and appends a marker.
+func truncateText(text string, maxChars int) string {
+	const marker = "\n[... truncated ...]"
+	if len(text) <= maxChars {
+		return text
+	}
+	if maxChars <= len(marker) {
+		return text[:maxChars]
+	}
+	cut := text[:maxChars-len(marker)]
+	if idx := strings.LastIndex(cut, "\n"); idx > 0 {
+		cut = cut[:idx]
+	}
+	return cut + marker
+}
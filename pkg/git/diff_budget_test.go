@@ -0,0 +1,124 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileDiff 生成一个文件的 diff，每个 hunk 的新增行数由 hunkLines 指定
+func fileDiff(path string, hunkLines ...int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+	for h, n := range hunkLines {
+		fmt.Fprintf(&sb, "@@ -%d,1 +%d,%d @@ func hunk%d()\n context\n", h*100+1, h*100+1, n+1, h)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "+%s hunk %d line %d\n", path, h, i)
+		}
+	}
+	return sb.String()
+}
+
+func TestSplitDiffByFile(t *testing.T) {
+	diff := fileDiff("a.go", 2, 3) + fileDiff("dir/b.go", 1)
+	files := SplitDiffByFile(diff)
+	require.Len(t, files, 2)
+	assert.Equal(t, "a.go", files[0].Path)
+	assert.Len(t, files[0].Header, 4)
+	assert.Len(t, files[0].Hunks, 2)
+	assert.Equal(t, 5, files[0].ChangedLines())
+	assert.Equal(t, "dir/b.go", files[1].Path)
+	assert.Equal(t, diff, files[0].String()+files[1].String())
+}
+
+func TestDiffFileStats(t *testing.T) {
+	deleted := "diff --git a/old.go b/old.go\ndeleted file mode 100644\nindex 1111111..0000000\n--- a/old.go\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-package old\n-\n"
+	stats := DiffFileStats(fileDiff("a.go", 2, 3) + deleted)
+	require.Len(t, stats, 2)
+	assert.Equal(t, FileStat{Path: "a.go", Status: "modified", Added: 5}, stats[0])
+	assert.Equal(t, FileStat{Path: "old.go", Status: "deleted", Deleted: 2}, stats[1])
+}
+
+func TestIsLowSignalFile(t *testing.T) {
+	for _, p := range []string{"go.sum", "web/package-lock.json", "vendor/x/y.go", "frontend/wailsjs/go/models.ts", "api/v1/api.pb.go", "static/app.min.js"} {
+		assert.True(t, IsLowSignalFile(p), p)
+	}
+	for _, p := range []string{"main.go", "pkg/service/commit_service.go", "docs/vendor.md"} {
+		assert.False(t, IsLowSignalFile(p), p)
+	}
+}
+
+func TestIsLockFile(t *testing.T) {
+	assert.True(t, IsLockFile("go.sum", []string{"go.sum"}))
+	assert.True(t, IsLockFile("frontend/yarn.lock", []string{"yarn.lock"}))
+	assert.False(t, IsLockFile("notgo.sum", []string{"go.sum"}))
+}
+
+func TestFilterLockFiles(t *testing.T) {
+	diff := fileDiff("main.go", 1) + fileDiff("go.sum", 3) + fileDiff("web/yarn.lock", 2)
+	filtered := FilterLockFiles(diff, []string{"go.sum", "yarn.lock"})
+	assert.Contains(t, filtered, "main.go")
+	assert.NotContains(t, filtered, "go.sum")
+	assert.NotContains(t, filtered, "yarn.lock")
+}
+
+func TestTrimDiffToBudget_UnderBudget(t *testing.T) {
+	diff := fileDiff("main.go", 3)
+	trimmed, report := TrimDiffToBudget(diff, len(diff))
+	assert.Equal(t, diff, trimmed)
+	assert.False(t, report.Trimmed())
+}
+
+func TestTrimDiffToBudget_DropsLowSignalFirst(t *testing.T) {
+	main := fileDiff("main.go", 5)
+	diff := main + fileDiff("go.sum", 200) + fileDiff("vendor/lib/lib.go", 50)
+
+	trimmed, report := TrimDiffToBudget(diff, len(main)+300)
+	assert.LessOrEqual(t, len(trimmed), len(main)+300)
+	assert.Contains(t, trimmed, main, "source file is kept whole")
+	assert.Equal(t, []string{"go.sum", "vendor/lib/lib.go"}, report.OmittedFiles)
+	assert.Empty(t, report.TruncatedFiles)
+	assert.Contains(t, trimmed, "diff --git a/go.sum b/go.sum\n[... 200 changed lines omitted ...]")
+	assert.Equal(t, len(trimmed), report.FinalChars)
+	assert.Equal(t, len(diff), report.OriginalChars)
+}
+
+func TestTrimDiffToBudget_SharesBudgetFairly(t *testing.T) {
+	small := fileDiff("small.go", 2)
+	diff := small + fileDiff("big1.go", 5, 60, 10) + fileDiff("big2.go", 80)
+
+	budget := 3000
+	trimmed, report := TrimDiffToBudget(diff, budget)
+	assert.LessOrEqual(t, len(trimmed), budget)
+	assert.Contains(t, trimmed, small, "files under their share are kept whole")
+	assert.Equal(t, []string{"big1.go", "big2.go"}, report.TruncatedFiles)
+	assert.Empty(t, report.OmittedFiles)
+
+	// 被截断的文件保留所有 hunk 头，优先保留改动最多的 hunk
+	for h := 0; h < 3; h++ {
+		assert.Contains(t, trimmed, fmt.Sprintf("func hunk%d()", h))
+	}
+	assert.Contains(t, trimmed, "big1.go hunk 1 line 0")
+	assert.Contains(t, trimmed, "lines omitted ...]")
+	// 两个大文件都保留了部分内容
+	assert.Contains(t, trimmed, "big2.go hunk 0 line 0")
+}
+
+func TestTrimDiffToBudget_PlainText(t *testing.T) {
+	text := strings.Repeat("summary line\n", 100)
+	trimmed, report := TrimDiffToBudget(text, 200)
+	assert.LessOrEqual(t, len(trimmed), 200)
+	assert.True(t, strings.HasSuffix(trimmed, "[... truncated ...]"))
+	assert.True(t, report.Trimmed())
+}
+
+func TestOmitLowSignalFiles_DoesNotTruncate(t *testing.T) {
+	diff := fileDiff("main.go", 100) + fileDiff("go.sum", 100)
+	trimmed, report := OmitLowSignalFiles(diff, 100)
+	assert.Equal(t, []string{"go.sum"}, report.OmittedFiles)
+	assert.Empty(t, report.TruncatedFiles)
+	assert.Contains(t, trimmed, fileDiff("main.go", 100))
+}
@@ -2,7 +2,6 @@ package git
 
 import (
 	"context"
-	"os"
 	"testing"
 
 	"github.com/allanpk716/ai-commit-hub/tests/helpers"
@@ -12,12 +11,7 @@ import (
 func TestGetGitDiffIgnoringMoves_NoChanges(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 
-	// 切换到测试目录执行
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	diff, err := GetGitDiffIgnoringMoves(context.Background())
+	diff, err := GetGitDiffIgnoringMoves(context.Background(), repo.Path)
 
 	assert.NoError(t, err)
 	assert.Empty(t, diff)
@@ -27,12 +21,7 @@ func TestGetGitDiffIgnoringMoves_WithNewFile(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 	repo.CreateStagedChange(t, "test.txt", "new content")
 
-	// 切换到测试目录执行
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	diff, err := GetGitDiffIgnoringMoves(context.Background())
+	diff, err := GetGitDiffIgnoringMoves(context.Background(), repo.Path)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, diff)
@@ -43,11 +32,7 @@ func TestGetStagedDiff_Success(t *testing.T) {
 	repo := helpers.SetupTestRepo(t)
 	repo.CreateStagedChange(t, "test.txt", "content")
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	os.Chdir(repo.Path)
-
-	diff, err := GetStagedDiff(context.Background())
+	diff, err := GetStagedDiff(context.Background(), repo.Path)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, diff)
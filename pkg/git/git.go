@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -24,9 +25,9 @@ type lineDiff struct {
 	Text string
 }
 
-// IsGitRepository returns true if "." is a Git repo.
-func IsGitRepository(ctx context.Context) bool {
-	_, err := gogit.PlainOpen(".")
+// IsGitRepository returns true if repoPath is a Git repo.
+func IsGitRepository(ctx context.Context, repoPath string) bool {
+	_, err := gogit.PlainOpen(repoPath)
 	return err == nil
 }
 
@@ -37,8 +38,8 @@ func IsGitRepository(ctx context.Context) bool {
 // NOTE: New content is read from the working tree, not the index. This is a known limitation
 // if the user stages partial changes and then edits further. To make it *exactly* reflect the
 // index, you’d need to read blobs from the index (or shell-out to `git show :path`).
-func GetGitDiffIgnoringMoves(ctx context.Context) (string, error) {
-	repo, err := gogit.PlainOpen(".")
+func GetGitDiffIgnoringMoves(ctx context.Context, repoPath string) (string, error) {
+	repo, err := gogit.PlainOpen(repoPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -60,7 +61,7 @@ func GetGitDiffIgnoringMoves(ctx context.Context) (string, error) {
 	headRef, err := repo.Head()
 	if err != nil {
 		// No HEAD (e.g., first commit) – treat as diff against empty tree.
-		return getDiffAgainstEmptyIgnoringMoves(repo)
+		return getDiffAgainstEmptyIgnoringMoves(repo, repoPath)
 	}
 	headCommit, err := repo.CommitObject(headRef.Hash())
 	if err != nil {
@@ -93,7 +94,7 @@ func GetGitDiffIgnoringMoves(ctx context.Context) (string, error) {
 		var newContent string
 		if fileStatus.Staging != gogit.Deleted {
 			// NOTE: reads working tree; for exact staged content, use index blob or `git show :path`.
-			if data, err := os.ReadFile(newPath); err == nil && !isBinary(data) {
+			if data, err := os.ReadFile(filepath.Join(repoPath, newPath)); err == nil && !isBinary(data) {
 				newContent = string(data)
 			}
 		}
@@ -134,7 +135,7 @@ func GetGitDiffIgnoringMoves(ctx context.Context) (string, error) {
 }
 
 // getDiffAgainstEmptyIgnoringMoves computes a diff vs empty repo.
-func getDiffAgainstEmptyIgnoringMoves(repo *gogit.Repository) (string, error) {
+func getDiffAgainstEmptyIgnoringMoves(repo *gogit.Repository, repoPath string) (string, error) {
 	worktree, err := repo.Worktree()
 	if err != nil {
 		return "", fmt.Errorf("failed to get worktree: %w", err)
@@ -153,7 +154,7 @@ func getDiffAgainstEmptyIgnoringMoves(repo *gogit.Repository) (string, error) {
 		}
 		var newContent string
 		if fileStatus.Staging != gogit.Deleted {
-			data, err := os.ReadFile(filePath)
+			data, err := os.ReadFile(filepath.Join(repoPath, filePath))
 			if err == nil && !isBinary(data) {
 				newContent = string(data)
 			}
@@ -278,16 +279,7 @@ func FilterLockFiles(diff string, lockFiles []string) string {
 
 	for _, line := range lines {
 		if strings.HasPrefix(line, "diff --git ") {
-			matchFound := false
-			for _, lf := range lockFiles {
-				pattern := fmt.Sprintf(`^diff --git a/(.*/)?(%s)$`, regexp.QuoteMeta(lf))
-				matched, _ := regexp.MatchString(pattern, strings.TrimSpace(line))
-				if matched {
-					matchFound = true
-					break
-				}
-			}
-			isLockFile = matchFound
+			isLockFile = IsLockFile(parseFilePath(line), lockFiles)
 			if isLockFile {
 				continue
 			}
@@ -299,10 +291,21 @@ func FilterLockFiles(diff string, lockFiles []string) string {
 	return strings.Join(filtered, "\n")
 }
 
+// IsLockFile reports whether filePath (slash-separated, relative to the repository root)
+// names one of lockFiles, in any directory.
+func IsLockFile(filePath string, lockFiles []string) bool {
+	for _, lf := range lockFiles {
+		if filePath == lf || strings.HasSuffix(filePath, "/"+lf) {
+			return true
+		}
+	}
+	return false
+}
+
 // CommitChanges creates a commit with a supplied message using the repository's Git configuration.
 // If no author is configured in Git, it falls back to go-git's default behavior.
-func CommitChanges(ctx context.Context, commitMessage string) error {
-	repo, err := gogit.PlainOpen(".")
+func CommitChanges(ctx context.Context, repoPath, commitMessage string) error {
+	repo, err := gogit.PlainOpen(repoPath)
 	if err != nil {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -323,12 +326,12 @@ func CommitChanges(ctx context.Context, commitMessage string) error {
 	// If not set in repo config, try to get from global config via git command
 	if authorName == "" || authorEmail == "" {
 		if authorName == "" {
-			if name, err := getGitConfig("user.name"); err == nil && name != "" {
+			if name, err := getGitConfig(repoPath, "user.name"); err == nil && name != "" {
 				authorName = name
 			}
 		}
 		if authorEmail == "" {
-			if email, err := getGitConfig("user.email"); err == nil && email != "" {
+			if email, err := getGitConfig(repoPath, "user.email"); err == nil && email != "" {
 				authorEmail = email
 			}
 		}
@@ -350,8 +353,9 @@ func CommitChanges(ctx context.Context, commitMessage string) error {
 
 // getGitConfig reads a Git configuration value using git command.
 // This fallback ensures we get global/system config when go-git doesn't have it.
-func getGitConfig(key string) (string, error) {
+func getGitConfig(repoPath, key string) (string, error) {
 	cmd := Command("git", "config", "--get", key)
+	cmd.Dir = repoPath
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 	if err := cmd.Run(); err != nil {
@@ -361,8 +365,8 @@ func getGitConfig(key string) (string, error) {
 }
 
 // GetHeadCommitMessage returns the HEAD commit message.
-func GetHeadCommitMessage(ctx context.Context) (string, error) {
-	repo, err := gogit.PlainOpen(".")
+func GetHeadCommitMessage(ctx context.Context, repoPath string) (string, error) {
+	repo, err := gogit.PlainOpen(repoPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -377,9 +381,41 @@ func GetHeadCommitMessage(ctx context.Context) (string, error) {
 	return strings.TrimSpace(commit.Message), nil
 }
 
+// GetRecentCommitSubjects returns the subject lines of the last n commits reachable
+// from HEAD, most recent first. A repository without commits has none.
+func GetRecentCommitSubjects(ctx context.Context, repoPath string, n int) ([]string, error) {
+	repo, err := gogit.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	headRef, err := repo.Head()
+	if err != nil {
+		return nil, nil
+	}
+	iter, err := repo.Log(&gogit.LogOptions{From: headRef.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit log: %w", err)
+	}
+	defer iter.Close()
+
+	var subjects []string
+	for len(subjects) < n {
+		commit, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return subjects, fmt.Errorf("failed to read commit log: %w", err)
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		subjects = append(subjects, strings.TrimSpace(subject))
+	}
+	return subjects, nil
+}
+
 // GetCurrentBranch returns the short name of the current branch.
-func GetCurrentBranch(ctx context.Context) (string, error) {
-	repo, err := gogit.PlainOpen(".")
+func GetCurrentBranch(ctx context.Context, repoPath string) (string, error) {
+	repo, err := gogit.PlainOpen(repoPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -572,7 +608,7 @@ func buildPatch(chunks []DiffChunk, selected map[int]bool) (string, error) {
 }
 
 // partialCommit applies a synthesized patch to the index and commits with an AI-generated message.
-func partialCommit(chunks []DiffChunk, selected map[int]bool, client any) error {
+func partialCommit(repoPath string, chunks []DiffChunk, selected map[int]bool, client any) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -585,6 +621,7 @@ func partialCommit(chunks []DiffChunk, selected map[int]bool, client any) error
 	}
 
 	cmd := Command("git", "apply", "--cached", "-")
+	cmd.Dir = repoPath
 	cmd.Stdin = strings.NewReader(patch)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -593,7 +630,7 @@ func partialCommit(chunks []DiffChunk, selected map[int]bool, client any) error
 	}
 
 	// Recompute diff (still from working tree, see note above).
-	partialDiff, err := GetGitDiffIgnoringMoves(ctx)
+	partialDiff, err := GetGitDiffIgnoringMoves(ctx, repoPath)
 	if err != nil {
 		return fmt.Errorf("failed to get partial diff: %w", err)
 	}
@@ -619,7 +656,7 @@ Diff:
 	if err != nil {
 		return fmt.Errorf("AI error: %w", err)
 	}
-	if err := CommitChanges(ctx, strings.TrimSpace(msg)); err != nil {
+	if err := CommitChanges(ctx, repoPath, strings.TrimSpace(msg)); err != nil {
 		return err
 	}
 	return nil
@@ -627,8 +664,9 @@ Diff:
 
 // GetStagedDiff returns the diff of staged changes using git diff --cached.
 // This is more reliable than GetGitDiffIgnoringMoves for getting only staged changes.
-func GetStagedDiff(ctx context.Context) (string, error) {
+func GetStagedDiff(ctx context.Context, repoPath string) (string, error) {
 	cmd := Command("git", "-c", "core.quotepath=false", "diff", "--cached")
+	cmd.Dir = repoPath
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 	if err := cmd.Run(); err != nil {
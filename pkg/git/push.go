@@ -6,11 +6,12 @@ import (
 	"strings"
 )
 
-// PushToRemote pushes the current branch to the origin remote repository.
+// PushToRemote pushes the current branch of repoPath to the origin remote repository.
 // It uses the system git command to ensure compatibility with SSH keys and credentials.
-func PushToRemote(ctx context.Context) error {
+func PushToRemote(ctx context.Context, repoPath string) error {
 	// 获取当前分支名
 	branchCmd := Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	branchCmd.Dir = repoPath
 	branchOutput, err := branchCmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to get current branch: %w", err)
@@ -23,6 +24,7 @@ func PushToRemote(ctx context.Context) error {
 
 	// 执行推送命令
 	pushCmd := Command("git", "push", "origin", branchName)
+	pushCmd.Dir = repoPath
 	output, err := pushCmd.CombinedOutput()
 
 	if err != nil {
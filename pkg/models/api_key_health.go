@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// APIKeyHealth tracks the results of requests made with one API key of a provider,
+// so that keys which were rejected or rate limited are skipped until they cool down.
+// The key itself is never stored; KeyID is a fingerprint of it.
+type APIKeyHealth struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Provider string `gorm:"uniqueIndex:idx_api_key_health_key;size:128" json:"provider"`
+	KeyID    string `gorm:"uniqueIndex:idx_api_key_health_key;size:64" json:"key_id"`
+	Label    string `json:"label"`
+
+	Successes           int `json:"successes"`
+	Failures            int `json:"failures"`
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// 最近一次请求的 HTTP 状态码（成功为 200）和失败原因
+	LastStatus int    `json:"last_status"`
+	LastError  string `json:"last_error"`
+
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	// CooldownUntil 之前不再使用该 key，除非所有 key 都不可用
+	CooldownUntil *time.Time `json:"cooldown_until"`
+
+	// 当天（本地时间 YYYY-MM-DD）的请求数，用于检查每日配额
+	Day         string `json:"day"`
+	DayRequests int    `json:"day_requests"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for APIKeyHealth
+func (APIKeyHealth) TableName() string {
+	return "api_key_health"
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// AuditLog records one provider request made while generating a commit message: the
+// prompt sent, the raw response and the resulting message, with secrets masked. It is
+// only written when the audit log is enabled in config.yaml.
+type AuditLog struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	SessionID   string `gorm:"index;size:64" json:"session_id"`
+	ProjectPath string `gorm:"index" json:"project_path"`
+	// 候选消息序号（从 0 开始），-1 表示总结 diff 分块的请求
+	CandidateIndex int    `json:"candidate_index"`
+	Provider       string `gorm:"index;size:128" json:"provider"`
+	Model          string `json:"model"`
+
+	Prompt string `json:"prompt"`
+	// RawResponse 是 provider 返回的原始文本（思考过程已分离），Result 是最终使用的消息
+	RawResponse string `json:"raw_response"`
+	Result      string `json:"result"`
+	// Error 为空表示请求成功
+	Error string `json:"error"`
+
+	DurationMs       int64 `json:"duration_ms"`
+	PromptTokens     int   `json:"prompt_tokens"`
+	CompletionTokens int   `json:"completion_tokens"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for AuditLog
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
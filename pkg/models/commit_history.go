@@ -9,6 +9,15 @@ type CommitHistory struct {
 	Message   string `gorm:"type:text" json:"message"`
 	Provider  string `json:"provider"`
 	Language  string `json:"language"`
+
+	// Token 用量（provider 未返回用量时为 0）
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+
+	// 多候选生成时未被选中的候选消息
+	RejectedCandidates []string `gorm:"serializer:json" json:"rejected_candidates,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 
 	Project   GitProject `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
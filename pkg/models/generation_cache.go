@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// GenerationCache stores generated commit messages keyed by a hash of the generation inputs
+// (providers in the fallback chain with their models and parameters, language, prompt
+// template and staged diff), so identical requests can be answered without calling the
+// provider again.
+type GenerationCache struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Key      string `gorm:"uniqueIndex;size:64" json:"key"`
+	Provider string `json:"provider"` // 实际生成消息的 provider（可能来自回退链）
+	Model    string `json:"model"`    // Provider 使用的模型
+	Language string `json:"language"`
+
+	// 按候选序号保存的消息
+	Messages []string `gorm:"serializer:json" json:"messages"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `gorm:"index" json:"last_used_at"`
+}
+
+// TableName specifies the table name for GenerationCache
+func (GenerationCache) TableName() string {
+	return "generation_caches"
+}
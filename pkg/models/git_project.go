@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5"
+
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
 
 // GitProject represents a git repository project
@@ -22,6 +24,12 @@ type GitProject struct {
 	Model      *string `json:"model,omitempty"`       // nil 表示使用默认
 	UseDefault bool    `gorm:"default:true" json:"use_default"` // true=使用默认配置
 
+	// 项目级别的 Provider 回退链（nil 表示使用配置文件中的 fallbackProviders）
+	FallbackProviders []string `gorm:"serializer:json" json:"fallback_providers,omitempty"`
+
+	// 项目级别的生成参数，覆盖配置文件中各 provider 的 params（nil 表示不覆盖）
+	GenerationParams *aicommitconfig.GenerationParams `gorm:"serializer:json" json:"generation_params,omitempty"`
+
 	// Pushover Hook 配置
 	HookInstalled   bool        `gorm:"default:false" json:"hook_installed"`
 	NotificationMode string     `gorm:"default:'enabled'" json:"notification_mode"` // enabled/pushover_only/windows_only/disabled
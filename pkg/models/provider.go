@@ -5,4 +5,34 @@ type ProviderInfo struct {
 	Name      string `json:"name"`
 	Configured bool   `json:"configured"`
 	Reason     string `json:"reason,omitempty"` // 未配置的原因（如"缺少 API Key"）
+	// Capabilities 是 provider 注册时声明的能力（流式、上下文窗口、JSON 模式等）
+	Capabilities ProviderCapabilities `json:"capabilities"`
+	// Latency 是本次运行中观测到的生成耗时，尚未生成过时为 nil
+	Latency *ProviderLatency `json:"latency,omitempty"`
+}
+
+// ProviderCapabilities 是 provider 在注册表中声明的能力，由 service 层填充
+type ProviderCapabilities struct {
+	Streaming     bool                `json:"streaming"`
+	ContextTokens int                 `json:"contextTokens,omitempty"` // 默认模型的上下文窗口，未知时为 0
+	JSONMode      bool                `json:"jsonMode"`
+	Reasoning     bool                `json:"reasoning"`
+	Local         bool                `json:"local"`
+	Models        []string            `json:"models,omitempty"`
+	Params        ProviderParamLimits `json:"params"`
+}
+
+// ProviderParamLimits 是 provider 支持的生成参数及其范围
+type ProviderParamLimits struct {
+	MaxTemperature  float64 `json:"maxTemperature,omitempty"` // 不支持 temperature 时为 0
+	TopP            bool    `json:"topP"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"` // 不限制时为 0
+	SystemPrompt    bool    `json:"systemPrompt"`
+}
+
+// ProviderLatency 是 provider 生成消息的耗时统计，竞速中被取消的请求以已等待的时间计入
+type ProviderLatency struct {
+	AvgMs   int64 `json:"avgMs"`  // 指数移动平均，近期的请求权重更高
+	LastMs  int64 `json:"lastMs"` // 最近一次的耗时
+	Samples int   `json:"samples"`
 }
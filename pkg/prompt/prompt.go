@@ -5,16 +5,33 @@ import (
 	"strings"
 
 	gogitobj "github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/committypes"
 )
 
 // DefaultPromptTemplate is used if no template is configured for commit message generation.
+// See CommitPromptData for the values available to templates.
 const DefaultPromptTemplate = `You are an expert software engineer.
 Analyze the provided ` + "`git diff`" + ` and generate a commit message following the **Conventional Commits** specification.
 
 ### 1. INPUT
 - **Diff Data**: See below.
-- **Target Language**: {LANGUAGE}
+- **Target Language**: {{.Language}}
+{{- if .ProjectName}}
+- **Project**: {{.ProjectName}}
+{{- end}}
+{{- if .Branch}}
+- **Branch**: {{.Branch}}
+{{- end}}
+{{- if .IssueKey}}
+- **Issue**: {{.IssueKey}} (add a "Refs: {{.IssueKey}}" footer)
+{{- end}}
+{{- if .Files}}
+- **Changed Files** ({{.Stats}}):
+{{.Files}}
+{{- end}}
+{{- if .RecentCommits}}
+- **Recent Commits** (follow their scope and wording conventions):
+{{.RecentCommits}}
+{{- end}}
 
 ### 2. RULES
 - **Intent**: Focus on *why* the change was made.
@@ -22,13 +39,13 @@ Analyze the provided ` + "`git diff`" + ` and generate a commit message followin
 - **Types**: Keep standard types (feat, fix, chore, etc.) in English.
 
 ### 3. OUTPUT FORMAT (Strictly Follow)
-You must generate the message in the structure below, using **{LANGUAGE}** for the description and body:
+You must generate the message in the structure below, using **{{.Language}}** for the description and body:
 
-<type>(<scope>): <concise description in {LANGUAGE}>
+<type>(<scope>): <concise description in {{.Language}}>
 
-[Optional Body in {LANGUAGE}, bullet points]
+[Optional Body in {{.Language}}, bullet points]
 
-### 4. EXAMPLES (For format reference only - Translate your output to {LANGUAGE})
+### 4. EXAMPLES (For format reference only - Translate your output to {{.Language}})
 
 Input: (Logic change)
 Output: fix(auth): resolve nil pointer in token validation
@@ -41,10 +58,10 @@ Output: chore(gitignore): ignore .DS_Store files
 
 ### 5. FINAL INSTRUCTION
 Analyze the diff below and write the commit message.
-**CRITICAL**: The <description> and <body> MUST be in **{LANGUAGE}**.
+**CRITICAL**: The <description> and <body> MUST be in **{{.Language}}**.
 
 ### DIFF TO ANALYZE:
-{DIFF}`
+{{.Diff}}`
 
 // DefaultCodeReviewPromptTemplate is used for code review prompts.
 const DefaultCodeReviewPromptTemplate = `Review the following code diff for potential issues, and provide suggestions, following these rules:
@@ -105,6 +122,28 @@ Diff:
 {DIFF}
 `
 
+// DefaultDiffChunkSummaryPromptTemplate is used to summarize one part of a diff that is too
+// large to send at once; the final commit message is generated from the per-part summaries.
+const DefaultDiffChunkSummaryPromptTemplate = `You are helping to write a commit message for a large change.
+Below is part {PART} of {TOTAL} of the ` + "`git diff`" + `.
+
+Summarize the changes in this part for each file:
+- Start each file with its path on its own line, followed by a colon.
+- Add one to three short bullet points describing what changed and, when it is clear, why.
+- Ignore pure formatting changes.
+- Do NOT write a commit message and do not add any other text.
+
+### DIFF PART:
+{DIFF}`
+
+// BuildDiffChunkSummaryPrompt builds the prompt for summarizing part index+1 of total of a large diff.
+func BuildDiffChunkSummaryPrompt(diff string, index, total int) string {
+	promptText := strings.ReplaceAll(DefaultDiffChunkSummaryPromptTemplate, "{PART}", fmt.Sprint(index+1))
+	promptText = strings.ReplaceAll(promptText, "{TOTAL}", fmt.Sprint(total))
+	promptText = strings.ReplaceAll(promptText, "{DIFF}", diff)
+	return promptText
+}
+
 // BuildCommitSummaryPrompt constructs the prompt used to ask the AI for a commit summary.
 // It replaces placeholders with actual commit information and the diff string.
 func BuildCommitSummaryPrompt(commit *gogitobj.Commit, diffStr, customPromptTemplate, language string) string {
@@ -122,30 +161,44 @@ func BuildCommitSummaryPrompt(commit *gogitobj.Commit, diffStr, customPromptTemp
 	return promptText
 }
 
-// BuildCommitPrompt builds the prompt for generating a commit message.
-// It replaces placeholders with the provided diff, language, commit type, and any additional context.
+// BuildCommitPrompt builds the prompt for generating a commit message from the provided
+// diff, language, commit type, and any additional context. promptTemplate is rendered by
+// ExpandCommitPrompt, so a template that is not a valid text/template still has its
+// {NAME} placeholders replaced.
 func BuildCommitPrompt(diff, language, commitType, additionalText, promptTemplate string) string {
-	finalTemplate := promptTemplate
-	if finalTemplate == "" {
-		finalTemplate = DefaultPromptTemplate
-	}
+	promptText, _ := ExpandCommitPrompt(promptTemplate, NewCommitPromptData(diff, language, commitType, additionalText))
+	return promptText
+}
 
-	commitTypeHint := ""
-	if commitType != "" && committypes.IsValidCommitType(commitType) {
-		commitTypeHint = fmt.Sprintf("- Use the commit type '%s'.\n", commitType)
+// WithCandidateVariation appends an instruction asking for alternative number index+1 of total,
+// so parallel requests for multiple candidates produce different phrasings.
+// It returns promptText unchanged when total <= 1.
+func WithCandidateVariation(promptText string, index, total int) string {
+	if total <= 1 {
+		return promptText
 	}
+	return promptText + fmt.Sprintf("\n\n[Alternative %d of %d] Describe the same changes, but use a wording and emphasis that differs from the other alternatives. Output only the commit message.", index+1, total)
+}
 
-	promptText := strings.ReplaceAll(finalTemplate, "{COMMIT_TYPE_HINT}", commitTypeHint)
-	promptText = strings.ReplaceAll(promptText, "{LANGUAGE}", language)
-	promptText = strings.ReplaceAll(promptText, "{DIFF}", diff)
-
-	additionalContextStr := ""
-	if additionalText != "" {
-		additionalContextStr = "\n\n[Additional context provided by user]\n" + additionalText
-	}
-	promptText = strings.ReplaceAll(promptText, "{ADDITIONAL_CONTEXT}", additionalContextStr)
+// structuredOutputInstruction replaces the free-text output format of a commit prompt with JSON.
+const structuredOutputInstruction = `
+
+### OUTPUT AS JSON (overrides the output format above)
+Respond with a single JSON object only, without markdown fences or any other text:
+{
+  "type": "<commit type, in English>",
+  "scope": "<scope, or empty string>",
+  "subject": "<concise description>",
+  "body": ["<bullet point>", "..."],
+  "breakingChange": "<description of the breaking change, or empty string>",
+  "footers": [{"token": "Refs", "value": "#123"}]
+}
+Use empty arrays when there is no body or footer. The language rules above still apply to subject, body and breakingChange.`
 
-	return promptText
+// WithStructuredOutput appends an instruction asking for the commit message as a JSON
+// object (type, scope, subject, body, breakingChange, footers) instead of free text.
+func WithStructuredOutput(promptText string) string {
+	return promptText + structuredOutputInstruction
 }
 
 // BuildCodeReviewPrompt builds the prompt for a code review.
@@ -0,0 +1,200 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/committypes"
+)
+
+// FileChange is a file changed by the diff a commit message is generated for.
+type FileChange struct {
+	Path string
+	// Status is "added", "deleted", "renamed" or "modified".
+	Status  string
+	Added   int
+	Deleted int
+}
+
+// FileChanges prints as one "- path (status, +added -deleted)" line per file, so
+// {{.Files}} lists the files while {{range .Files}} gives access to each one.
+type FileChanges []FileChange
+
+func (f FileChanges) String() string {
+	lines := make([]string, len(f))
+	for i, c := range f {
+		lines[i] = fmt.Sprintf("- %s (%s, +%d -%d)", c.Path, c.Status, c.Added, c.Deleted)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffStats summarizes the size of a diff.
+type DiffStats struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+func (s DiffStats) String() string {
+	return fmt.Sprintf("%d files changed, %d insertions(+), %d deletions(-)", s.Files, s.Insertions, s.Deletions)
+}
+
+// Lines prints as one "- item" line per element.
+type Lines []string
+
+func (l Lines) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	return "- " + strings.Join(l, "\n- ")
+}
+
+// CommitPromptData holds the values available to commit message prompt templates,
+// which are Go text/template templates, e.g.
+//
+//	{{if .IssueKey}}Reference {{.IssueKey}} in a footer.{{end}}
+//	Changed files ({{.Stats}}):
+//	{{.Files}}
+type CommitPromptData struct {
+	Diff       string
+	Language   string
+	CommitType string
+	// CommitTypeHint is an instruction to use CommitType, or empty.
+	CommitTypeHint string
+	// AdditionalContext is the context given by the user, with a heading, or empty.
+	AdditionalContext string
+
+	ProjectName string
+	Branch      string
+	// IssueKey is the issue referenced by the branch name, such as "PROJ-123", or empty.
+	IssueKey      string
+	Files         FileChanges
+	RecentCommits Lines
+}
+
+// Stats returns the totals of Files.
+func (d CommitPromptData) Stats() DiffStats {
+	stats := DiffStats{Files: len(d.Files)}
+	for _, f := range d.Files {
+		stats.Insertions += f.Added
+		stats.Deletions += f.Deleted
+	}
+	return stats
+}
+
+// legacyPlaceholders maps the {NAME} placeholders of templates written before prompts
+// became text/templates to the fields they are converted to.
+var legacyPlaceholders = map[string]string{
+	"DIFF":               "Diff",
+	"LANGUAGE":           "Language",
+	"COMMIT_TYPE":        "CommitType",
+	"COMMIT_TYPE_HINT":   "CommitTypeHint",
+	"ADDITIONAL_CONTEXT": "AdditionalContext",
+	"PROJECT_NAME":       "ProjectName",
+	"BRANCH":             "Branch",
+	"ISSUE_KEY":          "IssueKey",
+	"FILES":              "Files",
+	"STATS":              "Stats",
+	"RECENT_COMMITS":     "RecentCommits",
+}
+
+var legacyPlaceholderPattern = regexp.MustCompile(`\{([A-Z][A-Z0-9_]*)\}`)
+
+// issueKeyPattern matches issue keys such as "PROJ-123" in branch names.
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-[0-9]+\b`)
+
+// IssueKeyFromBranch returns the first issue key such as "PROJ-123" in branch, or "".
+func IssueKeyFromBranch(branch string) string {
+	return issueKeyPattern.FindString(branch)
+}
+
+// convertLegacyPlaceholders rewrites known {NAME} placeholders to {{.Field}} actions.
+// Unknown placeholders are left as they are.
+func convertLegacyPlaceholders(tmpl string) string {
+	return legacyPlaceholderPattern.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		field, ok := legacyPlaceholders[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		return "{{." + field + "}}"
+	})
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// ParseCommitTemplate parses a commit prompt template. Legacy {NAME} placeholders
+// such as {DIFF} and {LANGUAGE} are accepted alongside text/template actions.
+// An empty template uses DefaultPromptTemplate.
+func ParseCommitTemplate(tmpl string) (*template.Template, error) {
+	if tmpl == "" {
+		tmpl = DefaultPromptTemplate
+	}
+	t, err := template.New("commit-message").Funcs(templateFuncs).Option("missingkey=error").Parse(convertLegacyPlaceholders(tmpl))
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt template: %w", err)
+	}
+	return t, nil
+}
+
+// RenderCommitPrompt renders the commit prompt template tmpl with data. Referencing a
+// field that does not exist is an error.
+func RenderCommitPrompt(tmpl string, data CommitPromptData) (string, error) {
+	t, err := ParseCommitTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("render prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// ExpandCommitPrompt renders tmpl with data like RenderCommitPrompt. Templates written
+// before prompts became text/templates may contain a literal "{{", e.g. in a JSON or Go
+// example, and so fail to parse; for a template that fails to render, the known {NAME}
+// placeholders are replaced literally instead. The text is always usable; the render
+// error is returned so callers can report it.
+func ExpandCommitPrompt(tmpl string, data CommitPromptData) (string, error) {
+	promptText, err := RenderCommitPrompt(tmpl, data)
+	if err == nil {
+		return promptText, nil
+	}
+	if tmpl == "" {
+		tmpl = DefaultPromptTemplate
+	}
+	return expandLegacyPlaceholders(tmpl, data), err
+}
+
+// expandLegacyPlaceholders replaces the known {NAME} placeholders in tmpl with the
+// values of their fields in data. Values are not scanned for further placeholders.
+func expandLegacyPlaceholders(tmpl string, data CommitPromptData) string {
+	return legacyPlaceholderPattern.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		field, ok := legacyPlaceholders[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		var sb strings.Builder
+		if err := template.Must(template.New(field).Parse("{{."+field+"}}")).Execute(&sb, data); err != nil {
+			return placeholder
+		}
+		return sb.String()
+	})
+}
+
+// NewCommitPromptData returns the prompt data for the given diff, language, commit
+// type and additional user context, filling CommitTypeHint and AdditionalContext.
+func NewCommitPromptData(diff, language, commitType, additionalText string) CommitPromptData {
+	data := CommitPromptData{Diff: diff, Language: language, CommitType: commitType}
+	if commitType != "" && committypes.IsValidCommitType(commitType) {
+		data.CommitTypeHint = fmt.Sprintf("- Use the commit type '%s'.\n", commitType)
+	}
+	if additionalText != "" {
+		data.AdditionalContext = "\n\n[Additional context provided by user]\n" + additionalText
+	}
+	return data
+}
@@ -0,0 +1,86 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCommitPrompt_LegacyPlaceholders(t *testing.T) {
+	data := NewCommitPromptData("+added line", "chinese", "", "fixes login")
+	data.Branch = "feature/PROJ-7"
+
+	out, err := RenderCommitPrompt("Lang: {LANGUAGE}\nBranch: {BRANCH}\n{DIFF}{ADDITIONAL_CONTEXT}\nKeep {UNKNOWN}", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Lang: chinese\nBranch: feature/PROJ-7\n+added line\n\n[Additional context provided by user]\nfixes login\nKeep {UNKNOWN}", out)
+}
+
+func TestRenderCommitPrompt_Conditionals(t *testing.T) {
+	tmpl := `{{if .IssueKey}}Refs: {{.IssueKey}}
+{{end}}{{.Stats}}
+{{.Files}}
+{{range .Files}}{{.Path}} {{end}}
+{{.RecentCommits}}`
+	data := CommitPromptData{
+		IssueKey:      IssueKeyFromBranch("feature/PROJ-42-login"),
+		Files:         FileChanges{{Path: "a.go", Status: "modified", Added: 3, Deleted: 1}, {Path: "b.go", Status: "added", Added: 10}},
+		RecentCommits: Lines{"feat: add login", "fix: typo"},
+	}
+
+	out, err := RenderCommitPrompt(tmpl, data)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"Refs: PROJ-42",
+		"2 files changed, 13 insertions(+), 1 deletions(-)",
+		"- a.go (modified, +3 -1)",
+		"- b.go (added, +10 -0)",
+		"a.go b.go ",
+		"- feat: add login",
+		"- fix: typo",
+	}, "\n"), out)
+
+	data.IssueKey = ""
+	out, err = RenderCommitPrompt(`{{if .IssueKey}}Refs{{else}}none{{end}}`, data)
+	require.NoError(t, err)
+	assert.Equal(t, "none", out)
+}
+
+func TestRenderCommitPrompt_Errors(t *testing.T) {
+	_, err := RenderCommitPrompt("{{if .Branch}}unterminated", CommitPromptData{})
+	assert.Error(t, err)
+
+	_, err = RenderCommitPrompt("{{.Brnach}}", CommitPromptData{})
+	assert.Error(t, err, "拼错的字段名")
+
+	// 无效的模板仍按旧方式替换占位符
+	assert.Equal(t, "english {{.Brnach}}", BuildCommitPrompt("", "english", "", "", "{LANGUAGE} {{.Brnach}}"))
+}
+
+func TestExpandCommitPrompt_LegacyTemplateWithBraces(t *testing.T) {
+	data := NewCommitPromptData("+x {LANGUAGE}", "english", "", "")
+	data.Branch = "main"
+
+	out, err := ExpandCommitPrompt("Reply like {{ \"type\": \"feat\" }} in {LANGUAGE} on {BRANCH}\n{DIFF}", data)
+	assert.Error(t, err)
+	// diff 中的 {LANGUAGE} 不会被再次替换
+	assert.Equal(t, "Reply like {{ \"type\": \"feat\" }} in english on main\n+x {LANGUAGE}", out)
+
+	out, err = ExpandCommitPrompt("{LANGUAGE}: {{.Branch}}", data)
+	require.NoError(t, err)
+	assert.Equal(t, "english: main", out)
+}
+
+func TestDefaultPromptTemplate(t *testing.T) {
+	out := BuildCommitPrompt("+x", "english", "", "", "")
+	assert.Contains(t, out, "**Target Language**: english\n\n### 2. RULES")
+	assert.True(t, strings.HasSuffix(out, "### DIFF TO ANALYZE:\n+x"))
+
+	data := NewCommitPromptData("+x", "english", "", "")
+	data.Branch = "main"
+	data.Files = FileChanges{{Path: "a.go", Status: "modified", Added: 1}}
+	out, err := RenderCommitPrompt("", data)
+	require.NoError(t, err)
+	assert.Contains(t, out, "- **Branch**: main\n- **Changed Files** (1 files changed, 1 insertions(+), 0 deletions(-)):\n- a.go (modified, +1 -0)\n")
+}
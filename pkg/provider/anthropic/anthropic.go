@@ -9,19 +9,26 @@ import (
 	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
 
+// defaultMaxTokens is the output limit when none is configured; the API requires one.
+const defaultMaxTokens = 1024
+
 type AnthropicClient struct {
     ai.BaseAIClient
     client anthropic.Client
     model  string
+    // Params are applied to every request.
+    Params config.GenerationParams
 }
 
 func NewAnthropicClient(provider, apiKey, model, baseURL string) (*AnthropicClient, error) {
     if strings.TrimSpace(apiKey) == "" {
         return nil, errors.New("anthropic API key is required")
     }
-    var opts []option.RequestOption
+    // Retries are handled by the caller's retry policy, not the SDK.
+    opts := []option.RequestOption{option.WithMaxRetries(0)}
     opts = append(opts, option.WithAPIKey(apiKey))
     if strings.TrimSpace(baseURL) != "" {
         opts = append(opts, option.WithBaseURL(baseURL))
@@ -35,13 +42,7 @@ func NewAnthropicClient(provider, apiKey, model, baseURL string) (*AnthropicClie
 }
 
 func (ac *AnthropicClient) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
-    params := anthropic.MessageNewParams{
-        MaxTokens: 1024,
-        Messages: []anthropic.MessageParam{
-            anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-        },
-        Model: anthropic.Model(ac.model),
-    }
+    params := ac.newParams(prompt)
     resp, err := ac.client.Messages.New(ctx, params)
     if err != nil {
         return "", fmt.Errorf("failed to get message from Anthropic: %w", err)
@@ -60,18 +61,13 @@ func (ac *AnthropicClient) GetCommitMessage(ctx context.Context, prompt string)
     if msg == "" {
         return "", errors.New("empty response from Anthropic")
     }
+    ac.reportUsage(ctx, string(resp.Model), resp.Usage)
     return msg, nil
 }
 
 // StreamCommitMessage streams text deltas from Anthropic SDK.
 func (ac *AnthropicClient) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(string)) (string, error) {
-    params := anthropic.MessageNewParams{
-        MaxTokens: 1024,
-        Messages: []anthropic.MessageParam{
-            anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-        },
-        Model: anthropic.Model(ac.model),
-    }
+    params := ac.newParams(prompt)
     stream := ac.client.Messages.NewStreaming(ctx, params)
     msg := anthropic.Message{}
     for stream.Next() {
@@ -87,6 +83,9 @@ func (ac *AnthropicClient) StreamCommitMessage(ctx context.Context, prompt strin
                 if d.Text != "" {
                     onDelta(d.Text)
                 }
+            case anthropic.ThinkingDelta:
+                // Extended thinking is reported apart from the message.
+                ai.ReportReasoning(ctx, d.Thinking)
             }
         }
     }
@@ -109,9 +108,85 @@ func (ac *AnthropicClient) StreamCommitMessage(ctx context.Context, prompt strin
             sb.WriteString(v.Text)
         }
     }
+    // Accumulated from message_start (input) and message_delta (output) events
+    ac.reportUsage(ctx, string(msg.Model), msg.Usage)
     return sb.String(), nil
 }
 
+// GenerateStructured forces a call to a tool whose input schema is schema and
+// returns the tool input as JSON.
+func (ac *AnthropicClient) GenerateStructured(ctx context.Context, prompt, name string, schema *ai.Schema) (string, error) {
+    params := ac.newParams(prompt)
+    params.Tools = []anthropic.ToolUnionParam{
+        anthropic.ToolUnionParamOfTool(anthropic.ToolInputSchemaParam{
+            Properties: schema.Properties,
+            Required:   schema.Required,
+        }, name),
+    }
+    params.ToolChoice = anthropic.ToolChoiceParamOfTool(name)
+    resp, err := ac.client.Messages.New(ctx, params)
+    if err != nil {
+        return "", fmt.Errorf("failed to get structured message from Anthropic: %w", err)
+    }
+    for _, blk := range resp.Content {
+        if v, ok := blk.AsAny().(anthropic.ToolUseBlock); ok && v.Name == name {
+            ac.reportUsage(ctx, string(resp.Model), resp.Usage)
+            return string(v.Input), nil
+        }
+    }
+    return "", errors.New("no tool call in Anthropic response")
+}
+
+// newParams builds a message request for prompt with the configured generation parameters.
+func (ac *AnthropicClient) newParams(prompt string) anthropic.MessageNewParams {
+    params := anthropic.MessageNewParams{
+        MaxTokens: defaultMaxTokens,
+        Messages: []anthropic.MessageParam{
+            anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+        },
+        Model: anthropic.Model(ac.model),
+    }
+    if ac.Params.MaxOutputTokens > 0 {
+        params.MaxTokens = int64(ac.Params.MaxOutputTokens)
+    }
+    if strings.TrimSpace(ac.Params.SystemPrompt) != "" {
+        params.System = []anthropic.TextBlockParam{{Text: ac.Params.SystemPrompt}}
+    }
+    if ac.Params.Temperature != nil {
+        params.Temperature = anthropic.Float(*ac.Params.Temperature)
+    }
+    if ac.Params.TopP != nil {
+        params.TopP = anthropic.Float(*ac.Params.TopP)
+    }
+    return params
+}
+
+// reportUsage forwards token usage from the response to the recorder in ctx.
+func (ac *AnthropicClient) reportUsage(ctx context.Context, model string, usage anthropic.Usage) {
+    if model == "" {
+        model = ac.model
+    }
+    ai.ReportUsage(ctx, ai.Usage{
+        Provider:         ac.ProviderName(),
+        Model:            model,
+        PromptTokens:     int(usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens),
+        CompletionTokens: int(usage.OutputTokens),
+    })
+}
+
+// ListModels returns the model IDs available to the API key.
+func (ac *AnthropicClient) ListModels(ctx context.Context) ([]string, error) {
+    iter := ac.client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+    var models []string
+    for iter.Next() {
+        models = append(models, iter.Current().ID)
+    }
+    if err := iter.Err(); err != nil {
+        return nil, fmt.Errorf("failed to list Anthropic models: %w", err)
+    }
+    return models, nil
+}
+
 func (ac *AnthropicClient) SanitizeResponse(message, commitType string) string {
     return ac.BaseAIClient.SanitizeResponse(message, commitType)
 }
@@ -122,3 +197,5 @@ func (ac *AnthropicClient) MaybeSummarizeDiff(diff string, maxLength int) (strin
 
 var _ ai.AIClient = (*AnthropicClient)(nil)
 var _ ai.StreamingAIClient = (*AnthropicClient)(nil)
+var _ ai.ModelLister = (*AnthropicClient)(nil)
+var _ ai.StructuredOutputClient = (*AnthropicClient)(nil)
@@ -11,11 +11,24 @@ import (
 const ProviderName = "anthropic"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
-    return NewAnthropicClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    c, err := NewAnthropicClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    if err != nil {
+        return nil, err
+    }
+    c.Params = ps.Params
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "claude-3-7-sonnet-latest", BaseURL: "https://api.anthropic.com/v1"})
     registry.SetRequiresAPIKey(ProviderName, true)
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming:     true,
+        ContextTokens: 200000,
+        JSONMode:      true,
+        Reasoning:     true,
+        Models:        []string{"claude-3-7-sonnet-latest", "claude-sonnet-4-0", "claude-opus-4-0", "claude-3-5-haiku-latest"},
+        Params:        registry.ParamLimits{MaxTemperature: 1, TopP: true, MaxOutputTokens: 64000, SystemPrompt: true},
+    })
 }
@@ -0,0 +1,61 @@
+// Package azure provides an Azure OpenAI client. Azure routes requests by deployment
+// name, requires an api-version query parameter and authenticates with an api-key
+// header instead of a bearer token; otherwise it speaks the OpenAI chat API.
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	compat "github.com/allanpk716/ai-commit-hub/pkg/aicommit/provider/openai_compat"
+)
+
+// AzureClient is an OpenAI-compatible client bound to one Azure OpenAI deployment.
+type AzureClient struct {
+	*compat.Client
+	deployment string
+}
+
+// NewAzureClient returns a client for deployment on the Azure OpenAI resource at
+// endpoint, e.g. https://my-resource.openai.azure.com.
+func NewAzureClient(provider, apiKey, endpoint, deployment, apiVersion string) (*AzureClient, error) {
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, errors.New("azure API key is required")
+	}
+	if strings.TrimSpace(endpoint) == "" {
+		return nil, errors.New("azure endpoint is required")
+	}
+	if strings.TrimSpace(deployment) == "" {
+		return nil, errors.New("azure deployment is required")
+	}
+	if strings.TrimSpace(apiVersion) == "" {
+		return nil, errors.New("azure api-version is required")
+	}
+
+	baseURL := strings.TrimRight(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment)
+	c := compat.NewCompatClient(provider, "", deployment, baseURL,
+		option.WithQueryAdd("api-version", apiVersion),
+		option.WithHeader("api-key", apiKey),
+		// Never send a bearer token picked up from OPENAI_API_KEY to Azure.
+		option.WithHeaderDel("Authorization"),
+	)
+	// Azure supports strict JSON schema structured outputs since 2024-08-01.
+	c.JSONSchema = true
+	return &AzureClient{Client: c, deployment: deployment}, nil
+}
+
+// ListModels returns the configured deployment. Deployments are managed in the Azure
+// portal and cannot be listed with the API key of a single resource.
+func (c *AzureClient) ListModels(ctx context.Context) ([]string, error) {
+	return []string{c.deployment}, nil
+}
+
+var _ ai.AIClient = (*AzureClient)(nil)
+var _ ai.StreamingAIClient = (*AzureClient)(nil)
+var _ ai.ModelLister = (*AzureClient)(nil)
+var _ ai.StructuredOutputClient = (*AzureClient)(nil)
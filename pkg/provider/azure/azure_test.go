@@ -0,0 +1,91 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+// newAzureServer stands in for an Azure OpenAI resource with one deployment.
+func newAzureServer(t *testing.T, apiVersion string, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/commit-gpt/chat/completions", r.URL.Path)
+		assert.Equal(t, apiVersion, r.URL.Query().Get("api-version"))
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"), "Azure must not receive a bearer token")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAzureClient_StreamCommitMessage(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-should-not-leak")
+	server := newAzureServer(t, "2024-10-21", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"feat: ", "add azure"} {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	factory, ok := registry.Get(ProviderName)
+	require.True(t, ok)
+	client, err := factory(context.Background(), ProviderName, config.ProviderSettings{
+		APIKey:  "azure-key",
+		BaseURL: server.URL + "/",
+		Model:   "commit-gpt",
+	})
+	require.NoError(t, err)
+
+	sc, ok := client.(ai.StreamingAIClient)
+	require.True(t, ok)
+
+	var usage ai.Usage
+	ctx := ai.WithUsageRecorder(context.Background(), func(u ai.Usage) { usage = u })
+	var deltas []string
+	msg, err := sc.StreamCommitMessage(ctx, "prompt", func(d string) { deltas = append(deltas, d) })
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add azure", msg)
+	assert.Equal(t, []string{"feat: ", "add azure"}, deltas)
+	assert.Equal(t, 12, usage.PromptTokens)
+	assert.Equal(t, ProviderName, usage.Provider)
+}
+
+func TestAzureClient_GetCommitMessage(t *testing.T) {
+	server := newAzureServer(t, "2025-01-01-preview", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"fix: azure"},"finish_reason":"stop"}]}`)
+	})
+
+	client, err := NewAzureClient(ProviderName, "azure-key", server.URL, "commit-gpt", "2025-01-01-preview")
+	require.NoError(t, err)
+	msg, err := client.GetCommitMessage(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "fix: azure", msg)
+
+	models, err := client.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"commit-gpt"}, models)
+}
+
+func TestNewAzureClient_RequiresSettings(t *testing.T) {
+	_, err := NewAzureClient(ProviderName, "", "https://r.openai.azure.com", "d", DefaultAPIVersion)
+	assert.ErrorContains(t, err, "API key")
+	_, err = NewAzureClient(ProviderName, "key", "", "d", DefaultAPIVersion)
+	assert.ErrorContains(t, err, "endpoint")
+	_, err = NewAzureClient(ProviderName, "key", "https://r.openai.azure.com", "", DefaultAPIVersion)
+	assert.ErrorContains(t, err, "deployment")
+}
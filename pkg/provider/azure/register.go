@@ -0,0 +1,44 @@
+package azure
+
+import (
+	"context"
+	"strings"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+const ProviderName = "azure"
+
+// DefaultAPIVersion is the Azure OpenAI API version used when none is configured.
+const DefaultAPIVersion = "2024-10-21"
+
+// factory maps the provider settings to Azure:
+//
+//	baseURL:    resource endpoint, e.g. https://my-resource.openai.azure.com
+//	model:      deployment name
+//	apiVersion: api-version query parameter, DefaultAPIVersion if empty
+func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
+	apiVersion := ps.APIVersion
+	if strings.TrimSpace(apiVersion) == "" {
+		apiVersion = DefaultAPIVersion
+	}
+	c, err := NewAzureClient(name, ps.APIKey, ps.BaseURL, ps.Model, apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	c.Params = ps.Params
+	return c, nil
+}
+
+func init() {
+	registry.Register(ProviderName, factory)
+	registry.RegisterDefaults(ProviderName, config.ProviderSettings{APIVersion: DefaultAPIVersion})
+	registry.SetRequiresAPIKey(ProviderName, true)
+	registry.SetCapabilities(ProviderName, registry.Capabilities{
+		Streaming: true,
+		JSONMode:  true,
+		Params:    registry.ParamLimits{MaxTemperature: 2, TopP: true, MaxOutputTokens: 16384, SystemPrompt: true},
+	})
+}
@@ -0,0 +1,120 @@
+// Package custom registers user-defined OpenAI-compatible providers declared in config.yaml.
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	compat "github.com/allanpk716/ai-commit-hub/pkg/aicommit/provider/openai_compat"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+var (
+	mu         sync.Mutex
+	registered = map[string]bool{}
+)
+
+// Sync registers the given custom providers and unregisters custom providers that are
+// no longer declared. Invalid entries and names that clash with built-in providers are
+// skipped and reported in the returned error; valid entries are registered regardless.
+func Sync(providers []config.CustomProvider) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var errs []error
+	declared := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		name := strings.TrimSpace(p.Name)
+		switch {
+		case name == "":
+			errs = append(errs, errors.New("custom provider without name"))
+			continue
+		case strings.TrimSpace(p.BaseURL) == "":
+			errs = append(errs, fmt.Errorf("custom provider %q: baseURL is required", name))
+			continue
+		case declared[name]:
+			errs = append(errs, fmt.Errorf("custom provider %q is declared more than once", name))
+			continue
+		case registry.Has(name) && !registered[name]:
+			errs = append(errs, fmt.Errorf("custom provider %q conflicts with a built-in provider", name))
+			continue
+		}
+
+		declared[name] = true
+		registry.Register(name, factory(p.Headers))
+		registry.RegisterDefaults(name, aicommitconfig.ProviderSettings{
+			APIKey:  p.APIKey,
+			Model:   p.Model,
+			BaseURL: p.BaseURL,
+		})
+		registry.SetRequiresAPIKey(name, p.RequiresAPIKey)
+		registry.SetCapabilities(name, capabilities(p))
+	}
+
+	for name := range registered {
+		if !declared[name] {
+			registry.Unregister(name)
+		}
+	}
+	registered = declared
+
+	return errors.Join(errs...)
+}
+
+// IsCustom reports whether name is a registered custom provider.
+func IsCustom(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return registered[name]
+}
+
+func factory(headers map[string]string) registry.Factory {
+	opts := make([]option.RequestOption, 0, len(headers))
+	for k, v := range headers {
+		opts = append(opts, option.WithHeader(k, v))
+	}
+	return func(ctx context.Context, name string, ps aicommitconfig.ProviderSettings) (ai.AIClient, error) {
+		// Settings from the providers section override the custom provider declaration.
+		defaults, _ := registry.GetDefaults(name)
+		c := compat.NewCompatClient(name, pick(ps.APIKey, defaults.APIKey), pick(ps.Model, defaults.Model), pick(ps.BaseURL, defaults.BaseURL), opts...)
+		c.Params = ps.Params
+		return c, nil
+	}
+}
+
+// capabilities describes a custom provider: an OpenAI-compatible server streams,
+// supports JSON mode and the OpenAI sampling parameters; it is local when it listens
+// on the loopback interface.
+func capabilities(p config.CustomProvider) registry.Capabilities {
+	c := registry.Capabilities{
+		Streaming: true,
+		JSONMode:  true,
+		Params:    registry.ParamLimits{MaxTemperature: 2, TopP: true, SystemPrompt: true},
+	}
+	if u, err := url.Parse(p.BaseURL); err == nil {
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		c.Local = host == "localhost" || (ip != nil && ip.IsLoopback())
+	}
+	if p.Model != "" {
+		c.Models = []string{p.Model}
+	}
+	return c
+}
+
+func pick(s, dft string) string {
+	if strings.TrimSpace(s) != "" {
+		return s
+	}
+	return dft
+}
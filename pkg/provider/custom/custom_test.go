@@ -0,0 +1,173 @@
+package custom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+func TestSync_RegistersProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "team-a", r.Header.Get("X-Team"))
+		assert.Equal(t, "Bearer gateway-key", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"qwen","choices":[{"index":0,"message":{"role":"assistant","content":"feat: gateway"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	err := Sync([]config.CustomProvider{{
+		Name:           "team-gateway",
+		BaseURL:        server.URL + "/v1",
+		Model:          "qwen",
+		APIKey:         "gateway-key",
+		Headers:        map[string]string{"X-Team": "team-a"},
+		RequiresAPIKey: true,
+	}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = Sync(nil) })
+
+	assert.True(t, IsCustom("team-gateway"))
+	assert.True(t, registry.RequiresAPIKey("team-gateway"))
+	caps, ok := registry.GetCapabilities("team-gateway")
+	require.True(t, ok)
+	assert.True(t, caps.Streaming)
+	assert.True(t, caps.Local, "httptest server listens on 127.0.0.1")
+	assert.Equal(t, []string{"qwen"}, caps.Models)
+
+	factory, ok := registry.Get("team-gateway")
+	require.True(t, ok)
+	client, err := factory(context.Background(), "team-gateway", aicommitconfig.ProviderSettings{})
+	require.NoError(t, err)
+
+	msg, err := client.GetCommitMessage(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "feat: gateway", msg)
+	_, streaming := client.(ai.StreamingAIClient)
+	assert.True(t, streaming)
+}
+
+func TestSync_UnregistersRemovedProviders(t *testing.T) {
+	require.NoError(t, Sync([]config.CustomProvider{{Name: "lm-studio", BaseURL: "http://localhost:1234/v1"}}))
+	assert.True(t, registry.Has("lm-studio"))
+
+	require.NoError(t, Sync(nil))
+	assert.False(t, registry.Has("lm-studio"))
+	assert.False(t, IsCustom("lm-studio"))
+}
+
+func TestSync_InvalidEntries(t *testing.T) {
+	registry.Register("builtin-test", func(ctx context.Context, name string, ps aicommitconfig.ProviderSettings) (ai.AIClient, error) {
+		return nil, nil
+	})
+	t.Cleanup(func() {
+		registry.Unregister("builtin-test")
+		_ = Sync(nil)
+	})
+
+	err := Sync([]config.CustomProvider{
+		{Name: "", BaseURL: "http://localhost"},
+		{Name: "no-url"},
+		{Name: "builtin-test", BaseURL: "http://localhost"},
+		{Name: "vllm", BaseURL: "http://localhost:8000/v1"},
+		{Name: "vllm", BaseURL: "http://localhost:8001/v1"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "baseURL is required")
+	assert.Contains(t, err.Error(), "conflicts with a built-in provider")
+	assert.Contains(t, err.Error(), "declared more than once")
+
+	assert.True(t, IsCustom("vllm"), "有效的条目仍然注册")
+	assert.False(t, IsCustom("builtin-test"))
+	defaults, _ := registry.GetDefaults("vllm")
+	assert.Equal(t, "http://localhost:8000/v1", defaults.BaseURL)
+}
+
+func TestFactory_AppliesGenerationParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			Temperature *float64 `json:"temperature"`
+			TopP        *float64 `json:"top_p"`
+			MaxTokens   int      `json:"max_tokens"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Messages, 2)
+		assert.Equal(t, "system", body.Messages[0].Role)
+		assert.Equal(t, "Write terse messages.", body.Messages[0].Content)
+		assert.Equal(t, "user", body.Messages[1].Role)
+		require.NotNil(t, body.Temperature)
+		assert.Equal(t, 0.0, *body.Temperature, "zero temperature must still be sent")
+		require.NotNil(t, body.TopP)
+		assert.Equal(t, 0.9, *body.TopP)
+		assert.Equal(t, 200, body.MaxTokens)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"qwen","choices":[{"index":0,"message":{"role":"assistant","content":"fix: tune"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	require.NoError(t, Sync([]config.CustomProvider{{Name: "tuned", BaseURL: server.URL, Model: "qwen"}}))
+	t.Cleanup(func() { _ = Sync(nil) })
+
+	temperature, topP := 0.0, 0.9
+	params := aicommitconfig.GenerationParams{Temperature: &temperature, TopP: &topP, MaxOutputTokens: 200, SystemPrompt: "Write terse messages."}
+	require.NoError(t, registry.ValidateParams("tuned", params))
+
+	factory, ok := registry.Get("tuned")
+	require.True(t, ok)
+	client, err := factory(context.Background(), "tuned", aicommitconfig.ProviderSettings{Params: params})
+	require.NoError(t, err)
+	msg, err := client.GetCommitMessage(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "fix: tune", msg)
+}
+
+func TestFactory_SeparatesReasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{
+			`{"reasoning_content":"field reasoning"}`,
+			`{"content":"<think>inline"}`,
+			`{"content":" reasoning</think>\n\nfeat: "}`,
+			`{"content":"add thinking"}`,
+		} {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"r1\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	require.NoError(t, Sync([]config.CustomProvider{{Name: "reasoner", BaseURL: server.URL, Model: "r1"}}))
+	t.Cleanup(func() { _ = Sync(nil) })
+
+	factory, ok := registry.Get("reasoner")
+	require.True(t, ok)
+	client, err := factory(context.Background(), "reasoner", aicommitconfig.ProviderSettings{})
+	require.NoError(t, err)
+	streamer, ok := client.(ai.StreamingAIClient)
+	require.True(t, ok)
+
+	var reasoning string
+	ctx := ai.WithReasoningHandler(context.Background(), func(delta string) { reasoning += delta })
+	var deltas string
+	msg, err := streamer.StreamCommitMessage(ctx, "prompt", func(delta string) { deltas += delta })
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add thinking", msg)
+	assert.Equal(t, "feat: add thinking", deltas)
+	assert.Equal(t, "field reasoninginline reasoning", reasoning)
+}
@@ -11,11 +11,24 @@ import (
 const ProviderName = "deepseek"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
-    return NewDeepseekClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    c, err := NewDeepseekClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    if err != nil {
+        return nil, err
+    }
+    c.Params = ps.Params
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "deepseek-chat", BaseURL: "https://api.deepseek.com/v1"})
     registry.SetRequiresAPIKey(ProviderName, true)
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming:     true,
+        ContextTokens: 64000,
+        JSONMode:      true,
+        Reasoning:     true,
+        Models:        []string{"deepseek-chat", "deepseek-reasoner"},
+        Params:        registry.ParamLimits{MaxTemperature: 2, TopP: true, MaxOutputTokens: 8192, SystemPrompt: true},
+    })
 }
@@ -2,18 +2,23 @@ package google
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
 	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
 
 type GoogleClient struct {
     ai.BaseAIClient
     client *genai.GenerativeModel
+    api    *genai.Client // used for model discovery; nil when created via NewClient
+    model  string
 }
 
 func NewClient(provider string, client *genai.GenerativeModel) *GoogleClient {
@@ -23,7 +28,32 @@ func NewClient(provider string, client *genai.GenerativeModel) *GoogleClient {
     }
 }
 
+// ApplyParams sets the generation parameters on model.
+func ApplyParams(model *genai.GenerativeModel, p config.GenerationParams) {
+	if p.Temperature != nil {
+		model.SetTemperature(float32(*p.Temperature))
+	}
+	if p.TopP != nil {
+		model.SetTopP(float32(*p.TopP))
+	}
+	if p.MaxOutputTokens > 0 {
+		model.SetMaxOutputTokens(int32(p.MaxOutputTokens))
+	}
+	if strings.TrimSpace(p.SystemPrompt) != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(p.SystemPrompt))
+	}
+}
+
 func NewGoogleProClient(ctx context.Context, apiKey string, modelName string, baseURL string) (*genai.GenerativeModel, error) {
+	client, err := newGenaiClient(ctx, apiKey, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	model := client.GenerativeModel(modelName)
+	return model, nil
+}
+
+func newGenaiClient(ctx context.Context, apiKey string, baseURL string) (*genai.Client, error) {
 	opts := []option.ClientOption{option.WithAPIKey(apiKey)}
 	if strings.TrimSpace(baseURL) != "" {
 		opts = append(opts, option.WithEndpoint(baseURL))
@@ -32,8 +62,7 @@ func NewGoogleProClient(ctx context.Context, apiKey string, modelName string, ba
 	if err != nil {
 		return nil, fmt.Errorf("error creating google client: %w", err)
 	}
-	model := client.GenerativeModel(modelName)
-	return model, nil
+	return client, nil
 }
 
 func (gc *GoogleClient) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
@@ -44,12 +73,139 @@ func (gc *GoogleClient) GetCommitMessage(ctx context.Context, prompt string) (st
 	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
 		return "", fmt.Errorf("no response from Google")
 	}
+	gc.reportUsage(ctx, resp.UsageMetadata)
 	if text, ok := resp.Candidates[0].Content.Parts[0].(genai.Text); ok {
 		return string(text), nil
 	}
 	return "", fmt.Errorf("unexpected response format from Google")
 }
 
+// StreamCommitMessage streams the response using GenerateContentStream, calling onDelta
+// with the text of every chunk of the first candidate.
+func (gc *GoogleClient) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(string)) (string, error) {
+	iter := gc.client.GenerateContentStream(ctx, genai.Text(prompt))
+	var sb strings.Builder
+	var usage *genai.UsageMetadata
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to stream content: %w", err)
+		}
+		// Usage metadata is cumulative; the last chunk carries the totals.
+		if resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok && text != "" {
+				onDelta(string(text))
+				sb.WriteString(string(text))
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("no response from Google")
+	}
+	gc.reportUsage(ctx, usage)
+	return sb.String(), nil
+}
+
+// GenerateStructured asks Gemini for a JSON response constrained to schema.
+func (gc *GoogleClient) GenerateStructured(ctx context.Context, prompt, name string, schema *ai.Schema) (string, error) {
+	// Configure a copy so the shared model keeps producing plain text.
+	model := *gc.client
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = toGenaiSchema(schema)
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate structured content %s: %w", name, err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", fmt.Errorf("no response from Google")
+	}
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			sb.WriteString(string(text))
+		}
+	}
+	gc.reportUsage(ctx, resp.UsageMetadata)
+	return sb.String(), nil
+}
+
+// toGenaiSchema converts a JSON schema to Gemini's schema type. Gemini has no
+// additionalProperties, so that constraint is dropped.
+func toGenaiSchema(s *ai.Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	out := &genai.Schema{Description: s.Description, Required: s.Required, Items: toGenaiSchema(s.Items)}
+	switch s.Type {
+	case "object":
+		out.Type = genai.TypeObject
+	case "array":
+		out.Type = genai.TypeArray
+	case "boolean":
+		out.Type = genai.TypeBoolean
+	case "integer":
+		out.Type = genai.TypeInteger
+	case "number":
+		out.Type = genai.TypeNumber
+	default:
+		out.Type = genai.TypeString
+	}
+	if len(s.Properties) > 0 {
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			out.Properties[name] = toGenaiSchema(prop)
+		}
+	}
+	return out
+}
+
+// reportUsage forwards token usage from the response to the recorder in ctx.
+func (gc *GoogleClient) reportUsage(ctx context.Context, usage *genai.UsageMetadata) {
+	if usage == nil {
+		return
+	}
+	ai.ReportUsage(ctx, ai.Usage{
+		Provider:         gc.ProviderName(),
+		Model:            gc.model,
+		PromptTokens:     int(usage.PromptTokenCount),
+		CompletionTokens: int(usage.CandidatesTokenCount),
+	})
+}
+
+// ListModels returns the Gemini models that support content generation.
+func (gc *GoogleClient) ListModels(ctx context.Context) ([]string, error) {
+	if gc.api == nil {
+		return nil, errors.New("google client was created without API access")
+	}
+	iter := gc.api.ListModels(ctx)
+	var models []string
+	for {
+		m, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Google models: %w", err)
+		}
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "generateContent" {
+				models = append(models, m.Name)
+				break
+			}
+		}
+	}
+	return models, nil
+}
+
 // SanitizeResponse cleans Google specific responses if needed.  Overrides default.
 func (gc *GoogleClient) SanitizeResponse(message, commitType string) string {
 	return gc.BaseAIClient.SanitizeResponse(message, commitType)
@@ -60,3 +216,6 @@ func (gc *GoogleClient) MaybeSummarizeDiff(diff string, maxLength int) (string,
 }
 
 var _ ai.AIClient = (*GoogleClient)(nil)
+var _ ai.StreamingAIClient = (*GoogleClient)(nil)
+var _ ai.ModelLister = (*GoogleClient)(nil)
+var _ ai.StructuredOutputClient = (*GoogleClient)(nil)
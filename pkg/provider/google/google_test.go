@@ -0,0 +1,110 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/providererr"
+)
+
+// newTestClient 创建指向本地 httptest 服务的 GoogleClient
+func newTestClient(t *testing.T, handler http.HandlerFunc) *GoogleClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	temperature := 0.2
+	client, err := factory(context.Background(), ProviderName, config.ProviderSettings{
+		APIKey:  "test-key",
+		Model:   "models/gemini-2.5-flash",
+		BaseURL: server.URL,
+		Params:  config.GenerationParams{Temperature: &temperature, SystemPrompt: "be brief"},
+	})
+	require.NoError(t, err)
+	return client.(*GoogleClient)
+}
+
+func textChunk(text string) string {
+	return fmt.Sprintf(`{"candidates":[{"content":{"role":"model","parts":[{"text":%q}]}}]}`, text)
+}
+
+func TestGoogleClient_StreamCommitMessage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, "models/gemini-2.5-flash:streamGenerateContent"), r.URL.Path)
+
+		var body struct {
+			Contents []struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"contents"`
+			GenerationConfig struct {
+				Temperature float64 `json:"temperature"`
+			} `json:"generationConfig"`
+			SystemInstruction struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"systemInstruction"`
+		}
+		if assert.NoError(t, json.NewDecoder(r.Body).Decode(&body)) {
+			assert.Equal(t, "prompt", body.Contents[0].Parts[0].Text)
+			assert.InDelta(t, 0.2, body.GenerationConfig.Temperature, 1e-6)
+			assert.Equal(t, "be brief", body.SystemInstruction.Parts[0].Text)
+		}
+
+		// REST 流式接口返回 JSON 数组，每个元素为一个分块，最后一个分块携带 usage
+		chunks := []string{
+			textChunk("feat: "),
+			textChunk("add gemini "),
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"streaming"}]},"finishReason":1}],"usageMetadata":{"promptTokenCount":30,"candidatesTokenCount":5,"totalTokenCount":35}}`,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "[")
+		for i, chunk := range chunks {
+			if i > 0 {
+				fmt.Fprint(w, ",\n")
+			}
+			fmt.Fprint(w, chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "]")
+	})
+
+	var usage ai.Usage
+	ctx := ai.WithUsageRecorder(context.Background(), func(u ai.Usage) { usage = u })
+
+	var deltas []string
+	msg, err := client.StreamCommitMessage(ctx, "prompt", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add gemini streaming", msg)
+	assert.Equal(t, []string{"feat: ", "add gemini ", "streaming"}, deltas)
+	assert.Equal(t, "gemini-2.5-flash", usage.Model)
+	assert.Equal(t, 30, usage.PromptTokens)
+	assert.Equal(t, 5, usage.CompletionTokens)
+}
+
+func TestGoogleClient_StreamCommitMessage_Error(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`)
+	})
+
+	_, err := client.StreamCommitMessage(context.Background(), "prompt", func(string) {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quota exceeded")
+	assert.Equal(t, http.StatusTooManyRequests, providererr.StatusCode(err))
+}
@@ -2,6 +2,7 @@ package google
 
 import (
 	"context"
+	"strings"
 
 	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
 	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
@@ -11,15 +12,28 @@ import (
 const ProviderName = "google"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
-    gm, err := NewGoogleProClient(ctx, ps.APIKey, ps.Model, ps.BaseURL)
+    api, err := newGenaiClient(ctx, ps.APIKey, ps.BaseURL)
     if err != nil {
         return nil, err
     }
-    return NewClient(name, gm), nil
+    model := api.GenerativeModel(ps.Model)
+    ApplyParams(model, ps.Params)
+    c := NewClient(name, model)
+    c.api = api
+    c.model = strings.TrimPrefix(ps.Model, "models/")
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "models/gemini-2.5-flash", BaseURL: "https://generativelanguage.googleapis.com"})
     registry.SetRequiresAPIKey(ProviderName, true)
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming:     true,
+        ContextTokens: 1048576,
+        JSONMode:      true,
+        Reasoning:     true,
+        Models:        []string{"models/gemini-2.5-flash", "models/gemini-2.5-pro", "models/gemini-2.0-flash"},
+        Params:        registry.ParamLimits{MaxTemperature: 2, TopP: true, MaxOutputTokens: 65536, SystemPrompt: true},
+    })
 }
@@ -2,6 +2,7 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,12 +11,15 @@ import (
 
 	"github.com/ollama/ollama/api"
 	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 )
 
 type OllamaClient struct {
     ai.BaseAIClient
     client *api.Client
     model  string
+    // Params are applied to every request.
+    Params config.GenerationParams
 }
 
 func NewOllamaClient(provider, baseURL, model string) (*OllamaClient, error) {
@@ -37,22 +41,159 @@ func NewOllamaClient(provider, baseURL, model string) (*OllamaClient, error) {
 func (oc *OllamaClient) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
 	stream := false
 	req := &api.GenerateRequest{
-		Model:  oc.model,
-		Prompt: prompt,
-		Stream: &stream,
+		Model:   oc.model,
+		Prompt:  prompt,
+		System:  oc.Params.SystemPrompt,
+		Stream:  &stream,
+		Options: oc.options(),
 	}
-	var response string
+	var response, thinking string
+	var metrics api.Metrics
 	err := oc.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
 		response = resp.Response
+		thinking = resp.Thinking
+		metrics = resp.Metrics
 		return nil
 	})
 	if err != nil {
 		return "", fmt.Errorf("ollama generate failed: %w", err)
 	}
-	if strings.TrimSpace(response) == "" {
+	response = answer(ctx, response, thinking)
+	if response == "" {
 		return "", errors.New("empty response from Ollama")
 	}
-	return strings.TrimSpace(response), nil
+	oc.reportUsage(ctx, metrics)
+	return response, nil
+}
+
+// StreamCommitMessage streams the response through Ollama's chat API, calling onDelta
+// with the content of every message chunk.
+func (oc *OllamaClient) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(string)) (string, error) {
+	stream := true
+	req := &api.ChatRequest{
+		Model:    oc.model,
+		Messages: oc.messages(prompt),
+		Stream:   &stream,
+		Options:  oc.options(),
+	}
+	var sb strings.Builder
+	var metrics api.Metrics
+	// Thinking models send their reasoning in a separate field or in <think> blocks.
+	splitter := ai.NewReasoningSplitter(ctx, onDelta)
+	err := oc.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+		splitter.Reasoning(resp.Message.Thinking)
+		splitter.Content(resp.Message.Content)
+		sb.WriteString(resp.Message.Content)
+		if resp.Done {
+			metrics = resp.Metrics
+		}
+		return nil
+	})
+	splitter.Flush()
+	if err != nil {
+		return "", fmt.Errorf("ollama chat failed: %w", err)
+	}
+	text, _ := ai.StripReasoning(sb.String())
+	if text == "" {
+		return "", errors.New("empty response from Ollama")
+	}
+	oc.reportUsage(ctx, metrics)
+	return text, nil
+}
+
+// GenerateStructured constrains the chat response to schema using Ollama's format parameter.
+func (oc *OllamaClient) GenerateStructured(ctx context.Context, prompt, name string, schema *ai.Schema) (string, error) {
+	format, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode schema %s: %w", name, err)
+	}
+	stream := false
+	req := &api.ChatRequest{
+		Model:    oc.model,
+		Messages: oc.messages(prompt),
+		Stream:   &stream,
+		Format:   format,
+		Options:  oc.options(),
+	}
+	var content, thinking string
+	var metrics api.Metrics
+	err = oc.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+		content += resp.Message.Content
+		thinking += resp.Message.Thinking
+		if resp.Done {
+			metrics = resp.Metrics
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("ollama chat failed: %w", err)
+	}
+	content = answer(ctx, content, thinking)
+	if content == "" {
+		return "", errors.New("empty response from Ollama")
+	}
+	oc.reportUsage(ctx, metrics)
+	return content, nil
+}
+
+// answer returns content without <think> blocks and reports them, and the thinking
+// field of the response, as reasoning.
+func answer(ctx context.Context, content, thinking string) string {
+	ai.ReportReasoning(ctx, thinking)
+	text, thought := ai.StripReasoning(content)
+	ai.ReportReasoning(ctx, thought)
+	return text
+}
+
+// messages returns the chat messages for prompt, preceded by the system prompt if set.
+func (oc *OllamaClient) messages(prompt string) []api.Message {
+	var msgs []api.Message
+	if strings.TrimSpace(oc.Params.SystemPrompt) != "" {
+		msgs = append(msgs, api.Message{Role: "system", Content: oc.Params.SystemPrompt})
+	}
+	return append(msgs, api.Message{Role: "user", Content: prompt})
+}
+
+// options maps the generation parameters to Ollama model options; nil keeps the
+// Modelfile defaults.
+func (oc *OllamaClient) options() map[string]any {
+	opts := map[string]any{}
+	if oc.Params.Temperature != nil {
+		opts["temperature"] = *oc.Params.Temperature
+	}
+	if oc.Params.TopP != nil {
+		opts["top_p"] = *oc.Params.TopP
+	}
+	if oc.Params.MaxOutputTokens > 0 {
+		opts["num_predict"] = oc.Params.MaxOutputTokens
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return opts
+}
+
+// reportUsage forwards the prompt and generated token counts to the recorder in ctx.
+func (oc *OllamaClient) reportUsage(ctx context.Context, metrics api.Metrics) {
+	ai.ReportUsage(ctx, ai.Usage{
+		Provider:         oc.ProviderName(),
+		Model:            oc.model,
+		PromptTokens:     metrics.PromptEvalCount,
+		CompletionTokens: metrics.EvalCount,
+	})
+}
+
+// ListModels returns the models pulled on the Ollama server (/api/tags).
+func (oc *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	resp, err := oc.client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ollama list models failed: %w", err)
+	}
+	models := make([]string, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
 }
 
 func (oc *OllamaClient) SanitizeResponse(message, commitType string) string {
@@ -71,3 +212,6 @@ func pick(s, dft string) string {
 }
 
 var _ ai.AIClient = (*OllamaClient)(nil)
+var _ ai.StreamingAIClient = (*OllamaClient)(nil)
+var _ ai.ModelLister = (*OllamaClient)(nil)
+var _ ai.StructuredOutputClient = (*OllamaClient)(nil)
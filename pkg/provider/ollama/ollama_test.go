@@ -0,0 +1,135 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+)
+
+func TestOllamaClient_StreamCommitMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+
+		var req api.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "llama3", req.Model)
+		require.NotNil(t, req.Stream)
+		assert.True(t, *req.Stream)
+		require.Len(t, req.Messages, 1)
+		assert.Equal(t, "prompt", req.Messages[0].Content)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, chunk := range []string{"feat: ", "add ", "streaming"} {
+			fmt.Fprintf(w, `{"model":"llama3","message":{"role":"assistant","content":%q},"done":false}`+"\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":42,"eval_count":7}`)
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(ProviderName, server.URL, "llama3")
+	require.NoError(t, err)
+
+	var usage ai.Usage
+	ctx := ai.WithUsageRecorder(context.Background(), func(u ai.Usage) { usage = u })
+
+	var deltas []string
+	msg, err := client.StreamCommitMessage(ctx, "prompt", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add streaming", msg)
+	assert.Equal(t, []string{"feat: ", "add ", "streaming"}, deltas)
+	assert.Equal(t, 42, usage.PromptTokens)
+	assert.Equal(t, 7, usage.CompletionTokens)
+}
+
+func TestOllamaClient_StreamCommitMessage_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, `{"error":"model 'llama3' not found"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(ProviderName, server.URL, "llama3")
+	require.NoError(t, err)
+
+	_, err = client.StreamCommitMessage(context.Background(), "prompt", func(string) {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestOllamaClient_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"models":[{"name":"llama3:latest","model":"llama3:latest"},{"name":"qwen2.5-coder:7b","model":"qwen2.5-coder:7b"}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(ProviderName, server.URL, "llama3")
+	require.NoError(t, err)
+
+	models, err := client.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"llama3:latest", "qwen2.5-coder:7b"}, models)
+}
+
+func TestOllamaClient_GenerationParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Messages, 2)
+		assert.Equal(t, "system", req.Messages[0].Role)
+		assert.Equal(t, "Use English.", req.Messages[0].Content)
+		assert.Equal(t, 0.2, req.Options["temperature"])
+		assert.EqualValues(t, 100, req.Options["num_predict"])
+		assert.NotContains(t, req.Options, "top_p")
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"docs: tune"},"done":true}`)
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(ProviderName, server.URL, "llama3")
+	require.NoError(t, err)
+	temperature := 0.2
+	client.Params = config.GenerationParams{Temperature: &temperature, MaxOutputTokens: 100, SystemPrompt: "Use English."}
+
+	msg, err := client.StreamCommitMessage(context.Background(), "prompt", func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, "docs: tune", msg)
+}
+
+func TestOllamaClient_StreamCommitMessage_Thinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"The diff renames "},"done":false}`)
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"a function."},"done":false}`)
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":"refactor: rename helper"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(ProviderName, server.URL, "qwen3")
+	require.NoError(t, err)
+
+	var reasoning string
+	ctx := ai.WithReasoningHandler(context.Background(), func(delta string) { reasoning += delta })
+	var deltas []string
+	msg, err := client.StreamCommitMessage(ctx, "prompt", func(delta string) { deltas = append(deltas, delta) })
+	require.NoError(t, err)
+	assert.Equal(t, "refactor: rename helper", msg)
+	assert.Equal(t, []string{"refactor: rename helper"}, deltas)
+	assert.Equal(t, "The diff renames a function.", reasoning)
+}
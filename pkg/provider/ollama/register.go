@@ -11,11 +11,26 @@ import (
 const ProviderName = "ollama"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
-    return NewOllamaClient(name, ps.BaseURL, ps.Model)
+    c, err := NewOllamaClient(name, ps.BaseURL, ps.Model)
+    if err != nil {
+        return nil, err
+    }
+    c.Params = ps.Params
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "llama2", BaseURL: "http://localhost:11434"})
     registry.SetRequiresAPIKey(ProviderName, false)
+    // The context window depends on the local model and its num_ctx, so it is left
+    // unknown; set providers.ollama.contextTokens to summarize diffs that do not fit.
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming: true,
+        JSONMode:  true,
+        Reasoning: true,
+        Local:     true,
+        Models:    []string{"llama2", "llama3", "qwen2.5-coder"},
+        Params:    registry.ParamLimits{MaxTemperature: 2, TopP: true, SystemPrompt: true},
+    })
 }
@@ -7,5 +7,9 @@ import (
 // NewOpenAIClient returns an OpenAI-compatible client powered by the official SDK.
 // It reuses the generic compat client to avoid duplication.
 func NewOpenAIClient(provider, key, model, baseURL string) *openaic.Client {
-    return openaic.NewCompatClient(provider, key, model, baseURL)
+    c := openaic.NewCompatClient(provider, key, model, baseURL)
+    // OpenAI supports strict JSON schema structured outputs.
+    c.JSONSchema = true
+    c.MaxCompletionTokens = true
+    return c
 }
@@ -12,11 +12,20 @@ const ProviderName = "openai"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
     // No ctx usage needed for OpenAI client construction.
-    return NewOpenAIClient(name, ps.APIKey, ps.Model, ps.BaseURL), nil
+    c := NewOpenAIClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    c.Params = ps.Params
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "chatgpt-4o-latest", BaseURL: "https://api.openai.com/v1"})
     registry.SetRequiresAPIKey(ProviderName, true)
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming:     true,
+        ContextTokens: 128000,
+        JSONMode:      true,
+        Models:        []string{"chatgpt-4o-latest", "gpt-4o", "gpt-4o-mini", "gpt-4.1", "gpt-4.1-mini"},
+        Params:        registry.ParamLimits{MaxTemperature: 2, TopP: true, MaxOutputTokens: 32768, SystemPrompt: true},
+    })
 }
@@ -13,11 +13,20 @@ const ProviderName = "openrouter"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
     // OpenRouter is OpenAI-compatible; reuse the compat client.
-    return compat.NewCompatClient(name, ps.APIKey, ps.Model, ps.BaseURL), nil
+    c := compat.NewCompatClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    c.Params = ps.Params
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "openrouter/auto", BaseURL: "https://openrouter.ai/api/v1"})
     registry.SetRequiresAPIKey(ProviderName, true)
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming: true,
+        JSONMode:  true,
+        Reasoning: true,
+        Models:    []string{"openrouter/auto"},
+        Params:    registry.ParamLimits{MaxTemperature: 2, TopP: true, SystemPrompt: true},
+    })
 }
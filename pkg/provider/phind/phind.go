@@ -1,7 +1,6 @@
 package phind
 
 import (
-    "bufio"
     "context"
     "encoding/json"
     "fmt"
@@ -11,6 +10,7 @@ import (
     "strings"
 
     "github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+    "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
     "github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
 )
 
@@ -20,6 +20,9 @@ type PhindClient struct {
 	model      string
 	apiBaseURL string
 	token      string
+	// Params are applied to every request. The endpoint only accepts a system prompt;
+	// sampling parameters are chosen by Phind.
+	Params config.GenerationParams
 }
 
 func NewPhindClient(provider, token, model, baseURL string) (*PhindClient, error) {
@@ -57,14 +60,9 @@ func (p *PhindClient) GetCommitMessage(ctx context.Context, prompt string) (stri
 		"additional_extension_context": "",
 		"allow_magic_buttons":          true,
 		"is_vscode_extension":          true,
-		"message_history": []map[string]string{
-			{
-				"content": prompt,
-				"role":    "user",
-			},
-		},
-		"requested_model": p.model,
-		"user_input":      prompt,
+		"message_history":              p.messageHistory(prompt),
+		"requested_model":              p.model,
+		"user_input":                   prompt,
 	}
 
 	data, err := json.Marshal(payload)
@@ -106,20 +104,35 @@ func (p *PhindClient) GetCommitMessage(ctx context.Context, prompt string) (stri
         data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
         bodyStr := string(data)
         _ = json.Unmarshal([]byte(bodyStr), &errResp)
-        if errResp.Error.Message != "" {
-            return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, errResp.Error.Message)
+        msg := errResp.Error.Message
+        if msg == "" {
+            msg = bodyStr
         }
-        return "", fmt.Errorf("unexpected response (status %d): %s", resp.StatusCode, bodyStr)
+        return "", &httpx.StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Message: msg}
     }
 
     // Stream-parse SSE using reusable helper and OpenAI-like decoder.
-    text, _ := httpx.StreamAggregate(ctx, resp.Body, httpx.OpenAIStyleDecoder)
-    if strings.TrimSpace(text) == "" {
+    text, _ := httpx.StreamDeltas(ctx, resp.Body, httpx.OpenAIStyleDecoder, func(d httpx.Delta) {
+        ai.ReportReasoning(ctx, d.Reasoning)
+    })
+    text, reasoning := ai.StripReasoning(text)
+    ai.ReportReasoning(ctx, reasoning)
+    if text == "" {
         return "", fmt.Errorf("no completion choice received")
     }
     return text, nil
 }
 
+// messageHistory returns the conversation sent for prompt, preceded by the system
+// prompt if set.
+func (p *PhindClient) messageHistory(prompt string) []map[string]string {
+    var history []map[string]string
+    if strings.TrimSpace(p.Params.SystemPrompt) != "" {
+        history = append(history, map[string]string{"content": p.Params.SystemPrompt, "role": "system"})
+    }
+    return append(history, map[string]string{"content": prompt, "role": "user"})
+}
+
 func (p *PhindClient) SanitizeResponse(message, commitType string) string {
     return p.BaseAIClient.SanitizeResponse(message, commitType)
 }
@@ -154,14 +167,9 @@ func (p *PhindClient) StreamCommitMessage(ctx context.Context, prompt string, on
         "additional_extension_context": "",
         "allow_magic_buttons":          true,
         "is_vscode_extension":          true,
-        "message_history": []map[string]string{
-            {
-                "content": prompt,
-                "role":    "user",
-            },
-        },
-        "requested_model": p.model,
-        "user_input":      prompt,
+        "message_history":              p.messageHistory(prompt),
+        "requested_model":              p.model,
+        "user_input":                   prompt,
     }
     data, err := json.Marshal(payload)
     if err != nil {
@@ -190,54 +198,33 @@ func (p *PhindClient) StreamCommitMessage(ctx context.Context, prompt string, on
         data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
         var errResp struct{ Error struct{ Message string `json:"message"` } `json:"error"` }
         _ = json.Unmarshal(data, &errResp)
-        if errResp.Error.Message != "" {
-            return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, errResp.Error.Message)
+        msg := errResp.Error.Message
+        if msg == "" {
+            msg = string(data)
         }
-        return "", fmt.Errorf("unexpected response (status %d): %s", resp.StatusCode, string(data))
-    }
-
-    // Stream SSE, emit deltas, and aggregate final content
-    type delta struct{ Content string `json:"content"` }
-    type choice struct{
-        Delta delta `json:"delta"`
-        FinishReason *string `json:"finish_reason"`
-    }
-    type streamResp struct{
-        Type string `json:"type"`
-        Choices []choice `json:"choices"`
-    }
-
-    var out strings.Builder
-    scanner := bufio.NewScanner(resp.Body)
-    // Increase buffer for safety
-    const maxBuf = 1024 * 1024
-    buf := make([]byte, 0, 64*1024)
-    scanner.Buffer(buf, maxBuf)
-
-    for scanner.Scan() {
-        // honor ctx cancellation
-        select { case <-ctx.Done(): return out.String(), ctx.Err(); default: }
-        line := strings.TrimSpace(scanner.Text())
-        if line == "" || !strings.HasPrefix(line, "data: ") { continue }
-        payload := strings.TrimPrefix(line, "data: ")
-        if payload == "[DONE]" { break }
-        var sr streamResp
-        if err := json.Unmarshal([]byte(payload), &sr); err != nil { continue }
-        if sr.Type == "metadata" { continue }
-        if len(sr.Choices) == 0 { continue }
-        d := sr.Choices[0].Delta.Content
-        if d != "" {
-            out.WriteString(d)
-            onDelta(d)
-        }
-        if sr.Choices[0].FinishReason != nil && *sr.Choices[0].FinishReason != "" { break }
+        return "", &httpx.StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Message: msg}
     }
-    if err := scanner.Err(); err != nil {
-        if strings.TrimSpace(out.String()) != "" { return out.String(), nil }
+
+    // Stream SSE, emit answer deltas and report reasoning separately
+    splitter := ai.NewReasoningSplitter(ctx, onDelta)
+    text, err := httpx.StreamDeltas(ctx, resp.Body, httpx.OpenAIStyleDecoder, func(d httpx.Delta) {
+        splitter.Reasoning(d.Reasoning)
+        splitter.Content(d.Content)
+    })
+    splitter.Flush()
+    final, _ := ai.StripReasoning(text)
+    if err != nil {
+        if ctx.Err() != nil {
+            return final, ctx.Err()
+        }
+        if final != "" {
+            return final, nil
+        }
         return "", fmt.Errorf("stream read error: %w", err)
     }
-    final := strings.TrimSpace(out.String())
-    if final == "" { return "", fmt.Errorf("no completion choice received") }
+    if final == "" {
+        return "", fmt.Errorf("no completion choice received")
+    }
     return final, nil
 }
 var _ ai.StreamingAIClient = (*PhindClient)(nil)
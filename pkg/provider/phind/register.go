@@ -11,11 +11,22 @@ import (
 const ProviderName = "phind"
 
 func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
-    return NewPhindClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    c, err := NewPhindClient(name, ps.APIKey, ps.Model, ps.BaseURL)
+    if err != nil {
+        return nil, err
+    }
+    c.Params = ps.Params
+    return c, nil
 }
 
 func init() {
     registry.Register(ProviderName, factory)
     registry.RegisterDefaults(ProviderName, config.ProviderSettings{Model: "Phind-70B", BaseURL: "https://https.extension.phind.com/agent/"})
     registry.SetRequiresAPIKey(ProviderName, false)
+    registry.SetCapabilities(ProviderName, registry.Capabilities{
+        Streaming:     true,
+        ContextTokens: 32000,
+        Models:        []string{"Phind-70B"},
+        Params:        registry.ParamLimits{SystemPrompt: true},
+    })
 }
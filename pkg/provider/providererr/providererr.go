@@ -0,0 +1,152 @@
+// Package providererr classifies errors returned by provider SDKs so callers can
+// report them to the user or decide whether to retry.
+package providererr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/ollama/ollama/api"
+	openai "github.com/openai/openai-go/v2"
+	"google.golang.org/api/googleapi"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+)
+
+// Kind is the category of a provider error.
+type Kind string
+
+const (
+	KindAuth          Kind = "auth"            // invalid or missing API key, no permission
+	KindNetwork       Kind = "network"         // connection refused, DNS failure, timeout
+	KindModelNotFound Kind = "model_not_found" // the configured model does not exist
+	KindQuota         Kind = "quota"           // rate limited or out of credit
+	KindServer        Kind = "server"          // provider-side 5xx error
+	KindUnknown       Kind = "unknown"
+)
+
+// StatusCode extracts the HTTP status code from SDK errors, or 0 if err carries none.
+func StatusCode(err error) int {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code
+	}
+	var ollamaErr api.StatusError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode
+	}
+	var httpErr *httpx.StatusError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+// RetryAfter returns the delay requested by the server's Retry-After header, or 0.
+func RetryAfter(err error) time.Duration {
+	var header http.Header
+	var openaiErr *openai.Error
+	var anthropicErr *anthropic.Error
+	var googleErr *googleapi.Error
+	var httpErr *httpx.StatusError
+	switch {
+	case errors.As(err, &openaiErr) && openaiErr.Response != nil:
+		header = openaiErr.Response.Header
+	case errors.As(err, &anthropicErr) && anthropicErr.Response != nil:
+		header = anthropicErr.Response.Header
+	case errors.As(err, &googleErr):
+		header = googleErr.Header
+	case errors.As(err, &httpErr):
+		header = httpErr.Header
+	}
+	if header == nil {
+		return 0
+	}
+	return httpx.ParseRetryAfter(header)
+}
+
+// IsRetryable reports whether err is transient (rate limit, provider 5xx, timeout
+// or connection failure) so the same request may succeed when retried.
+// Cancellation and exhausted credit are never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "insufficient_quota") {
+		return false
+	}
+	if code := StatusCode(err); code != 0 {
+		return httpx.IsRetryableStatus(code)
+	}
+	switch Classify(err) {
+	case KindNetwork, KindServer:
+		return true
+	case KindQuota:
+		// Rate limits are transient; exhausted credit will not recover on retry.
+		return containsAny(msg, "rate limit", "rate_limit", "too many requests")
+	}
+	return false
+}
+
+// Classify returns the category of err. HTTP status codes are preferred; errors
+// without one are classified by type and, as a last resort, by message.
+func Classify(err error) Kind {
+	if err == nil {
+		return ""
+	}
+
+	switch code := StatusCode(err); {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return KindAuth
+	case code == http.StatusNotFound:
+		return KindModelNotFound
+	case code == http.StatusTooManyRequests || code == http.StatusPaymentRequired:
+		return KindQuota
+	case code >= 500:
+		return KindServer
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindNetwork
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return KindNetwork
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case containsAny(msg, "api key", "api_key", "apikey", "unauthorized", "unauthenticated", "permission denied", "authentication"):
+		return KindAuth
+	case containsAny(msg, "quota", "rate limit", "rate_limit", "resource_exhausted", "insufficient", "too many requests"):
+		return KindQuota
+	case strings.Contains(msg, "model") && containsAny(msg, "not found", "does not exist", "not_found"):
+		return KindModelNotFound
+	case containsAny(msg, "connection refused", "no such host", "connection reset", "timeout", "eof"):
+		return KindNetwork
+	}
+	return KindUnknown
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package providererr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"nil", nil, ""},
+		{"google 401", fmt.Errorf("failed: %w", &googleapi.Error{Code: 401}), KindAuth},
+		{"google 429", &googleapi.Error{Code: 429}, KindQuota},
+		{"ollama 404", fmt.Errorf("ollama chat failed: %w", api.StatusError{StatusCode: 404, ErrorMessage: "model 'x' not found"}), KindModelNotFound},
+		{"ollama 500", api.StatusError{StatusCode: 500}, KindServer},
+		{"deadline", fmt.Errorf("request: %w", context.DeadlineExceeded), KindNetwork},
+		{"dial", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, KindNetwork},
+		{"message api key", errors.New("anthropic API key is required"), KindAuth},
+		{"message quota", errors.New("You exceeded your current quota"), KindQuota},
+		{"message model", errors.New("The model `gpt-5` does not exist"), KindModelNotFound},
+		{"other", errors.New("something odd"), KindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, 403, StatusCode(fmt.Errorf("wrap: %w", &googleapi.Error{Code: 403})))
+	assert.Equal(t, 0, StatusCode(errors.New("plain")))
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", fmt.Errorf("stream: %w", context.Canceled), false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"http 429", &httpx.StatusError{StatusCode: 429}, true},
+		{"http 503", fmt.Errorf("wrap: %w", &httpx.StatusError{StatusCode: 503}), true},
+		{"http 401", &httpx.StatusError{StatusCode: 401}, false},
+		{"google 500", &googleapi.Error{Code: 500}, true},
+		{"ollama 404", api.StatusError{StatusCode: 404}, false},
+		{"message rate limit", errors.New("Rate limit reached for requests"), true},
+		{"message insufficient quota", errors.New("429 insufficient_quota: check your plan"), false},
+		{"message quota", errors.New("You exceeded your current quota"), false},
+		{"other", errors.New("something odd"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "2")
+	assert.Equal(t, 2*time.Second, RetryAfter(fmt.Errorf("wrap: %w", &httpx.StatusError{StatusCode: 429, Header: header})))
+	assert.Equal(t, 2*time.Second, RetryAfter(&googleapi.Error{Code: 429, Header: header}))
+	assert.Equal(t, time.Duration(0), RetryAfter(errors.New("plain")))
+}
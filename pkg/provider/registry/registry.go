@@ -2,6 +2,8 @@ package registry
 
 import (
     "context"
+    "errors"
+    "fmt"
     "sync"
 
     "github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
@@ -11,11 +13,45 @@ import (
 // Factory constructs an AI client for a provider using the given settings.
 type Factory func(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error)
 
+// Capabilities describes what a provider supports, so callers can pick a strategy
+// (streaming, summarizing large diffs, structured output) without creating a client.
+type Capabilities struct {
+    // Streaming reports whether the client streams deltas (ai.StreamingAIClient).
+    Streaming bool `json:"streaming"`
+    // ContextTokens is the context window of the default model; 0 if unknown.
+    ContextTokens int `json:"contextTokens,omitempty"`
+    // JSONMode reports whether the provider can constrain output to JSON natively
+    // (ai.StructuredOutputClient).
+    JSONMode bool `json:"jsonMode"`
+    // Reasoning reports whether models of the provider can return reasoning output
+    // separately from the answer.
+    Reasoning bool `json:"reasoning"`
+    // Local reports whether the provider runs on the user's machine.
+    Local bool `json:"local"`
+    // Models are well-known models offered when the provider cannot list them.
+    Models []string `json:"models,omitempty"`
+    // Params are the generation parameters the provider accepts.
+    Params ParamLimits `json:"params"`
+}
+
+// ParamLimits describes the generation parameters a provider accepts and their ranges.
+type ParamLimits struct {
+    // MaxTemperature is the highest temperature accepted; 0 if temperature is not supported.
+    MaxTemperature float64 `json:"maxTemperature,omitempty"`
+    // TopP reports whether nucleus sampling can be set.
+    TopP bool `json:"topP"`
+    // MaxOutputTokens is the largest output length that can be requested; 0 if unbounded.
+    MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+    // SystemPrompt reports whether a system prompt can be sent.
+    SystemPrompt bool `json:"systemPrompt"`
+}
+
 var (
-    mu        sync.RWMutex
-    factories = map[string]Factory{}
-    defaults  = map[string]config.ProviderSettings{}
-    required  = map[string]bool{}
+    mu           sync.RWMutex
+    factories    = map[string]Factory{}
+    defaults     = map[string]config.ProviderSettings{}
+    required     = map[string]bool{}
+    capabilities = map[string]Capabilities{}
 )
 
 // Register adds a provider factory under the given name.
@@ -25,6 +61,16 @@ func Register(name string, f Factory) {
     mu.Unlock()
 }
 
+// Unregister removes a provider, its defaults and capabilities.
+func Unregister(name string) {
+    mu.Lock()
+    delete(factories, name)
+    delete(defaults, name)
+    delete(required, name)
+    delete(capabilities, name)
+    mu.Unlock()
+}
+
 // Get returns the factory for name if registered.
 func Get(name string) (Factory, bool) {
     mu.RLock()
@@ -81,3 +127,57 @@ func RequiresAPIKey(name string) bool {
     mu.RUnlock()
     return r
 }
+
+// SetCapabilities sets the capability descriptor of a provider.
+func SetCapabilities(name string, c Capabilities) {
+    mu.Lock()
+    capabilities[name] = c
+    mu.Unlock()
+}
+
+// GetCapabilities returns the capabilities of a provider if they were registered.
+func GetCapabilities(name string) (Capabilities, bool) {
+    mu.RLock()
+    c, ok := capabilities[name]
+    mu.RUnlock()
+    return c, ok
+}
+
+// ValidateParams checks p against the ranges every provider shares and, when name has
+// registered capabilities, against the limits of that provider.
+func ValidateParams(name string, p config.GenerationParams) error {
+    var errs []error
+    if p.Temperature != nil && *p.Temperature < 0 {
+        errs = append(errs, fmt.Errorf("temperature must not be negative, got %g", *p.Temperature))
+    }
+    if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+        errs = append(errs, fmt.Errorf("topP must be in (0, 1], got %g", *p.TopP))
+    }
+    if p.MaxOutputTokens < 0 {
+        errs = append(errs, fmt.Errorf("maxOutputTokens must not be negative, got %d", p.MaxOutputTokens))
+    }
+    if p.Timeout < 0 {
+        errs = append(errs, fmt.Errorf("timeout must not be negative, got %s", p.Timeout))
+    }
+
+    if c, ok := GetCapabilities(name); ok {
+        limits := c.Params
+        switch {
+        case p.Temperature == nil:
+        case limits.MaxTemperature == 0:
+            errs = append(errs, fmt.Errorf("%s does not support temperature", name))
+        case *p.Temperature > limits.MaxTemperature:
+            errs = append(errs, fmt.Errorf("%s accepts temperature up to %g, got %g", name, limits.MaxTemperature, *p.Temperature))
+        }
+        if p.TopP != nil && !limits.TopP {
+            errs = append(errs, fmt.Errorf("%s does not support topP", name))
+        }
+        if limits.MaxOutputTokens > 0 && p.MaxOutputTokens > limits.MaxOutputTokens {
+            errs = append(errs, fmt.Errorf("%s accepts maxOutputTokens up to %d, got %d", name, limits.MaxOutputTokens, p.MaxOutputTokens))
+        }
+        if p.SystemPrompt != "" && !limits.SystemPrompt {
+            errs = append(errs, fmt.Errorf("%s does not support a system prompt", name))
+        }
+    }
+    return errors.Join(errs...)
+}
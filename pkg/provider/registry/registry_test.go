@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+)
+
+func TestValidateParams(t *testing.T) {
+	SetCapabilities("limited", Capabilities{Params: ParamLimits{MaxTemperature: 1, MaxOutputTokens: 4096, SystemPrompt: true}})
+	t.Cleanup(func() { Unregister("limited") })
+
+	f := func(v float64) *float64 { return &v }
+
+	assert.NoError(t, ValidateParams("limited", config.GenerationParams{}))
+	assert.NoError(t, ValidateParams("limited", config.GenerationParams{Temperature: f(0), MaxOutputTokens: 4096, SystemPrompt: "be brief", Timeout: time.Minute}))
+
+	assert.ErrorContains(t, ValidateParams("limited", config.GenerationParams{Temperature: f(1.5)}), "temperature up to 1")
+	assert.ErrorContains(t, ValidateParams("limited", config.GenerationParams{TopP: f(0.5)}), "does not support topP")
+	assert.ErrorContains(t, ValidateParams("limited", config.GenerationParams{MaxOutputTokens: 8192}), "maxOutputTokens up to 4096")
+
+	// Shared ranges apply to providers without registered capabilities too.
+	assert.NoError(t, ValidateParams("unknown", config.GenerationParams{Temperature: f(1.5), TopP: f(1)}))
+	assert.ErrorContains(t, ValidateParams("unknown", config.GenerationParams{Temperature: f(-1)}), "temperature must not be negative")
+	assert.ErrorContains(t, ValidateParams("unknown", config.GenerationParams{TopP: f(0)}), "topP must be in (0, 1]")
+	assert.ErrorContains(t, ValidateParams("unknown", config.GenerationParams{Timeout: -time.Second}), "timeout must not be negative")
+
+	SetCapabilities("no-sampling", Capabilities{})
+	t.Cleanup(func() { Unregister("no-sampling") })
+	assert.ErrorContains(t, ValidateParams("no-sampling", config.GenerationParams{Temperature: f(0.5), SystemPrompt: "x"}), "does not support temperature")
+}
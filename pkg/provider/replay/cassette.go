@@ -0,0 +1,161 @@
+// Package replay provides a provider that records real provider responses to cassette
+// files and replays them offline, so tests can exercise streaming generation end to end
+// without network access.
+//
+// The provider is registered as "replay" and configured like any other provider:
+//
+//	providers:
+//	  replay:
+//	    baseURL: tests/integration/testdata/cassettes/commit.json
+//	    model: openai/gpt-4o-mini   # provider to record, record mode only
+//	    apiKey: sk-...              # its API key, record mode only
+//
+// Requests are answered from the cassette unless AI_COMMIT_HUB_REPLAY_MODE=record, in
+// which case they are sent to the named provider and appended to the cassette.
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+)
+
+// Chunk is one streamed delta and the time elapsed since the previous one. A chunk
+// holds either answer text or reasoning reported by a reasoning model.
+type Chunk struct {
+	Text      string `json:"text,omitempty"`
+	Reasoning string `json:"reasoning,omitempty"`
+	DelayMs   int64  `json:"delayMs,omitempty"`
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	// PromptHash is HashPrompt of the request prompt. An empty hash matches any prompt,
+	// which is convenient for hand-written cassettes.
+	PromptHash string `json:"promptHash,omitempty"`
+	// Chunks are the streamed deltas; empty for non-streaming responses.
+	Chunks   []Chunk   `json:"chunks,omitempty"`
+	Response string    `json:"response"`
+	Usage    *ai.Usage `json:"usage,omitempty"`
+	// Error is the error message of a failed request; StatusCode is set for HTTP errors.
+	Error      string `json:"error,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+}
+
+// Cassette is an ordered list of interactions stored as JSON.
+// It is safe for concurrent use.
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	interactions []Interaction
+	// played counts the interactions already replayed per prompt hash.
+	played map[string]int
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// NewCassette returns an empty cassette that Save writes to path.
+func NewCassette(path string) *Cassette {
+	return &Cassette{path: path, played: make(map[string]int)}
+}
+
+// LoadCassette reads the cassette at path.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	var f cassetteFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	c := NewCassette(path)
+	c.interactions = f.Interactions
+	return c, nil
+}
+
+// OpenCassette loads the cassette at path, or returns an empty one if the file does not exist.
+func OpenCassette(path string) (*Cassette, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return NewCassette(path), nil
+	}
+	return LoadCassette(path)
+}
+
+// HashPrompt returns the key interactions are matched by.
+func HashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Interactions returns a copy of the recorded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Add appends an interaction and writes the cassette to disk.
+func (c *Cassette) Add(i Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, i)
+	return c.save()
+}
+
+// Save writes the cassette to its path.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// next returns the interaction to replay for prompt. Interactions recorded for the same
+// prompt are replayed in order (so a failure followed by a retry replays both), and the
+// last one is repeated once they are used up. Interactions without a hash are used when
+// no recorded interaction matches.
+func (c *Cassette) next(prompt string) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hash := HashPrompt(prompt)
+	for _, key := range []string{hash, ""} {
+		var matches []int
+		for i, in := range c.interactions {
+			if in.PromptHash == key {
+				matches = append(matches, i)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		n := c.played[key]
+		c.played[key] = n + 1
+		if n >= len(matches) {
+			n = len(matches) - 1
+		}
+		return c.interactions[matches[n]], true
+	}
+	return Interaction{}, false
+}
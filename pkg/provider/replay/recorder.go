@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+)
+
+// Recorder forwards requests to a real provider and appends every request/response
+// pair, including failures, to a cassette.
+type Recorder struct {
+	ai.BaseAIClient
+	upstream ai.AIClient
+	cassette *Cassette
+}
+
+// NewRecorder returns a client that records the interactions of upstream into cassette.
+func NewRecorder(provider string, upstream ai.AIClient, cassette *Cassette) *Recorder {
+	return &Recorder{BaseAIClient: ai.BaseAIClient{Provider: provider}, upstream: upstream, cassette: cassette}
+}
+
+var (
+	_ ai.AIClient          = (*Recorder)(nil)
+	_ ai.StreamingAIClient = (*Recorder)(nil)
+)
+
+// GetCommitMessage calls upstream without streaming and records the response.
+func (r *Recorder) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
+	in := Interaction{PromptHash: HashPrompt(prompt)}
+	captureCtx, _ := r.capture(ctx, &in)
+	msg, err := r.upstream.GetCommitMessage(captureCtx, prompt)
+	in.Response = msg
+	return msg, r.record(in, err)
+}
+
+// StreamCommitMessage streams from upstream when it supports streaming and records each
+// delta, and the reasoning reported in between, with the time since the previous one.
+func (r *Recorder) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(delta string)) (string, error) {
+	sc, ok := r.upstream.(ai.StreamingAIClient)
+	if !ok {
+		msg, err := r.GetCommitMessage(ctx, prompt)
+		if err == nil {
+			onDelta(msg)
+		}
+		return msg, err
+	}
+
+	in := Interaction{PromptHash: HashPrompt(prompt)}
+	captureCtx, addText := r.capture(ctx, &in)
+	msg, err := sc.StreamCommitMessage(captureCtx, prompt, func(delta string) {
+		addText(delta)
+		onDelta(delta)
+	})
+	in.Response = msg
+	return msg, r.record(in, err)
+}
+
+// capture returns a context that stores the usage and reasoning reported by upstream in
+// in and still forwards them to ctx, and a function that records an answer delta.
+func (r *Recorder) capture(ctx context.Context, in *Interaction) (context.Context, func(delta string)) {
+	last := time.Now()
+	add := func(c Chunk) {
+		now := time.Now()
+		c.DelayMs = now.Sub(last).Milliseconds()
+		last = now
+		in.Chunks = append(in.Chunks, c)
+	}
+	captureCtx := ai.WithUsageRecorder(ctx, func(u ai.Usage) {
+		in.Usage = &u
+		ai.ReportUsage(ctx, u)
+	})
+	captureCtx = ai.WithReasoningHandler(captureCtx, func(delta string) {
+		add(Chunk{Reasoning: delta})
+		ai.ReportReasoning(ctx, delta)
+	})
+	return captureCtx, func(delta string) { add(Chunk{Text: delta}) }
+}
+
+// record appends in to the cassette and returns err, the upstream error. A cassette
+// that cannot be written is reported as the error of an otherwise successful request.
+func (r *Recorder) record(in Interaction, err error) error {
+	if err != nil {
+		// Cancellation is not a provider response worth replaying.
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		in.Error = err.Error()
+		var se *httpx.StatusError
+		if errors.As(err, &se) {
+			in.StatusCode = se.StatusCode
+			in.Error = se.Message
+		}
+	}
+	if saveErr := r.cassette.Add(in); saveErr != nil && err == nil {
+		return fmt.Errorf("replay: %w", saveErr)
+	}
+	return err
+}
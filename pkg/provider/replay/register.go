@@ -0,0 +1,116 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+const ProviderName = "replay"
+
+const (
+	// ModeEnv selects what the registered provider does: replay (the default) or record.
+	ModeEnv = "AI_COMMIT_HUB_REPLAY_MODE"
+	// ModeRecord forwards requests to the provider named in the model setting and appends
+	// them to the cassette.
+	ModeRecord = "record"
+)
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = map[string]*Cassette{}
+)
+
+// factory creates a replay client for the settings of the provider:
+//
+//	baseURL: path of the cassette file
+//	model:   in record mode, the provider to record as "provider" or "provider/model"
+//	apiKey:  in record mode, the API key of that provider
+//	params:  in record mode, the generation parameters of that provider
+//
+// Cassettes are loaded once per path and shared by all clients, so a retry or a second
+// generation replays the next interaction recorded for the same prompt.
+func factory(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
+	if strings.TrimSpace(ps.BaseURL) == "" {
+		return nil, errors.New("replay: baseURL must be set to the cassette file path")
+	}
+	record := os.Getenv(ModeEnv) == ModeRecord
+
+	cassette, err := cassetteAt(ps.BaseURL, record)
+	if err != nil {
+		return nil, err
+	}
+	if !record {
+		return NewClient(name, cassette), nil
+	}
+
+	upstreamName, model, _ := strings.Cut(ps.Model, "/")
+	if upstreamName == "" || upstreamName == ProviderName {
+		return nil, errors.New("replay: model must name the provider to record, e.g. openai/gpt-4o-mini")
+	}
+	upstreamFactory, ok := registry.Get(upstreamName)
+	if !ok {
+		return nil, fmt.Errorf("replay: unknown provider to record: %s", upstreamName)
+	}
+	if err := registry.ValidateParams(upstreamName, ps.Params); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	settings, _ := registry.GetDefaults(upstreamName)
+	settings.APIKey = ps.APIKey
+	settings.Params = ps.Params
+	if model != "" {
+		settings.Model = model
+	}
+	upstream, err := upstreamFactory(ctx, upstreamName, settings)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(name, upstream, cassette), nil
+}
+
+// cassetteAt returns the shared cassette for path. In record mode a missing file starts
+// an empty cassette.
+func cassetteAt(path string, record bool) (*Cassette, error) {
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	if c, ok := cassettes[path]; ok {
+		return c, nil
+	}
+	open := LoadCassette
+	if record {
+		open = OpenCassette
+	}
+	c, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	cassettes[path] = c
+	return c, nil
+}
+
+// Reset discards the cassettes loaded by the registered provider, so the next request
+// reloads them from disk and replays from the first interaction.
+func Reset() {
+	cassettesMu.Lock()
+	cassettes = map[string]*Cassette{}
+	cassettesMu.Unlock()
+}
+
+func init() {
+	registry.Register(ProviderName, factory)
+	registry.SetRequiresAPIKey(ProviderName, false)
+	// Parameters are only used in record mode, where they are checked against the
+	// recorded provider.
+	registry.SetCapabilities(ProviderName, registry.Capabilities{
+		Streaming: true,
+		Local:     true,
+		Params:    registry.ParamLimits{MaxTemperature: 2, TopP: true, SystemPrompt: true},
+	})
+}
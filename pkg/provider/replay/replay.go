@@ -0,0 +1,92 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+)
+
+// Client replays the interactions of a cassette.
+type Client struct {
+	ai.BaseAIClient
+	cassette *Cassette
+	// NoDelay skips the recorded delays between chunks.
+	NoDelay bool
+}
+
+// NewClient returns a client that answers prompts from cassette.
+func NewClient(provider string, cassette *Cassette) *Client {
+	return &Client{BaseAIClient: ai.BaseAIClient{Provider: provider}, cassette: cassette}
+}
+
+var (
+	_ ai.AIClient          = (*Client)(nil)
+	_ ai.StreamingAIClient = (*Client)(nil)
+)
+
+// GetCommitMessage returns the recorded response for prompt.
+func (c *Client) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
+	return c.StreamCommitMessage(ctx, prompt, func(string) {})
+}
+
+// StreamCommitMessage replays the recorded chunks for prompt with their original timing.
+// Responses recorded without streaming are delivered as a single delta; recorded
+// reasoning is reported through ai.ReportReasoning.
+func (c *Client) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(delta string)) (string, error) {
+	in, ok := c.cassette.next(prompt)
+	if !ok {
+		return "", fmt.Errorf("replay: no recorded interaction for prompt %s", HashPrompt(prompt)[:12])
+	}
+
+	chunks := in.Chunks
+	if !hasText(chunks) && in.Response != "" {
+		chunks = append(chunks, Chunk{Text: in.Response})
+	}
+	var sb strings.Builder
+	for _, chunk := range chunks {
+		if !c.NoDelay {
+			if err := httpx.Sleep(ctx, time.Duration(chunk.DelayMs)*time.Millisecond); err != nil {
+				return sb.String(), err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return sb.String(), err
+		}
+		ai.ReportReasoning(ctx, chunk.Reasoning)
+		if chunk.Text != "" {
+			sb.WriteString(chunk.Text)
+			onDelta(chunk.Text)
+		}
+	}
+
+	if in.Error != "" {
+		return sb.String(), recordedError(in)
+	}
+	if in.Usage != nil {
+		ai.ReportUsage(ctx, *in.Usage)
+	}
+	if in.Response != "" {
+		return in.Response, nil
+	}
+	return sb.String(), nil
+}
+
+func hasText(chunks []Chunk) bool {
+	for _, c := range chunks {
+		if c.Text != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func recordedError(in Interaction) error {
+	if in.StatusCode > 0 {
+		return &httpx.StatusError{StatusCode: in.StatusCode, Message: in.Error}
+	}
+	return errors.New(in.Error)
+}
@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+// scriptedClient streams fixed deltas, failing the first failTimes calls.
+type scriptedClient struct {
+	ai.BaseAIClient
+	deltas    []string
+	failTimes int
+	calls     int
+}
+
+func (s *scriptedClient) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
+	return s.StreamCommitMessage(ctx, prompt, func(string) {})
+}
+
+func (s *scriptedClient) StreamCommitMessage(ctx context.Context, prompt string, onDelta func(string)) (string, error) {
+	s.calls++
+	if s.calls <= s.failTimes {
+		return "", &httpx.StatusError{StatusCode: 429, Message: "rate limited"}
+	}
+	full := ""
+	for _, d := range s.deltas {
+		onDelta(d)
+		full += d
+	}
+	ai.ReportUsage(ctx, ai.Usage{Provider: "scripted", PromptTokens: 10, CompletionTokens: 3})
+	return full, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "commit.json")
+	upstream := &scriptedClient{deltas: []string{"feat: ", "add ", "replay"}, failTimes: 1}
+	recorder := NewRecorder(ProviderName, upstream, NewCassette(path))
+
+	_, err := recorder.StreamCommitMessage(context.Background(), "prompt", func(string) {})
+	require.Error(t, err)
+	var recordedDeltas []string
+	msg, err := recorder.StreamCommitMessage(context.Background(), "prompt", func(d string) { recordedDeltas = append(recordedDeltas, d) })
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add replay", msg)
+	assert.Equal(t, []string{"feat: ", "add ", "replay"}, recordedDeltas)
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions(), 2)
+	assert.Equal(t, 429, cassette.Interactions()[0].StatusCode)
+
+	client := NewClient(ProviderName, cassette)
+	client.NoDelay = true
+
+	// Interactions for the same prompt replay in recorded order, then the last one repeats.
+	_, err = client.StreamCommitMessage(context.Background(), "prompt", func(string) {})
+	var se *httpx.StatusError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, 429, se.StatusCode)
+
+	var usage ai.Usage
+	ctx := ai.WithUsageRecorder(context.Background(), func(u ai.Usage) { usage = u })
+	var deltas []string
+	msg, err = client.StreamCommitMessage(ctx, "prompt", func(d string) { deltas = append(deltas, d) })
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add replay", msg)
+	assert.Equal(t, []string{"feat: ", "add ", "replay"}, deltas)
+	assert.Equal(t, 10, usage.PromptTokens)
+
+	msg, err = client.GetCommitMessage(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add replay", msg)
+
+	_, err = client.GetCommitMessage(context.Background(), "other prompt")
+	assert.ErrorContains(t, err, "no recorded interaction")
+}
+
+func TestReplay_WildcardInteraction(t *testing.T) {
+	cassette := NewCassette(filepath.Join(t.TempDir(), "c.json"))
+	require.NoError(t, cassette.Add(Interaction{Response: "docs: update readme"}))
+
+	var deltas []string
+	msg, err := NewClient(ProviderName, cassette).StreamCommitMessage(context.Background(), "any prompt", func(d string) { deltas = append(deltas, d) })
+	require.NoError(t, err)
+	assert.Equal(t, "docs: update readme", msg)
+	assert.Equal(t, []string{"docs: update readme"}, deltas)
+}
+
+func TestReplay_CancelDuringDelay(t *testing.T) {
+	cassette := NewCassette(filepath.Join(t.TempDir(), "c.json"))
+	require.NoError(t, cassette.Add(Interaction{Chunks: []Chunk{{Text: "feat: "}, {Text: "slow", DelayMs: 60000}}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msg, err := NewClient(ProviderName, cassette).StreamCommitMessage(ctx, "prompt", func(string) { cancel() })
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "feat: ", msg)
+}
+
+func TestFactory_RecordMode(t *testing.T) {
+	t.Cleanup(Reset)
+	upstream := &scriptedClient{deltas: []string{"fix: ", "typo"}}
+	registry.Register("scripted", func(ctx context.Context, name string, ps config.ProviderSettings) (ai.AIClient, error) {
+		assert.Equal(t, "key", ps.APIKey)
+		assert.Equal(t, "model-x", ps.Model)
+		return upstream, nil
+	})
+	t.Cleanup(func() { registry.Unregister("scripted") })
+
+	path := filepath.Join(t.TempDir(), "record.json")
+	t.Setenv(ModeEnv, ModeRecord)
+	client, err := factory(context.Background(), ProviderName, config.ProviderSettings{BaseURL: path, Model: "scripted/model-x", APIKey: "key"})
+	require.NoError(t, err)
+	_, err = client.GetCommitMessage(context.Background(), "prompt")
+	require.NoError(t, err)
+
+	Reset()
+	t.Setenv(ModeEnv, "")
+	client, err = factory(context.Background(), ProviderName, config.ProviderSettings{BaseURL: path})
+	require.NoError(t, err)
+	msg, err := client.GetCommitMessage(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "fix: typo", msg)
+	assert.Equal(t, 1, upstream.calls)
+
+	_, err = factory(context.Background(), ProviderName, config.ProviderSettings{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// APIKeyHealthRepository handles API key health data operations
+type APIKeyHealthRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyHealthRepository creates a new APIKeyHealthRepository
+func NewAPIKeyHealthRepository() *APIKeyHealthRepository {
+	return &APIKeyHealthRepository{
+		db: GetDB(),
+	}
+}
+
+// Get returns the health of the key keyID of provider, or nil if it has not been used yet
+func (r *APIKeyHealthRepository) Get(provider, keyID string) (*models.APIKeyHealth, error) {
+	var health models.APIKeyHealth
+	err := r.db.Where("provider = ? AND key_id = ?", provider, keyID).First(&health).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key health: %w", err)
+	}
+	return &health, nil
+}
+
+// Save creates or replaces the health of the key with the same provider and key ID
+func (r *APIKeyHealthRepository) Save(health *models.APIKeyHealth) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "provider"}, {Name: "key_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"label", "successes", "failures", "consecutive_failures", "last_status", "last_error",
+			"last_used_at", "last_failure_at", "cooldown_until", "day", "day_requests", "updated_at",
+		}),
+	}).Create(health).Error
+	if err != nil {
+		return fmt.Errorf("failed to save api key health: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter selects audit log entries. Empty fields match everything.
+type AuditLogFilter struct {
+	ProjectPath string `json:"projectPath"`
+	Provider    string `json:"provider"`
+	SessionID   string `json:"sessionId"`
+	Limit       int    `json:"limit"`
+	Offset      int    `json:"offset"`
+}
+
+// auditLogSummaryColumns are the columns returned by List; the prompt and response
+// can be large and are only loaded by Get.
+var auditLogSummaryColumns = []string{
+	"id", "session_id", "project_path", "candidate_index", "provider", "model",
+	"result", "error", "duration_ms", "prompt_tokens", "completion_tokens", "created_at",
+}
+
+// AuditLogRepository handles audit log data operations
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{
+		db: GetDB(),
+	}
+}
+
+// Create creates a new audit log entry
+func (r *AuditLogRepository) Create(entry *models.AuditLog) error {
+	if err := r.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries matching filter, most recent first, without their prompt
+// and raw response. It also returns the total number of matching entries.
+func (r *AuditLogRepository) List(filter AuditLogFilter) ([]models.AuditLog, int64, error) {
+	query := r.db.Model(&models.AuditLog{})
+	if filter.ProjectPath != "" {
+		query = query.Where("project_path = ?", filter.ProjectPath)
+	}
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	var entries []models.AuditLog
+	err := query.Select(auditLogSummaryColumns).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(filter.Offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	return entries, total, nil
+}
+
+// Get returns the entry id with its prompt and raw response, or nil if it does not exist
+func (r *AuditLogRepository) Get(id uint) (*models.AuditLog, error) {
+	var entry models.AuditLog
+	err := r.db.First(&entry, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	return &entry, nil
+}
+
+// Purge deletes the entries created before before, or all entries if before is zero.
+// It returns the number of deleted entries.
+func (r *AuditLogRepository) Purge(before time.Time) (int64, error) {
+	query := r.db.Where("1 = 1")
+	if !before.IsZero() {
+		query = r.db.Where("created_at < ?", before)
+	}
+	result := query.Delete(&models.AuditLog{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge audit logs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Evict keeps at most maxEntries most recent entries. maxEntries <= 0 disables the limit.
+func (r *AuditLogRepository) Evict(maxEntries int) error {
+	if maxEntries <= 0 {
+		return nil
+	}
+	keep := r.db.Model(&models.AuditLog{}).Select("id").Order("created_at DESC, id DESC").Limit(maxEntries)
+	if err := r.db.Where("id NOT IN (?)", keep).Delete(&models.AuditLog{}).Error; err != nil {
+		return fmt.Errorf("failed to evict audit logs: %w", err)
+	}
+	return nil
+}
@@ -46,3 +46,13 @@ func (r *CommitHistoryRepository) GetRecent(limit int) ([]models.CommitHistory,
 		Find(&histories).Error
 	return histories, err
 }
+
+// GetWithUsage retrieves all commit histories that recorded token usage, with their projects
+func (r *CommitHistoryRepository) GetWithUsage() ([]models.CommitHistory, error) {
+	var histories []models.CommitHistory
+	err := r.db.Preload("Project").
+		Where("prompt_tokens > 0 OR completion_tokens > 0").
+		Order("created_at DESC").
+		Find(&histories).Error
+	return histories, err
+}
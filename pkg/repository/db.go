@@ -55,7 +55,7 @@ func InitializeDatabase(config *DatabaseConfig) error {
 		}
 
 		// Auto migrate schemas
-		if err := db.AutoMigrate(&models.GitProject{}, &models.CommitHistory{}, &models.UpdatePreferences{}, &models.WindowState{}); err != nil {
+		if err := db.AutoMigrate(&models.GitProject{}, &models.CommitHistory{}, &models.GenerationCache{}, &models.UpdatePreferences{}, &models.WindowState{}, &models.APIKeyHealth{}, &models.AuditLog{}); err != nil {
 			initErr = fmt.Errorf("failed to migrate database: %w", err)
 			return
 		}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GenerationCacheRepository handles generation cache data operations
+type GenerationCacheRepository struct {
+	db *gorm.DB
+}
+
+// NewGenerationCacheRepository creates a new GenerationCacheRepository
+func NewGenerationCacheRepository() *GenerationCacheRepository {
+	return &GenerationCacheRepository{
+		db: GetDB(),
+	}
+}
+
+// Get returns the cache entry for key created after notBefore, or nil if there is none.
+// A hit refreshes the entry's LastUsedAt.
+func (r *GenerationCacheRepository) Get(key string, notBefore time.Time) (*models.GenerationCache, error) {
+	var entry models.GenerationCache
+	err := r.db.Where("key = ? AND created_at > ?", key, notBefore).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation cache: %w", err)
+	}
+
+	entry.LastUsedAt = time.Now()
+	if err := r.db.Model(&entry).Update("last_used_at", entry.LastUsedAt).Error; err != nil {
+		return nil, fmt.Errorf("failed to touch generation cache: %w", err)
+	}
+	return &entry, nil
+}
+
+// Put creates or replaces the cache entry with the same key
+func (r *GenerationCacheRepository) Put(entry *models.GenerationCache) error {
+	now := time.Now()
+	entry.CreatedAt = now
+	entry.LastUsedAt = now
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"provider", "model", "language", "messages", "created_at", "last_used_at"}),
+	}).Create(entry).Error
+	if err != nil {
+		return fmt.Errorf("failed to save generation cache: %w", err)
+	}
+	return nil
+}
+
+// Evict deletes entries created before notBefore, then keeps at most maxEntries
+// most recently used entries. maxEntries <= 0 disables the size limit.
+func (r *GenerationCacheRepository) Evict(notBefore time.Time, maxEntries int) error {
+	if err := r.db.Where("created_at <= ?", notBefore).Delete(&models.GenerationCache{}).Error; err != nil {
+		return fmt.Errorf("failed to evict expired generation cache: %w", err)
+	}
+	if maxEntries <= 0 {
+		return nil
+	}
+
+	keep := r.db.Model(&models.GenerationCache{}).Select("id").Order("last_used_at DESC").Limit(maxEntries)
+	if err := r.db.Where("id NOT IN (?)", keep).Delete(&models.GenerationCache{}).Error; err != nil {
+		return fmt.Errorf("failed to evict generation cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build !windows
+
+package secret
+
+import (
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"os/user"
+	"strings"
+)
+
+// machineIDFiles are read in order for a stable per-installation identifier.
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+func (machineProtector) Seal(key []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	kek, err := machineKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := encrypt(kek, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, sealed...), nil
+}
+
+func (machineProtector) Unseal(sealed []byte) ([]byte, error) {
+	if len(sealed) < saltSize {
+		return nil, errors.New("sealed key too short")
+	}
+	kek, err := machineKey(sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	key, err := decrypt(kek, sealed[saltSize:])
+	if err != nil {
+		return nil, errors.New("secret store was created on another machine or by another user")
+	}
+	return key, nil
+}
+
+// machineKey derives a key from the machine ID, host name and user. It keeps the keys
+// out of plain text but, unlike DPAPI, does not protect them from other programs of the
+// same user; set PassphraseEnv for that.
+func machineKey(salt []byte) ([]byte, error) {
+	var id strings.Builder
+	for _, path := range machineIDFiles {
+		if data, err := os.ReadFile(path); err == nil {
+			id.WriteString(strings.TrimSpace(string(data)))
+			break
+		}
+	}
+	host, _ := os.Hostname()
+	id.WriteString("\x00" + host)
+	if u, err := user.Current(); err == nil {
+		id.WriteString("\x00" + u.Uid + "\x00" + u.Username)
+	}
+	return hkdf.Key(sha256.New, []byte(id.String()), salt, "ai-commit-hub secret store", 32)
+}
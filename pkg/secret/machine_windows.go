@@ -0,0 +1,43 @@
+package secret
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// dpapiEntropy ties the protected data to this application, so other programs running
+// as the same user cannot unprotect it with a plain CryptUnprotectData call.
+var dpapiEntropy = []byte("ai-commit-hub secret store")
+
+func (machineProtector) Seal(key []byte) ([]byte, error) {
+	var out windows.DataBlob
+	err := windows.CryptProtectData(newBlob(key), nil, newBlob(dpapiEntropy), 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	if err != nil {
+		return nil, fmt.Errorf("DPAPI protect: %w", err)
+	}
+	return takeBlob(&out), nil
+}
+
+func (machineProtector) Unseal(sealed []byte) ([]byte, error) {
+	var out windows.DataBlob
+	err := windows.CryptUnprotectData(newBlob(sealed), nil, newBlob(dpapiEntropy), 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	if err != nil {
+		return nil, fmt.Errorf("DPAPI unprotect: %w", err)
+	}
+	return takeBlob(&out), nil
+}
+
+func newBlob(data []byte) *windows.DataBlob {
+	if len(data) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
+
+// takeBlob copies the output of a DPAPI call and frees the buffer Windows allocated for it.
+func takeBlob(b *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(b.Data)))
+	return append([]byte(nil), unsafe.Slice(b.Data, b.Size)...)
+}
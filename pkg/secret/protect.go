@@ -0,0 +1,94 @@
+package secret
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"os"
+)
+
+// PassphraseEnv names the environment variable that, when set, makes DefaultProtector
+// protect the store with its value as passphrase instead of the machine key.
+const PassphraseEnv = "AI_COMMIT_HUB_SECRET_PASSPHRASE"
+
+// passphraseIterations is the PBKDF2-SHA256 work factor recommended by OWASP.
+const passphraseIterations = 600_000
+
+const saltSize = 16
+
+// Protector seals the data key of a store.
+type Protector interface {
+	// Name identifies the kind of protection and is recorded in the store file.
+	Name() string
+	Seal(key []byte) ([]byte, error)
+	Unseal(sealed []byte) ([]byte, error)
+}
+
+// DefaultProtector returns the passphrase protector if PassphraseEnv is set and the
+// machine protector otherwise.
+func DefaultProtector() Protector {
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		return Passphrase(pass)
+	}
+	return Machine()
+}
+
+// Passphrase returns a protector that derives the key-encryption key from pass with
+// PBKDF2-SHA256. A store sealed with it can be opened on any machine with the passphrase.
+func Passphrase(pass string) Protector {
+	return passphraseProtector(pass)
+}
+
+type passphraseProtector string
+
+func (p passphraseProtector) Name() string { return "passphrase" }
+
+func (p passphraseProtector) Seal(key []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	kek, err := p.derive(salt)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := encrypt(kek, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, sealed...), nil
+}
+
+func (p passphraseProtector) Unseal(sealed []byte) ([]byte, error) {
+	if len(sealed) < saltSize {
+		return nil, errors.New("sealed key too short")
+	}
+	kek, err := p.derive(sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	key, err := decrypt(kek, sealed[saltSize:])
+	if err != nil {
+		return nil, errors.New("wrong passphrase")
+	}
+	return key, nil
+}
+
+func (p passphraseProtector) derive(salt []byte) ([]byte, error) {
+	if p == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	return pbkdf2.Key(sha256.New, string(p), salt, passphraseIterations, 32)
+}
+
+// Machine returns a protector bound to the current user on the current machine: DPAPI
+// on Windows, and elsewhere a key derived from the machine ID and user. A store sealed
+// with it cannot be opened after copying it to another machine or user account.
+func Machine() Protector {
+	return machineProtector{}
+}
+
+type machineProtector struct{}
+
+func (machineProtector) Name() string { return "machine" }
@@ -0,0 +1,254 @@
+// Package secret stores provider API keys in an encrypted file, so config.yaml only
+// holds references such as "secret:openai" instead of the keys themselves.
+//
+// Secrets are encrypted with AES-256-GCM under a random data key. The data key is in
+// turn protected by a Protector: the machine (DPAPI on Windows, a key derived from the
+// machine and user identity elsewhere) or a user passphrase.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefPrefix marks a config value that refers to a stored secret by ID.
+const RefPrefix = "secret:"
+
+// FileName is the name of the store file in the config directory.
+const FileName = "secrets.json"
+
+const fileVersion = 1
+
+var (
+	// ErrNotFound is returned for a secret ID that is not in the store.
+	ErrNotFound = errors.New("secret not found")
+	// ErrProtectionMismatch is returned by Open for a store written with another kind
+	// of protector.
+	ErrProtectionMismatch = errors.New("secret store protection mismatch")
+)
+
+// Ref returns the config value that refers to the secret id.
+func Ref(id string) string {
+	return RefPrefix + id
+}
+
+// ParseRef returns the secret ID of a reference created by Ref.
+func ParseRef(value string) (id string, ok bool) {
+	id, ok = strings.CutPrefix(strings.TrimSpace(value), RefPrefix)
+	return id, ok && id != ""
+}
+
+// Entry is a stored secret.
+type Entry struct {
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// storeFile is the on-disk format. Only Protection and the sealed key are readable
+// without the protector; the secrets and their IDs are in Data.
+type storeFile struct {
+	Version    int    `json:"version"`
+	Protection string `json:"protection"`
+	Key        []byte `json:"key"`
+	Data       []byte `json:"data"`
+}
+
+// Store is an encrypted set of secrets backed by a file. It is safe for concurrent use.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	protector Protector
+	dataKey   []byte
+	entries   map[string]Entry
+}
+
+// Open loads the store at path, or returns an empty one if the file does not exist.
+// The file must have been written with the same kind of protector.
+func Open(path string, p Protector) (*Store, error) {
+	s := &Store{path: path, protector: p, entries: make(map[string]Entry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read secret store: %w", err)
+	}
+
+	var f storeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse secret store %s: %w", path, err)
+	}
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported secret store version %d", f.Version)
+	}
+	if f.Protection != p.Name() {
+		return nil, fmt.Errorf("%w: protected by %s, not %s", ErrProtectionMismatch, f.Protection, p.Name())
+	}
+	if s.dataKey, err = p.Unseal(f.Key); err != nil {
+		return nil, fmt.Errorf("unlock secret store: %w", err)
+	}
+	plain, err := decrypt(s.dataKey, f.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt secret store: %w", err)
+	}
+	if err := json.Unmarshal(plain, &s.entries); err != nil {
+		return nil, fmt.Errorf("parse secret store entries: %w", err)
+	}
+	return s, nil
+}
+
+// Path returns the file the store is saved to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get returns the value of the secret id.
+func (s *Store) Get(id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return e.Value, nil
+}
+
+// Entry returns the secret id with its metadata.
+func (s *Store) Entry(id string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	return e, ok
+}
+
+// IDs returns the IDs of the stored secrets in sorted order.
+func (s *Store) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Set stores value under id, replacing any previous value, and saves the store.
+func (s *Store) Set(id, value string) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("secret id must not be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id] = Entry{Value: value, UpdatedAt: time.Now()}
+	return s.save()
+}
+
+// Delete removes the secret id and saves the store. Deleting a missing ID is not an error.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[id]; !ok {
+		return nil
+	}
+	delete(s.entries, id)
+	return s.save()
+}
+
+// Rekey re-encrypts the store under a new data key protected by p, which may be a
+// different kind of protector (for example to switch from machine to passphrase
+// protection).
+func (s *Store) Rekey(p Protector) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protector = p
+	s.dataKey = nil
+	return s.save()
+}
+
+func (s *Store) save() error {
+	if s.dataKey == nil {
+		s.dataKey = make([]byte, 32)
+		if _, err := rand.Read(s.dataKey); err != nil {
+			return err
+		}
+	}
+	sealedKey, err := s.protector.Seal(s.dataKey)
+	if err != nil {
+		return fmt.Errorf("protect secret store key: %w", err)
+	}
+	plain, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := encrypt(s.dataKey, plain)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(storeFile{
+		Version:    fileVersion,
+		Protection: s.protector.Name(),
+		Key:        sealedKey,
+		Data:       ciphertext,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("create secret store directory: %w", err)
+	}
+	// Write to a temporary file first so a failed write does not lose the existing secrets.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	return nil
+}
+
+// encrypt seals plain with AES-256-GCM, prefixing the random nonce.
+func encrypt(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func decrypt(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,67 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", FileName)
+	store, err := Open(path, Machine())
+	require.NoError(t, err)
+	require.NoError(t, store.Set("openai", "sk-plaintext-key"))
+	require.NoError(t, store.Set("deepseek", "sk-other"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-plaintext-key")
+	assert.NotContains(t, string(data), "openai", "secret IDs are encrypted too")
+
+	reopened, err := Open(path, Machine())
+	require.NoError(t, err)
+	value, err := reopened.Get("openai")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-plaintext-key", value)
+	assert.Equal(t, []string{"deepseek", "openai"}, reopened.IDs())
+
+	require.NoError(t, reopened.Delete("deepseek"))
+	require.NoError(t, reopened.Delete("missing"))
+	_, err = reopened.Get("deepseek")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_Passphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store, err := Open(path, Machine())
+	require.NoError(t, err)
+	require.NoError(t, store.Set("anthropic", "sk-ant"))
+
+	// Switching protection re-encrypts the existing secrets.
+	require.NoError(t, store.Rekey(Passphrase("correct horse")))
+
+	_, err = Open(path, Machine())
+	assert.ErrorIs(t, err, ErrProtectionMismatch)
+	_, err = Open(path, Passphrase("wrong"))
+	assert.ErrorContains(t, err, "wrong passphrase")
+
+	reopened, err := Open(path, Passphrase("correct horse"))
+	require.NoError(t, err)
+	value, err := reopened.Get("anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-ant", value)
+}
+
+func TestParseRef(t *testing.T) {
+	id, ok := ParseRef(Ref("openai"))
+	assert.True(t, ok)
+	assert.Equal(t, "openai", id)
+
+	_, ok = ParseRef("sk-plain")
+	assert.False(t, ok)
+	_, ok = ParseRef("secret:")
+	assert.False(t, ok)
+}
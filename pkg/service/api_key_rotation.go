@@ -0,0 +1,273 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/providererr"
+)
+
+const (
+	// authCooldown 是 key 被拒绝（401/403）后暂停使用的时间
+	authCooldown = time.Hour
+	// rateLimitCooldown 是 key 被限流（429）且服务端未返回 Retry-After 时暂停使用的时间
+	rateLimitCooldown = time.Minute
+)
+
+// KeyHealthStore 保存每个 API Key 的调用结果，用于多 key 轮换时跳过失效、限流或超出配额的 key
+type KeyHealthStore interface {
+	// Get 返回 key 的状态，尚未使用过时返回 nil
+	Get(provider, keyID string) (*models.APIKeyHealth, error)
+	Save(health *models.APIKeyHealth) error
+}
+
+// APIKeyStatus 是设置界面中显示的单个 API Key 的健康状态
+type APIKeyStatus struct {
+	KeyID               string     `json:"keyId"`
+	Label               string     `json:"label"`
+	Quota               int        `json:"quota"`
+	DayRequests         int        `json:"dayRequests"`
+	Successes           int        `json:"successes"`
+	Failures            int        `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastStatus          int        `json:"lastStatus"`
+	LastError           string     `json:"lastError"`
+	LastUsedAt          *time.Time `json:"lastUsedAt"`
+	CooldownUntil       *time.Time `json:"cooldownUntil"`
+	// Available 表示该 key 当前未在冷却中且未超出当日配额
+	Available bool `json:"available"`
+}
+
+// apiKeyID 返回 key 的指纹，用于记录状态而不保存 key 本身
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// apiKeyLabel 返回 key 的显示名称，未设置 label 时使用第 index 个（从 1 开始）
+func apiKeyLabel(entry config.APIKeyEntry, index int) string {
+	if entry.Label != "" {
+		return entry.Label
+	}
+	return fmt.Sprintf("key %d", index+1)
+}
+
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+// keyAvailable 判断 key 当前是否可用：不在冷却中，且未用完当日配额
+func keyAvailable(entry config.APIKeyEntry, health *models.APIKeyHealth, now time.Time) bool {
+	if health == nil {
+		return true
+	}
+	if health.CooldownUntil != nil && now.Before(*health.CooldownUntil) {
+		return false
+	}
+	return entry.Quota <= 0 || health.Day != now.Format(time.DateOnly) || health.DayRequests < entry.Quota
+}
+
+// keyCooldown 返回失败的 key 需要暂停使用的时间，不是鉴权或限流错误时返回 0（不轮换）
+func keyCooldown(err error) time.Duration {
+	switch providererr.StatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return authCooldown
+	case http.StatusTooManyRequests:
+		if d := providererr.RetryAfter(err); d > 0 {
+			return d
+		}
+		return rateLimitCooldown
+	}
+	return 0
+}
+
+// memoryKeyHealthStore 是进程内的 KeyHealthStore，未设置持久化存储时使用
+type memoryKeyHealthStore struct {
+	mu      sync.Mutex
+	entries map[string]models.APIKeyHealth
+}
+
+func newMemoryKeyHealthStore() *memoryKeyHealthStore {
+	return &memoryKeyHealthStore{entries: make(map[string]models.APIKeyHealth)}
+}
+
+func (m *memoryKeyHealthStore) Get(provider, keyID string) (*models.APIKeyHealth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health, ok := m.entries[provider+"/"+keyID]
+	if !ok {
+		return nil, nil
+	}
+	return &health, nil
+}
+
+func (m *memoryKeyHealthStore) Save(health *models.APIKeyHealth) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[health.Provider+"/"+health.KeyID] = *health
+	return nil
+}
+
+// keyHealthMu 保证同一 key 的状态读取和更新不会交错（例如多个项目同时生成）
+var keyHealthMu sync.Mutex
+
+// SetKeyHealthStore 设置 API Key 状态的存储，nil 时使用进程内存储
+func (s *CommitService) SetKeyHealthStore(store KeyHealthStore) {
+	if store == nil {
+		store = newMemoryKeyHealthStore()
+	}
+	s.keyHealth = store
+}
+
+// loadKeyHealth 返回 key 的状态，读取失败时视为没有记录
+func (s *CommitService) loadKeyHealth(provider, keyID string) *models.APIKeyHealth {
+	health, err := s.keyHealth.Get(provider, keyID)
+	if err != nil {
+		logger.Warnf("读取 API Key 状态失败: %v", err)
+		return nil
+	}
+	return health
+}
+
+// orderKeys 返回本次尝试的 key：可用的 key 按配置顺序排列；
+// 都不可用时只返回冷却最先结束的一个，让请求仍然可以发出
+func (s *CommitService) orderKeys(provider string, keys []config.APIKeyEntry) []int {
+	now := time.Now()
+	var usable []int
+	fallback, fallbackUntil := -1, time.Time{}
+	for i, entry := range keys {
+		health := s.loadKeyHealth(provider, apiKeyID(entry.Key))
+		if keyAvailable(entry, health, now) {
+			usable = append(usable, i)
+			continue
+		}
+		// 当日配额用完的 key 次日零点恢复
+		until := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		if health.CooldownUntil != nil && now.Before(*health.CooldownUntil) {
+			until = *health.CooldownUntil
+		}
+		if fallback < 0 || until.Before(fallbackUntil) {
+			fallback, fallbackUntil = i, until
+		}
+	}
+	if len(usable) == 0 && fallback >= 0 {
+		return []int{fallback}
+	}
+	return usable
+}
+
+// recordKeyResult 更新 key 的状态；err 为 nil 表示成功，cooldown 为失败后暂停使用的时间
+func (s *CommitService) recordKeyResult(provider string, entry config.APIKeyEntry, index int, err error, cooldown time.Duration) {
+	keyHealthMu.Lock()
+	defer keyHealthMu.Unlock()
+
+	keyID := apiKeyID(entry.Key)
+	health := s.loadKeyHealth(provider, keyID)
+	if health == nil {
+		health = &models.APIKeyHealth{Provider: provider, KeyID: keyID}
+	}
+	now := time.Now()
+	health.Label = apiKeyLabel(entry, index)
+	health.LastUsedAt = &now
+	if day := today(); health.Day != day {
+		health.Day, health.DayRequests = day, 0
+	}
+	health.DayRequests++
+	if err == nil {
+		health.Successes++
+		health.ConsecutiveFailures = 0
+		health.LastStatus = http.StatusOK
+		health.LastError = ""
+		health.CooldownUntil = nil
+	} else {
+		health.Failures++
+		health.ConsecutiveFailures++
+		health.LastStatus = providererr.StatusCode(err)
+		health.LastError = err.Error()
+		health.LastFailureAt = &now
+		if cooldown > 0 {
+			until := now.Add(cooldown)
+			health.CooldownUntil = &until
+		}
+	}
+	if err := s.keyHealth.Save(health); err != nil {
+		logger.Warnf("保存 API Key 状态失败: %v", err)
+	}
+}
+
+// generateWithKeys 使用 provider 配置的多个 API Key 生成消息。key 被拒绝（401/403）或限流（429）
+// 且尚未向前端输出内容时，记录失败并换用下一个可用的 key。只配置了一个 key 时直接调用 generateWithProvider。
+// provider 客户端在创建时绑定一个 key，且不在内部重试鉴权和限流错误，因此每次调用 generateWithProvider
+// 只对应一个 HTTP 请求，在这里换 key 即按 HTTP 请求轮换。
+func (s *CommitService) generateWithKeys(session *generationSession, cfg *config.Config, name, promptText string, index int, mode outputMode) (msg string, usage *ai.Usage, streamed bool, err error) {
+	keys := cfg.Providers[name].Keys()
+	if len(keys) <= 1 {
+		return s.generateWithProvider(session, cfg, name, promptText, index, mode)
+	}
+
+	order := s.orderKeys(name, keys)
+	for n, i := range order {
+		entry := keys[i]
+		// 复制配置，不修改会话共享的 cfg（竞速时多个 provider 并发使用）
+		keyCfg := *cfg
+		keyCfg.Providers = maps.Clone(cfg.Providers)
+		ps := keyCfg.Providers[name]
+		ps.APIKey = entry.Key
+		keyCfg.Providers[name] = ps
+
+		msg, usage, streamed, err = s.generateWithProvider(session, &keyCfg, name, promptText, index, mode)
+		if session.ctx.Err() != nil {
+			return msg, usage, streamed, err
+		}
+		cooldown := time.Duration(0)
+		if err != nil {
+			cooldown = keyCooldown(err)
+		}
+		s.recordKeyResult(name, entry, i, err, cooldown)
+		if err == nil || cooldown == 0 || streamed || n == len(order)-1 {
+			return msg, usage, streamed, err
+		}
+		logger.Warnf("Provider '%s' 的 API Key %s 不可用（%v），换用下一个 key", name, apiKeyLabel(entry, i), err)
+	}
+	return msg, usage, streamed, err
+}
+
+// KeyHealth 返回 provider 配置的各个 API Key 的状态，按配置顺序排列
+func (s *CommitService) KeyHealth(provider string) ([]APIKeyStatus, error) {
+	cfg, err := s.configService.LoadConfig(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := cfg.Providers[provider].Keys()
+	now := time.Now()
+	statuses := make([]APIKeyStatus, 0, len(keys))
+	for i, entry := range keys {
+		keyID := apiKeyID(entry.Key)
+		status := APIKeyStatus{KeyID: keyID, Label: apiKeyLabel(entry, i), Quota: entry.Quota}
+		health := s.loadKeyHealth(provider, keyID)
+		if health != nil {
+			status.Successes = health.Successes
+			status.Failures = health.Failures
+			status.ConsecutiveFailures = health.ConsecutiveFailures
+			status.LastStatus = health.LastStatus
+			status.LastError = health.LastError
+			status.LastUsedAt = health.LastUsedAt
+			status.CooldownUntil = health.CooldownUntil
+			if health.Day == today() {
+				status.DayRequests = health.DayRequests
+			}
+		}
+		status.Available = keyAvailable(entry, health, now)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	aicommitai "github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+// registerKeyedMockProvider 注册按 API Key 返回不同 client 的 mock provider
+func registerKeyedMockProvider(name string, clients map[string]*MockAIClient) {
+	registry.Register(name, func(ctx context.Context, n string, ps aicommitconfig.ProviderSettings) (aicommitai.AIClient, error) {
+		client := clients[ps.APIKey]
+		client.Provider = n
+		return client, nil
+	})
+}
+
+func TestCommitService_GenerateWithKeys_Rotates(t *testing.T) {
+	limited := NewMockAIClientWithError(&httpx.StatusError{StatusCode: 429, Message: "rate limited"})
+	revoked := NewMockAIClientWithError(&httpx.StatusError{StatusCode: 401, Message: "invalid key"})
+	team := &MockAIClient{Deltas: []string{"fix: ", "rotate"}}
+	registerKeyedMockProvider("mock-keys", map[string]*MockAIClient{"k-free": limited, "k-old": revoked, "k-team": team})
+
+	svc, recorder := newRecordingCommitService()
+	cfg := &config.Config{
+		Retry: config.RetrySettings{MaxAttempts: 1},
+		Providers: map[string]config.ProviderSettings{
+			"mock-keys": {
+				APIKey: "k-free",
+				APIKeys: []config.APIKeyEntry{
+					{Key: "k-old", Label: "old"},
+					{Key: "k-team", Label: "team", Quota: 2},
+				},
+			},
+		},
+	}
+
+	session := newTestSession(svc, "/test/project")
+	svc.runGeneration(session, cfg, []string{"mock-keys"}, "prompt", 1)
+
+	// 换用 key 不回退到其它 provider，也不发送重试事件
+	assert.Equal(t, []string{"commit-delta", "commit-delta", "commit-complete"}, recorder.Names())
+	events := recorder.Events()
+	assert.Equal(t, "fix: rotate", events[len(events)-1].Data.(CommitResult).Message)
+	assert.Equal(t, 1, limited.Calls())
+	assert.Equal(t, 1, revoked.Calls())
+
+	free := svc.loadKeyHealth("mock-keys", apiKeyID("k-free"))
+	require.NotNil(t, free)
+	assert.Equal(t, "key 1", free.Label)
+	assert.Equal(t, 429, free.LastStatus)
+	require.NotNil(t, free.CooldownUntil)
+	assert.WithinDuration(t, time.Now().Add(rateLimitCooldown), *free.CooldownUntil, 5*time.Second)
+
+	old := svc.loadKeyHealth("mock-keys", apiKeyID("k-old"))
+	require.NotNil(t, old)
+	assert.WithinDuration(t, time.Now().Add(authCooldown), *old.CooldownUntil, 5*time.Second)
+
+	// 冷却中的 key 不再尝试
+	session = newTestSession(svc, "/test/project")
+	svc.runGeneration(session, cfg, []string{"mock-keys"}, "prompt", 1)
+	assert.Equal(t, 1, limited.Calls())
+	assert.Equal(t, 1, revoked.Calls())
+	assert.Equal(t, 2, team.Calls())
+
+	teamHealth := svc.loadKeyHealth("mock-keys", apiKeyID("k-team"))
+	require.NotNil(t, teamHealth)
+	assert.Equal(t, 2, teamHealth.Successes)
+	assert.Equal(t, 2, teamHealth.DayRequests)
+}
+
+func TestCommitService_OrderKeys(t *testing.T) {
+	svc := NewCommitService(context.Background())
+	keys := []config.APIKeyEntry{{Key: "a"}, {Key: "b", Quota: 1}, {Key: "c"}}
+	now := time.Now()
+	soon, later := now.Add(time.Minute), now.Add(time.Hour)
+	require.NoError(t, svc.keyHealth.Save(&models.APIKeyHealth{Provider: "p", KeyID: apiKeyID("a"), CooldownUntil: &later}))
+	require.NoError(t, svc.keyHealth.Save(&models.APIKeyHealth{Provider: "p", KeyID: apiKeyID("b"), Day: today(), DayRequests: 1}))
+
+	assert.Equal(t, []int{2}, svc.orderKeys("p", keys), "冷却中和超出当日配额的 key 被跳过")
+
+	// 所有 key 都不可用时使用冷却最先结束的 key
+	require.NoError(t, svc.keyHealth.Save(&models.APIKeyHealth{Provider: "p", KeyID: apiKeyID("c"), CooldownUntil: &soon}))
+	assert.Equal(t, []int{2}, svc.orderKeys("p", keys))
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+)
+
+// redactedMask 替换审计日志中被屏蔽的内容
+const redactedMask = "[REDACTED]"
+
+// AuditStore 保存审计日志，config.yaml 中启用 audit.enabled 时每次 provider 请求写入一条
+type AuditStore interface {
+	Create(entry *models.AuditLog) error
+	// Evict 只保留最近的 maxEntries 条
+	Evict(maxEntries int) error
+}
+
+// SetAuditStore 设置审计日志的存储，nil 表示不记录
+func (s *CommitService) SetAuditStore(store AuditStore) {
+	s.audit = store
+}
+
+// redactor 屏蔽写入审计日志的文本中的 API Key 和 audit.redact 中配置的模式
+type redactor struct {
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// newRedactor 收集配置中所有 provider 的 API Key，并编译 audit.redact 中的正则表达式。
+// 无效的表达式记录警告后忽略。
+func newRedactor(cfg *config.Config) *redactor {
+	r := &redactor{}
+	for _, ps := range cfg.Providers {
+		for _, k := range ps.Keys() {
+			r.secrets = append(r.secrets, k.Key)
+		}
+	}
+	for _, cp := range cfg.CustomProviders {
+		if cp.APIKey != "" {
+			r.secrets = append(r.secrets, cp.APIKey)
+		}
+	}
+	for _, pattern := range cfg.Audit.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Warnf("审计日志的屏蔽规则无效，已忽略: %s: %v", pattern, err)
+			continue
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r
+}
+
+// redact 返回屏蔽了敏感内容的 text
+func (r *redactor) redact(text string) string {
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, redactedMask)
+	}
+	for _, re := range r.patterns {
+		text = re.ReplaceAllString(text, redactedMask)
+	}
+	return text
+}
+
+// recordAudit 在启用审计日志时记录一次 provider 请求。已取消的请求不记录。
+// raw 是 provider 返回的原始文本，result 是最终使用的消息。写入失败只记录警告，不影响生成。
+func (s *CommitService) recordAudit(session *generationSession, cfg *config.Config, name, promptText string, index int, raw, result string, usage *ai.Usage, genErr error, elapsed time.Duration) {
+	if s.audit == nil || !cfg.Audit.Enabled || session.ctx.Err() != nil {
+		return
+	}
+
+	r := newRedactor(cfg)
+	entry := &models.AuditLog{
+		SessionID:      session.SessionID,
+		ProjectPath:    session.ProjectPath,
+		CandidateIndex: index,
+		Provider:       name,
+		Model:          cfg.Providers[name].Model,
+		Prompt:         r.redact(promptText),
+		RawResponse:    r.redact(raw),
+		Result:         r.redact(result),
+		DurationMs:     elapsed.Milliseconds(),
+	}
+	if genErr != nil {
+		entry.Error = r.redact(genErr.Error())
+	}
+	if usage != nil {
+		entry.Model = usage.Model
+		entry.PromptTokens = usage.PromptTokens
+		entry.CompletionTokens = usage.CompletionTokens
+	}
+	if err := s.audit.Create(entry); err != nil {
+		logger.Warnf("写入审计日志失败: %v", err)
+		return
+	}
+
+	maxEntries := cfg.Audit.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = config.DefaultAuditMaxEntries
+	}
+	if err := s.audit.Evict(maxEntries); err != nil {
+		logger.Warnf("清理审计日志失败: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+)
+
+// memoryAuditStore 是内存中的 AuditStore 实现
+type memoryAuditStore struct {
+	mu      sync.Mutex
+	entries []models.AuditLog
+}
+
+func (m *memoryAuditStore) Create(entry *models.AuditLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *memoryAuditStore) Evict(maxEntries int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) > maxEntries {
+		m.entries = m.entries[len(m.entries)-maxEntries:]
+	}
+	return nil
+}
+
+func (m *memoryAuditStore) Entries() []models.AuditLog {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.AuditLog(nil), m.entries...)
+}
+
+func TestCommitService_AuditLog_Redacts(t *testing.T) {
+	registerMockProvider("mock-audit-down", NewMockAIClientWithError(&httpx.StatusError{StatusCode: 500, Message: "bad key sk-audit-secret"}))
+	registerMockProvider("mock-audit", &MockAIClient{Deltas: []string{"fix: rotate token ghp_abcdef123456"}})
+
+	svc, _ := newRecordingCommitService()
+	store := &memoryAuditStore{}
+	svc.SetAuditStore(store)
+	cfg := &config.Config{
+		Retry: config.RetrySettings{MaxAttempts: 1},
+		Providers: map[string]config.ProviderSettings{
+			"mock-audit-down": {APIKey: "sk-audit-secret"},
+			"mock-audit":      {Model: "audit-model"},
+		},
+		Audit: config.AuditSettings{Enabled: true, Redact: []string{`ghp_[A-Za-z0-9]+`, `(`}},
+	}
+
+	session := newTestSession(svc, "/test/project")
+	svc.runGeneration(session, cfg, []string{"mock-audit-down", "mock-audit"}, "diff with sk-audit-secret", 1)
+
+	entries := store.Entries()
+	require.Len(t, entries, 2, "失败和成功的请求都记录")
+
+	failed := entries[0]
+	assert.Equal(t, "mock-audit-down", failed.Provider)
+	assert.Equal(t, "diff with [REDACTED]", failed.Prompt)
+	assert.Contains(t, failed.Error, "bad key [REDACTED]")
+	assert.Empty(t, failed.Result)
+
+	ok := entries[1]
+	assert.Equal(t, session.SessionID, ok.SessionID)
+	assert.Equal(t, "/test/project", ok.ProjectPath)
+	assert.Equal(t, "audit-model", ok.Model)
+	assert.Equal(t, "fix: rotate token [REDACTED]", ok.RawResponse)
+	assert.Equal(t, "fix: rotate token [REDACTED]", ok.Result)
+	assert.Empty(t, ok.Error)
+
+	// 未启用时不记录
+	cfg.Audit.Enabled = false
+	svc.runGeneration(newTestSession(svc, "/test/project"), cfg, []string{"mock-audit"}, "prompt", 1)
+	assert.Len(t, store.Entries(), 2)
+}
+
+func TestCommitService_AuditLog_Evicts(t *testing.T) {
+	registerMockProvider("mock-audit-evict", NewMockAIClient("docs: readme", nil))
+
+	svc, _ := newRecordingCommitService()
+	store := &memoryAuditStore{}
+	svc.SetAuditStore(store)
+	cfg := &config.Config{Audit: config.AuditSettings{Enabled: true, MaxEntries: 2}}
+	for i := 0; i < 3; i++ {
+		svc.runGeneration(newTestSession(svc, "/test/project"), cfg, []string{"mock-audit-evict"}, "prompt", 1)
+	}
+	assert.Len(t, store.Entries(), 2)
+}
+
+func TestCommitService_AuditLog_SummaryChunks(t *testing.T) {
+	registerMockProvider("mock-audit-summary", NewMockAIClient("- summarized change", []string{"feat: large change"}))
+
+	svc, _ := newRecordingCommitService()
+	store := &memoryAuditStore{}
+	svc.SetAuditStore(store)
+	cfg := &config.Config{
+		Limits:    config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 2500}},
+		Providers: map[string]config.ProviderSettings{"mock-audit-summary": {}},
+		Audit:     config.AuditSettings{Enabled: true},
+	}
+	diff := buildTestDiff(6, 80)
+	chunks := len(splitDiffForSummary(diff, summaryChunkChars(cfg, "mock-audit-summary")))
+	result := svc.generateFromDiff(newTestSession(svc, "/test/project"), cfg, []string{"mock-audit-summary"}, diff, prompt.DefaultPromptTemplate, 1)
+	require.NotNil(t, result)
+
+	entries := store.Entries()
+	require.Len(t, entries, chunks+1, "每个分块的总结请求都记录")
+	summaries := 0
+	for _, entry := range entries {
+		if entry.CandidateIndex == summaryIndex {
+			summaries++
+			assert.Equal(t, "- summarized change", entry.Result)
+		}
+	}
+	assert.Equal(t, chunks, summaries)
+	assert.Equal(t, 0, entries[len(entries)-1].CandidateIndex)
+	assert.Equal(t, "feat: large change", entries[len(entries)-1].Result)
+}
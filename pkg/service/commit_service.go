@@ -2,35 +2,261 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/WQGroup/logger"
-	"github.com/allanpk716/ai-commit-hub/pkg/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
 	"github.com/allanpk716/ai-commit-hub/pkg/git"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
 	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/providererr"
 	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
-	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// EventEmitter 向前端发送事件，默认使用 Wails runtime.EventsEmit
+type EventEmitter func(ctx context.Context, eventName string, optionalData ...interface{})
+
+// SessionInfo 标识一次生成，所有 commit-* 事件都携带该信息，
+// 前端据此区分不同项目、不同轮次的输出。
+// commit-started 和 commit-cancelled 事件的数据即为 SessionInfo。
+type SessionInfo struct {
+	SessionID   string `json:"sessionId"`
+	ProjectPath string `json:"projectPath"`
+}
+
+// MaxCandidates 是一次生成允许的最大候选消息数
+const MaxCandidates = 5
+
+// 生成策略，决定如何使用 provider 调用链
+const (
+	// StrategyFallback 依次尝试调用链中的 provider，前一个失败时才使用下一个（默认）
+	StrategyFallback = "fallback"
+	// StrategyRace 同时向调用链中的前 MaxRaceProviders 个 provider 发送请求，
+	// 采用第一个有效的结果并取消其余请求；竞速时不流式输出
+	StrategyRace = "race"
+)
+
+// MaxRaceProviders 是竞速策略同时请求的最大 provider 数
+const MaxRaceProviders = 3
+
+// GenerateOptions 是一次生成的参数，零值表示使用配置文件中的默认值
+type GenerateOptions struct {
+	Provider string `json:"provider"`
+	Language string `json:"language"`
+	// FallbackProviders 为项目级回退链，nil 表示使用配置文件中的 fallbackProviders
+	FallbackProviders []string `json:"fallbackProviders"`
+	// Candidates 为候选消息数量，<=1 时只生成一条
+	Candidates int `json:"candidates"`
+	// Regenerate 为 true 时跳过缓存，重新调用 provider（新结果仍会写入缓存）
+	Regenerate bool `json:"regenerate"`
+	// Structured 为 true 时要求 provider 按 JSON schema 输出各字段，校验后渲染为文本（不流式输出）
+	Structured bool `json:"structured"`
+	// Params 为项目级生成参数，覆盖调用链中各 provider 在配置文件中的 params，nil 表示不覆盖
+	Params *aicommitconfig.GenerationParams `json:"params,omitempty"`
+	// Strategy 为生成策略（StrategyFallback / StrategyRace），空值表示 StrategyFallback
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// CommitDeltaEvent 是 commit-delta 事件的数据，Index 为候选消息序号
+type CommitDeltaEvent struct {
+	SessionInfo
+	Index int    `json:"index"`
+	Delta string `json:"delta"`
+}
+
+// CommitReasoningEvent 是 commit-reasoning 事件的数据：推理模型输出的思考过程，
+// 不包含在 commit-delta 和最终消息中，供前端在“思考过程”面板中显示
+type CommitReasoningEvent struct {
+	SessionInfo
+	Index int    `json:"index"`
+	Delta string `json:"delta"`
+}
+
+// CommitCandidate 是一条生成成功的候选消息
+type CommitCandidate struct {
+	Index    int       `json:"index"`
+	Message  string    `json:"message"`
+	Provider string    `json:"provider"`
+	Usage    *ai.Usage `json:"usage,omitempty"` // provider 未返回用量时为 nil
+	// Structured 为结构化模式下解析出的各字段，Message 由其渲染得到
+	Structured *ai.StructuredCommit `json:"structured,omitempty"`
+}
+
+// CommitCandidateEvent 是 commit-candidate 事件的数据，多候选模式下每条候选结束时发送。
+// Error 非空表示该候选生成失败。
+type CommitCandidateEvent struct {
+	SessionInfo
+	CommitCandidate
+	Error string `json:"error,omitempty"`
+}
+
+// CommitResult 是 commit-complete 事件的数据
+// Provider 为实际生成消息的 provider（可能来自回退链）
+// 多候选模式下 Candidates 按序号列出所有成功的候选，Message 为第一条
+// Usage 为本次生成所有候选的 token 用量之和
+// Cached 为 true 表示结果来自缓存，未调用 provider
+// Structured 为结构化模式下 Message 对应的各字段
+type CommitResult struct {
+	SessionInfo
+	Message    string               `json:"message"`
+	Provider   string               `json:"provider"`
+	Structured *ai.StructuredCommit `json:"structured,omitempty"`
+	Candidates []CommitCandidate    `json:"candidates,omitempty"`
+	Usage      *ai.Usage            `json:"usage,omitempty"`
+	Cached     bool                 `json:"cached,omitempty"`
+}
+
+// CommitFallbackEvent 是 commit-fallback 事件的数据，表示切换到下一个 provider
+type CommitFallbackEvent struct {
+	SessionInfo
+	Index          int    `json:"index"`
+	FailedProvider string `json:"failedProvider"`
+	NextProvider   string `json:"nextProvider"`
+	Error          string `json:"error"`
+}
+
+// CommitRetryEvent 是 commit-retry 事件的数据，表示 provider 遇到暂时性错误，
+// 将在 DelayMs 毫秒后进行第 Attempt 次（共 MaxAttempts 次）请求。Index 为 -1 表示总结 diff 分块的请求
+type CommitRetryEvent struct {
+	SessionInfo
+	Index       int    `json:"index"`
+	Provider    string `json:"provider"`
+	Attempt     int    `json:"attempt"`
+	MaxAttempts int    `json:"maxAttempts"`
+	DelayMs     int64  `json:"delayMs"`
+	Error       string `json:"error"`
+}
+
+// CommitErrorEvent 是 commit-error 事件的数据
+type CommitErrorEvent struct {
+	SessionInfo
+	Error string `json:"error"`
+}
+
+// GenerationCache 保存相同输入的生成结果，避免对同一份暂存区 diff 重复调用 provider
+type GenerationCache interface {
+	// Get 返回 notBefore 之后写入的缓存，未命中时返回 nil
+	Get(key string, notBefore time.Time) (*models.GenerationCache, error)
+	Put(entry *models.GenerationCache) error
+	// Evict 删除 notBefore 之前写入的缓存，并只保留最近使用的 maxEntries 条
+	Evict(notBefore time.Time, maxEntries int) error
+}
+
+// generationSession 表示一次进行中的生成，持有可取消的 context
+type generationSession struct {
+	SessionInfo
+	ctx    context.Context
+	cancel context.CancelFunc
+	// summaryUsage 是分块总结 diff 消耗的 token 用量，计入最终结果
+	summaryUsage *ai.Usage
+	// structured 表示本次生成使用结构化输出
+	structured bool
+	// race 表示本次生成使用竞速策略
+	race bool
+	// promptData 是渲染 prompt 模板用的项目信息（分支、变更文件、最近提交等），Diff 和 Language 在构建 prompt 时填入
+	promptData prompt.CommitPromptData
+}
+
+var sessionSeq atomic.Uint64
+
+// newSessionID 生成进程内唯一的会话 ID
+func newSessionID() string {
+	return fmt.Sprintf("gen-%d-%d", time.Now().UnixMilli(), sessionSeq.Add(1))
+}
+
 type CommitService struct {
-	ctx            context.Context
-	configService  *ConfigService
+	ctx           context.Context
+	configService *ConfigService
+	emitter       EventEmitter
+	cache         GenerationCache // 为 nil 时不使用缓存
+	keyHealth     KeyHealthStore
+	audit         AuditStore // 为 nil 时不记录审计日志
+
+	mu       sync.Mutex
+	sessions map[string]*generationSession // key: projectPath
 }
 
 func NewCommitService(ctx context.Context) *CommitService {
 	return &CommitService{
 		ctx:           ctx,
 		configService: NewConfigService(),
+		emitter:       runtime.EventsEmit,
+		keyHealth:     newMemoryKeyHealthStore(),
+		sessions:      make(map[string]*generationSession),
 	}
 }
 
-func (s *CommitService) GenerateCommit(projectPath, providerName, language string) error {
-	logger.Info("开始生成 Commit 消息")
+// SetEmitter 替换事件发送函数（用于测试）
+func (s *CommitService) SetEmitter(emitter EventEmitter) {
+	s.emitter = emitter
+}
+
+// SetCache 设置生成结果缓存，nil 表示禁用
+func (s *CommitService) SetCache(cache GenerationCache) {
+	s.cache = cache
+}
+
+func (s *CommitService) emit(eventName string, data interface{}) {
+	s.emitter(s.ctx, eventName, data)
+}
+
+func (s *CommitService) emitError(info SessionInfo, errMsg string) {
+	s.emit("commit-error", CommitErrorEvent{SessionInfo: info, Error: errMsg})
+}
+
+// GenerateCommit 生成 commit 消息。fallbackProviders 为项目级回退链，
+// 传 nil 时使用配置文件中的 fallbackProviders。
+// 返回的会话 ID 与本次生成发送的所有事件中的 sessionId 一致。
+// 不同项目的生成可以并发进行；同一项目再次生成会取消上一次。
+func (s *CommitService) GenerateCommit(projectPath, providerName, language string, fallbackProviders []string) (string, error) {
+	return s.GenerateCommitWithOptions(projectPath, GenerateOptions{
+		Provider:          providerName,
+		Language:          language,
+		FallbackProviders: fallbackProviders,
+	})
+}
+
+// GenerateCommitWithOptions 按 opts 生成 commit 消息。
+// opts.Candidates > 1 时并行生成多条候选消息，各候选的 commit-delta 以 index 区分。
+func (s *CommitService) GenerateCommitWithOptions(projectPath string, opts GenerateOptions) (string, error) {
+	info := SessionInfo{SessionID: newSessionID(), ProjectPath: projectPath}
+	providerName, language, fallbackProviders := opts.Provider, opts.Language, opts.FallbackProviders
+	candidates := opts.Candidates
+	if candidates < 1 {
+		candidates = 1
+	}
+	if candidates > MaxCandidates {
+		candidates = MaxCandidates
+	}
+	logger.Infof("开始生成 Commit 消息，会话: %s", info.SessionID)
 	logger.Infof("项目路径: %s", projectPath)
 	logger.Infof("请求的 Provider: %s", providerName)
 	logger.Infof("请求的语言: %s", language)
+	logger.Infof("候选消息数量: %d", candidates)
+
+	// 先通知前端本次生成的会话 ID，后续事件（包括错误）均携带该 ID
+	s.emit("commit-started", info)
+
+	switch opts.Strategy {
+	case "", StrategyFallback, StrategyRace:
+	default:
+		errMsg := fmt.Sprintf("未知的生成策略: %s", opts.Strategy)
+		logger.Error(errMsg)
+		s.emitError(info, errMsg)
+		return info.SessionID, errors.New(errMsg)
+	}
 
 	// 加载配置检查 provider 是否已配置
 	logger.Info("正在加载配置...")
@@ -38,8 +264,8 @@ func (s *CommitService) GenerateCommit(projectPath, providerName, language strin
 	if err != nil {
 		errMsg := fmt.Sprintf("加载配置失败: %v", err)
 		logger.Error(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return fmt.Errorf("加载配置失败: %w", err)
+		s.emitError(info, errMsg)
+		return info.SessionID, fmt.Errorf("加载配置失败: %w", err)
 	}
 	logger.Info("配置加载成功")
 	logger.Infof("当前默认 Provider: %s", cfg.Provider)
@@ -53,127 +279,752 @@ func (s *CommitService) GenerateCommit(projectPath, providerName, language strin
 		cfg.Language = language
 		logger.Infof("使用指定的语言: %s", language)
 	}
+	if fallbackProviders == nil {
+		fallbackProviders = cfg.FallbackProviders
+	}
+	if opts.Params != nil {
+		overrideGenerationParams(cfg, append([]string{cfg.Provider}, fallbackProviders...), *opts.Params)
+		logger.Info("使用项目级生成参数")
+	}
 
 	// 检查 provider 是否已配置
 	logger.Info("检查 Provider 配置状态...")
+	chain, err := s.buildProviderChain(cfg, fallbackProviders)
+	if err != nil {
+		errMsg := fmt.Sprintf("Provider '%s' 未配置或不可用，请先在设置中配置 API Key", cfg.Provider)
+		logger.Error(errMsg)
+		s.emitError(info, errMsg)
+		return info.SessionID, err
+	}
+	logger.Infof("Provider 调用链: %v", chain)
+
+	// Get diff - 使用 GetStagedDiff 读取暂存区变更（匹配 ai-commit 项目行为）
+	logger.Info("获取暂存区 Diff（使用 git diff --cached）...")
+	diff, err := git.GetStagedDiff(context.Background(), projectPath)
+	if err != nil {
+		errMsg := fmt.Sprintf("获取暂存区 diff 失败: %v", err)
+		logger.Error(errMsg)
+		s.emitError(info, errMsg)
+		return info.SessionID, fmt.Errorf("获取暂存区 diff 失败: %w", err)
+	}
+	logger.Infof("暂存区 Diff 获取成功，长度: %d 字符", len(diff))
+
+	if diff == "" {
+		errMsg := "暂存区没有变更"
+		logger.Warn(errMsg)
+		s.emitError(info, errMsg)
+		return info.SessionID, nil
+	}
+
+	promptTemplate := s.commitPromptTemplate(cfg)
+	if opts.Structured {
+		logger.Info("使用结构化输出模式")
+		promptTemplate = prompt.WithStructuredOutput(promptTemplate)
+	}
+	promptData := buildPromptData(context.Background(), projectPath, diff)
+	cacheKey := generationCacheKey(cfg, chain, promptTemplate, promptData, diff)
+	if opts.Regenerate {
+		logger.Info("重新生成，跳过缓存")
+	} else if result := s.lookupCache(info, cfg, cacheKey, candidates, opts.Structured); result != nil {
+		logger.Infof("命中生成缓存，Provider: %s", result.Provider)
+		s.emit("commit-complete", *result)
+		return info.SessionID, nil
+	}
+
+	session := s.startSession(info)
+	session.structured = opts.Structured
+	session.promptData = promptData
+	session.race = opts.Strategy == StrategyRace && len(chain) > 1
+	if session.race {
+		logger.Infof("使用竞速策略，同时请求: %v", chain[:min(len(chain), MaxRaceProviders)])
+	}
+	go func() {
+		defer s.endSession(session)
+		if result := s.generateFromDiff(session, cfg, chain, diff, promptTemplate, candidates); result != nil {
+			s.storeCache(cfg, cacheKey, result)
+		}
+	}()
+	return info.SessionID, nil
+}
+
+// generateFromDiff 按 lockFiles 和 limits 配置处理 diff、构建 prompt 并生成 commit 消息，
+// 有内容被裁剪时发送 commit-diff-trimmed 事件。
+// diff 超出 Limits.Diff 时先分块总结（map），再以各块总结作为输入生成最终消息（reduce）。
+func (s *CommitService) generateFromDiff(session *generationSession, cfg *config.Config, chain []string, diff, promptTemplate string, candidates int) *CommitResult {
+	diff, report := applyDiffLimits(cfg, diff)
+	summarized := needsDiffSummary(cfg, diff, chain[0])
+	if summarized {
+		logger.Infof("Diff 长度 %d 超出限制（配置 %d 字符，%s 上下文约 %d 字符），分块总结后生成", len(diff), cfg.Limits.Diff.MaxChars, chain[0], contextChars(cfg, chain[0]))
+		summary, usage, err := s.summarizeDiff(session, cfg, chain, diff)
+		if session.ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("分块总结 diff 失败: %v", err)
+			logger.Error(errMsg)
+			s.emitError(session.SessionInfo, errMsg)
+			return nil
+		}
+		session.summaryUsage = usage
+		diff = summary
+	}
+
+	logger.Info("构建 Prompt...")
+	promptText, report := buildLimitedPrompt(cfg, diff, promptTemplate, summarized, session.promptData, report)
+	logger.Debugf("Prompt 长度: %d 字符", len(promptText))
+	if report.Trimmed() {
+		logger.Infof("Diff 已裁剪: %d -> %d 字符，省略文件: %v，截断文件: %v", report.OriginalChars, report.FinalChars, report.OmittedFiles, report.TruncatedFiles)
+		s.emit("commit-diff-trimmed", CommitDiffTrimmedEvent{SessionInfo: session.SessionInfo, DiffTrimReport: report})
+	}
+	return s.runGeneration(session, cfg, chain, promptText, candidates)
+}
+
+// generationCacheKey 由调用链中各 provider 的模型和生成参数、语言、prompt 模板及其项目信息和 diff 计算缓存键。
+// 回退或竞速时结果可能来自链中任一 provider，因此整条链都计入缓存键。
+func generationCacheKey(cfg *config.Config, chain []string, promptTemplate string, promptData prompt.CommitPromptData, diff string) string {
+	promptData.Diff = ""
+	promptDataJSON, _ := json.Marshal(promptData)
+	parts := []string{cfg.Language, promptTemplate, string(promptDataJSON), diff}
+	for _, name := range chain {
+		ps := cfg.Providers[name]
+		// 超时不影响生成内容，不计入缓存键
+		params := ps.Params
+		params.Timeout = 0
+		paramsJSON, _ := json.Marshal(params)
+		parts = append(parts, name, ps.Model, string(paramsJSON))
+	}
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheSettings 返回缓存的 TTL 和最大条数，未配置时使用默认值
+func cacheSettings(cfg *config.Config) (time.Duration, int) {
+	ttl, maxEntries := cfg.Cache.TTL, cfg.Cache.MaxEntries
+	if ttl <= 0 {
+		ttl = config.DefaultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = config.DefaultCacheMaxEntries
+	}
+	return ttl, maxEntries
+}
+
+// lookupCache 查找缓存的生成结果，缓存的候选数量不足 candidates 时视为未命中。
+// structured 为 true 时缓存的是各字段的 JSON，解析后重新渲染为消息。
+func (s *CommitService) lookupCache(info SessionInfo, cfg *config.Config, key string, candidates int, structured bool) *CommitResult {
+	if s.cache == nil || cfg.Cache.Disabled {
+		return nil
+	}
+
+	ttl, _ := cacheSettings(cfg)
+	entry, err := s.cache.Get(key, time.Now().Add(-ttl))
+	if err != nil {
+		logger.Warnf("读取生成缓存失败: %v", err)
+		return nil
+	}
+	if entry == nil || len(entry.Messages) < candidates {
+		return nil
+	}
+
+	cached := make([]CommitCandidate, 0, candidates)
+	for i, msg := range entry.Messages[:candidates] {
+		c := CommitCandidate{Index: i, Message: msg, Provider: entry.Provider}
+		if structured {
+			if c.Structured, err = parseStructuredCommit(msg); err != nil {
+				logger.Warnf("解析缓存的结构化消息失败: %v", err)
+				return nil
+			}
+			c.Message = c.Structured.Render()
+		}
+		cached = append(cached, c)
+	}
+
+	result := &CommitResult{
+		SessionInfo: info,
+		Message:     cached[0].Message,
+		Provider:    entry.Provider,
+		Structured:  cached[0].Structured,
+		Cached:      true,
+	}
+	if candidates > 1 {
+		result.Candidates = cached
+	}
+	return result
+}
+
+// storeCache 写入生成结果并按 TTL 和容量淘汰旧缓存
+func (s *CommitService) storeCache(cfg *config.Config, key string, result *CommitResult) {
+	if s.cache == nil || cfg.Cache.Disabled {
+		return
+	}
+
+	candidates := result.Candidates
+	if len(candidates) == 0 {
+		candidates = []CommitCandidate{{Message: result.Message, Structured: result.Structured}}
+	}
+	messages := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		msg := c.Message
+		if c.Structured != nil {
+			data, err := json.Marshal(c.Structured)
+			if err != nil {
+				logger.Warnf("序列化结构化消息失败: %v", err)
+				return
+			}
+			msg = string(data)
+		}
+		messages = append(messages, msg)
+	}
+
+	entry := &models.GenerationCache{
+		Key:      key,
+		Provider: result.Provider,
+		Model:    cfg.Providers[result.Provider].Model,
+		Language: cfg.Language,
+		Messages: messages,
+	}
+	if err := s.cache.Put(entry); err != nil {
+		logger.Warnf("写入生成缓存失败: %v", err)
+		return
+	}
+
+	ttl, maxEntries := cacheSettings(cfg)
+	if err := s.cache.Evict(time.Now().Add(-ttl), maxEntries); err != nil {
+		logger.Warnf("淘汰生成缓存失败: %v", err)
+	}
+}
+
+// CancelGeneration 取消项目正在进行的生成，中断 HTTP 流并发送 commit-cancelled 事件。
+// 没有进行中的生成时返回 false。
+func (s *CommitService) CancelGeneration(projectPath string) bool {
+	s.mu.Lock()
+	session, ok := s.sessions[projectPath]
+	if ok {
+		delete(s.sessions, projectPath)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		logger.Infof("项目没有进行中的生成: %s", projectPath)
+		return false
+	}
+
+	session.cancel()
+	logger.Infof("已取消生成: %s (会话 %s)", projectPath, session.SessionID)
+	s.emit("commit-cancelled", session.SessionInfo)
+	return true
+}
+
+// IsGenerating 返回项目是否有进行中的生成
+func (s *CommitService) IsGenerating(projectPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sessions[projectPath]
+	return ok
+}
+
+// startSession 为项目创建新的生成会话；同一项目已有的会话会被取消，并为其发送 commit-cancelled 事件
+func (s *CommitService) startSession(info SessionInfo) *generationSession {
+	ctx, cancel := context.WithCancel(s.ctx)
+	session := &generationSession{SessionInfo: info, ctx: ctx, cancel: cancel}
+
+	s.mu.Lock()
+	previous := s.sessions[info.ProjectPath]
+	s.sessions[info.ProjectPath] = session
+	s.mu.Unlock()
+
+	if previous != nil {
+		logger.Infof("项目已有进行中的生成，取消旧的生成: %s (会话 %s)", info.ProjectPath, previous.SessionID)
+		previous.cancel()
+		s.emit("commit-cancelled", previous.SessionInfo)
+	}
+	return session
+}
+
+// endSession 结束会话并释放 context（会话已被替换或取消时不影响新会话）
+func (s *CommitService) endSession(session *generationSession) {
+	s.mu.Lock()
+	if s.sessions[session.ProjectPath] == session {
+		delete(s.sessions, session.ProjectPath)
+	}
+	s.mu.Unlock()
+	session.cancel()
+}
+
+// buildProviderChain 返回按顺序尝试的 provider 列表：主 provider 在前，
+// 随后是已配置的回退 provider（去重，跳过未配置或未注册的）。
+// 主 provider 未配置时返回错误。
+func (s *CommitService) buildProviderChain(cfg *config.Config, fallbackProviders []string) ([]string, error) {
 	providers := s.configService.GetConfiguredProviders(cfg)
 	logger.Infof("找到 %d 个已配置的 Provider", len(providers))
 
-	providerConfigured := false
+	configured := make(map[string]bool, len(providers))
 	for _, p := range providers {
 		logger.Debugf("Provider: %s, 配置状态: %v", p.Name, p.Configured)
-		if p.Name == cfg.Provider && p.Configured {
-			providerConfigured = true
-			logger.Infof("找到已配置的 Provider: %s", cfg.Provider)
-			break
+		configured[p.Name] = p.Configured
+	}
+
+	if !configured[cfg.Provider] {
+		logger.Infof("可用的 Provider: %v", providers)
+		return nil, fmt.Errorf("provider not configured: %s", cfg.Provider)
+	}
+
+	chain := []string{cfg.Provider}
+	seen := map[string]bool{cfg.Provider: true}
+	for _, name := range fallbackProviders {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
 		}
+		seen[name] = true
+		if !configured[name] {
+			logger.Warnf("回退 Provider '%s' 未配置，已跳过", name)
+			continue
+		}
+		chain = append(chain, name)
 	}
+	return chain, nil
+}
 
-	if !providerConfigured {
-		errMsg := fmt.Sprintf("Provider '%s' 未配置或不可用，请先在设置中配置 API Key", cfg.Provider)
+// runGeneration 生成 candidates 条消息并发送最终事件：
+// 成功时发送 commit-complete 并返回结果，全部失败时发送 commit-error，取消时不发送；后两种情况返回 nil。
+func (s *CommitService) runGeneration(session *generationSession, cfg *config.Config, chain []string, promptText string, candidates int) *CommitResult {
+	if candidates <= 1 {
+		candidate, err := s.runProviderChain(session, cfg, chain, promptText, 0)
+		if session.ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("生成失败: %v", err)
+			logger.Error(errMsg)
+			s.emitError(session.SessionInfo, errMsg)
+			return nil
+		}
+		result := CommitResult{
+			SessionInfo: session.SessionInfo,
+			Message:     candidate.Message,
+			Provider:    candidate.Provider,
+			Structured:  candidate.Structured,
+			Usage:       mergeUsage(candidate.Usage, session.summaryUsage),
+		}
+		s.emit("commit-complete", result)
+		return &result
+	}
+
+	// 多候选：并行调用，每条候选使用带序号提示的 prompt 以获得不同措辞
+	results := make([]*CommitCandidate, candidates)
+	errs := make([]error, candidates)
+	var wg sync.WaitGroup
+	for i := 0; i < candidates; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			candidatePrompt := prompt.WithCandidateVariation(promptText, index, candidates)
+			candidate, err := s.runProviderChain(session, cfg, chain, candidatePrompt, index)
+			if session.ctx.Err() != nil {
+				return
+			}
+			event := CommitCandidateEvent{SessionInfo: session.SessionInfo, CommitCandidate: candidate}
+			if err != nil {
+				errs[index] = err
+				event.Index = index
+				event.Error = err.Error()
+			} else {
+				results[index] = &candidate
+			}
+			s.emit("commit-candidate", event)
+		}(i)
+	}
+	wg.Wait()
+
+	if session.ctx.Err() != nil {
+		return nil
+	}
+
+	var succeeded []CommitCandidate
+	var lastErr error
+	for i, c := range results {
+		if c != nil {
+			succeeded = append(succeeded, *c)
+		} else if errs[i] != nil {
+			lastErr = errs[i]
+		}
+	}
+	if len(succeeded) == 0 {
+		errMsg := fmt.Sprintf("生成失败: %v", lastErr)
 		logger.Error(errMsg)
-		logger.Infof("可用的 Provider: %v", providers)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return fmt.Errorf("provider not configured: %s", cfg.Provider)
+		s.emitError(session.SessionInfo, errMsg)
+		return nil
 	}
 
+	logger.Infof("候选消息生成完成: %d/%d 成功", len(succeeded), candidates)
+	result := CommitResult{
+		SessionInfo: session.SessionInfo,
+		Message:     succeeded[0].Message,
+		Provider:    succeeded[0].Provider,
+		Structured:  succeeded[0].Structured,
+		Candidates:  succeeded,
+		Usage:       mergeUsage(sumUsage(succeeded), session.summaryUsage),
+	}
+	s.emit("commit-complete", result)
+	return &result
+}
+
+// sumUsage 汇总候选的 token 用量，Provider 和 Model 取第一条有用量的候选（按其计价）
+func sumUsage(candidates []CommitCandidate) *ai.Usage {
+	var total *ai.Usage
+	for _, c := range candidates {
+		total = mergeUsage(total, c.Usage)
+	}
+	return total
+}
+
+// mergeUsage 返回 a 与 b 相加后的用量（不修改参数），Provider 和 Model 取 a，a 为 nil 时取 b
+func mergeUsage(a, b *ai.Usage) *ai.Usage {
+	if a == nil {
+		if b == nil {
+			return nil
+		}
+		a, b = b, nil
+	}
+	total := *a
+	if b != nil {
+		total.PromptTokens += b.PromptTokens
+		total.CompletionTokens += b.CompletionTokens
+	}
+	return &total
+}
+
+// parseStructuredCommit 解析 provider 返回的结构化 JSON 并按配置的 commit 类型校验
+func parseStructuredCommit(text string) (*ai.StructuredCommit, error) {
+	structured, err := ai.ParseStructuredCommit(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := structured.Validate(); err != nil {
+		return nil, err
+	}
+	return structured, nil
+}
+
+// runProviderChain 为序号为 index 的候选依次尝试 chain 中的 provider，直到某个成功生成消息。
+// 切换 provider 时发送 commit-fallback 事件；全部失败时返回最后一个错误。
+// 会话被取消时立即返回（commit-cancelled 事件已由 CancelGeneration 发送）。
+// 使用竞速策略时先同时请求前 MaxRaceProviders 个 provider，全部失败后再依次尝试其余的 provider。
+func (s *CommitService) runProviderChain(session *generationSession, cfg *config.Config, chain []string, promptText string, index int) (CommitCandidate, error) {
+	mode := outputStream
+	if session.structured {
+		mode = outputStructured
+	}
+	var lastErr error
+	if session.race {
+		racers := chain[:min(len(chain), MaxRaceProviders)]
+		candidate, err := s.raceProviders(session, cfg, racers, promptText, index)
+		if err == nil || session.ctx.Err() != nil {
+			return candidate, err
+		}
+		lastErr = err
+		chain = chain[len(racers):]
+		if len(chain) > 0 {
+			logger.Warnf("竞速的 Provider 全部失败，回退到: %s", chain[0])
+			s.emit("commit-fallback", CommitFallbackEvent{
+				SessionInfo:    session.SessionInfo,
+				Index:          index,
+				FailedProvider: strings.Join(racers, ", "),
+				NextProvider:   chain[0],
+				Error:          err.Error(),
+			})
+		}
+	}
+	for i, name := range chain {
+		candidate, err := s.generateCandidate(session, cfg, name, promptText, index, mode)
+		if session.ctx.Err() != nil {
+			logger.Infof("生成已取消，停止 Provider 调用链（当前: %s）", name)
+			return CommitCandidate{}, session.ctx.Err()
+		}
+		if err == nil {
+			logger.Infof("Commit 消息生成成功，Provider: %s，候选: %d", name, index)
+			return candidate, nil
+		}
+
+		lastErr = err
+		logger.Errorf("Provider '%s' 生成失败: %v", name, err)
+		if i+1 < len(chain) {
+			next := chain[i+1]
+			logger.Warnf("回退到下一个 Provider: %s", next)
+			s.emit("commit-fallback", CommitFallbackEvent{
+				SessionInfo:    session.SessionInfo,
+				Index:          index,
+				FailedProvider: name,
+				NextProvider:   next,
+				Error:          err.Error(),
+			})
+		}
+	}
+	return CommitCandidate{}, lastErr
+}
+
+// raceProviders 同时向 racers 中的 provider 发送相同的 prompt，采用第一个有效的结果并取消其余请求。
+// 多个 provider 的输出无法合并到同一条消息中，因此竞速时不发送 commit-delta 事件。
+// 全部失败时返回最后一个错误。
+func (s *CommitService) raceProviders(session *generationSession, cfg *config.Config, racers []string, promptText string, index int) (CommitCandidate, error) {
+	mode := outputText
+	if session.structured {
+		mode = outputStructured
+	}
+	// 各 provider 使用同一个可取消的子会话，产生结果后取消其余请求，不影响会话本身
+	race := *session
+	race.ctx, race.cancel = context.WithCancel(session.ctx)
+	defer race.cancel()
+
+	type outcome struct {
+		candidate CommitCandidate
+		err       error
+	}
+	outcomes := make(chan outcome, len(racers))
+	for _, name := range racers {
+		go func(name string) {
+			start := time.Now()
+			candidate, err := s.generateCandidate(&race, cfg, name, promptText, index, mode)
+			// 落后被取消的 provider 只有耗时下限，也要记录，否则排序只会偏向每次获胜的 provider
+			if err != nil && race.ctx.Err() != nil && session.ctx.Err() == nil {
+				providerLatencies.recordAtLeast(name, time.Since(start))
+			}
+			outcomes <- outcome{candidate: candidate, err: err}
+		}(name)
+	}
+
+	var lastErr error
+	for range racers {
+		o := <-outcomes
+		if session.ctx.Err() != nil {
+			logger.Infof("生成已取消，停止竞速")
+			return CommitCandidate{}, session.ctx.Err()
+		}
+		if o.err == nil {
+			logger.Infof("竞速完成，采用 Provider: %s，候选: %d", o.candidate.Provider, index)
+			return o.candidate, nil
+		}
+		lastErr = o.err
+		logger.Errorf("竞速中的 Provider 生成失败: %v", o.err)
+	}
+	return CommitCandidate{}, lastErr
+}
+
+// generateCandidate 使用单个 provider（含重试）生成序号为 index 的候选消息，并在启用时写入审计日志。
+// 结构化输出无法解析或未通过校验时返回错误，由调用方回退到其它 provider。
+func (s *CommitService) generateCandidate(session *generationSession, cfg *config.Config, name, promptText string, index int, mode outputMode) (CommitCandidate, error) {
+	start := time.Now()
+	raw, usage, err := s.generateWithRetry(session, cfg, name, promptText, index, mode)
+	msg := raw
+	var structured *ai.StructuredCommit
+	if err == nil && session.structured {
+		if structured, err = parseStructuredCommit(raw); err != nil {
+			err = fmt.Errorf("provider %s 返回的结构化结果无效: %w", name, err)
+		} else {
+			msg = structured.Render()
+		}
+	}
+	if err != nil {
+		msg = ""
+	}
+	s.recordAudit(session, cfg, name, promptText, index, raw, msg, usage, err, time.Since(start))
+	if err != nil {
+		return CommitCandidate{}, err
+	}
+	return CommitCandidate{Index: index, Message: msg, Provider: name, Usage: usage, Structured: structured}, nil
+}
+
+// overrideGenerationParams 用项目级生成参数覆盖 providers 中各 provider 的 params，未设置的字段保留配置文件中的值
+func overrideGenerationParams(cfg *config.Config, providers []string, params aicommitconfig.GenerationParams) {
+	if cfg.Providers == nil {
+		cfg.Providers = make(map[string]config.ProviderSettings)
+	}
+	for _, name := range providers {
+		ps := cfg.Providers[name]
+		ps.Params = ps.Params.Merge(params)
+		cfg.Providers[name] = ps
+	}
+}
+
+// newProviderClient 从注册表创建 provider 的 AI client，使用配置文件中该 provider 的设置
+func newProviderClient(ctx context.Context, cfg *config.Config, name string) (ai.AIClient, error) {
 	// Get AI client from registry (imports provider packages for side effects)
 	// The providers are already registered via their init() functions
-	logger.Infof("从注册表获取 Provider: %s", cfg.Provider)
-	factory, ok := registry.Get(cfg.Provider)
+	logger.Infof("从注册表获取 Provider: %s", name)
+	factory, ok := registry.Get(name)
 	if !ok {
-		errMsg := fmt.Sprintf("未知的 provider: %s", cfg.Provider)
-		logger.Error(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return fmt.Errorf("未知的 provider: %s", cfg.Provider)
+		return nil, fmt.Errorf("未知的 provider: %s", name)
 	}
 
 	// Convert our config.ProviderSettings to ai-commit's config.ProviderSettings
-	providerSettings := cfg.Providers[cfg.Provider]
+	providerSettings := cfg.Providers[name]
+	apiKey := providerSettings.APIKey
+	if keys := providerSettings.Keys(); apiKey == "" && len(keys) > 0 {
+		// 只配置了 apiKeys 列表时使用第一个 key，多个 key 的轮换见 generateWithKeys
+		apiKey = keys[0].Key
+	}
 	ps := aicommitconfig.ProviderSettings{
-		APIKey:  providerSettings.APIKey,
-		Model:   providerSettings.Model,
-		BaseURL: providerSettings.BaseURL,
+		APIKey:     apiKey,
+		Model:      providerSettings.Model,
+		BaseURL:    providerSettings.BaseURL,
+		APIVersion: providerSettings.APIVersion,
+		Params:     providerSettings.Params,
 	}
 	logger.Infof("Provider 配置 - Model: %s, BaseURL: %s", providerSettings.Model, providerSettings.BaseURL)
+	if err := registry.ValidateParams(name, ps.Params); err != nil {
+		return nil, fmt.Errorf("provider %s 的生成参数无效: %w", name, err)
+	}
 
 	logger.Info("创建 AI Client...")
-	client, err := factory(context.Background(), cfg.Provider, ps)
+	client, err := factory(ctx, name, ps)
 	if err != nil {
-		errMsg := fmt.Sprintf("创建 AI client 失败: %v", err)
-		logger.Error(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return fmt.Errorf("创建 AI client 失败: %w", err)
+		return nil, fmt.Errorf("创建 AI client 失败: %w", err)
 	}
 	logger.Info("AI Client 创建成功")
+	return client, nil
+}
 
-	// Get diff - 使用 GetStagedDiff 读取暂存区变更（匹配 ai-commit 项目行为）
-	logger.Info("获取暂存区 Diff（使用 git diff --cached）...")
-	originalDir, _ := os.Getwd()
-	err = os.Chdir(projectPath)
-	if err != nil {
-		errMsg := fmt.Sprintf("切换到项目目录失败: %v", err)
-		logger.Error(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return fmt.Errorf("切换目录失败: %w", err)
+// retryPolicy 返回配置的重试策略，未配置的字段使用默认值
+func retryPolicy(cfg *config.Config) httpx.RetryPolicy {
+	policy := httpx.DefaultRetryPolicy()
+	if cfg.Retry.MaxAttempts > 0 {
+		policy.MaxAttempts = cfg.Retry.MaxAttempts
 	}
-	defer os.Chdir(originalDir)
-
-	diff, err := git.GetStagedDiff(context.Background())
-	if err != nil {
-		errMsg := fmt.Sprintf("获取暂存区 diff 失败: %v", err)
-		logger.Error(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return fmt.Errorf("获取暂存区 diff 失败: %w", err)
+	if cfg.Retry.BaseDelay > 0 {
+		policy.BaseDelay = cfg.Retry.BaseDelay
 	}
-	logger.Infof("暂存区 Diff 获取成功，长度: %d 字符", len(diff))
+	if cfg.Retry.MaxDelay > 0 {
+		policy.MaxDelay = cfg.Retry.MaxDelay
+	}
+	return policy
+}
 
-	if diff == "" {
-		errMsg := "暂存区没有变更"
-		logger.Warn(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return nil
+// generateWithRetry 使用单个 provider 生成消息，遇到限流、5xx、超时等暂时性错误时按重试策略重试，
+// 每次重试前发送 commit-retry 事件。已有内容发送到前端后不再重试，避免重复输出。
+func (s *CommitService) generateWithRetry(session *generationSession, cfg *config.Config, name, promptText string, index int, mode outputMode) (string, *ai.Usage, error) {
+	policy := retryPolicy(cfg)
+	for attempt := 1; ; attempt++ {
+		msg, usage, streamed, err := s.generateWithKeys(session, cfg, name, promptText, index, mode)
+		if err == nil || session.ctx.Err() != nil {
+			return msg, usage, err
+		}
+		if attempt >= policy.MaxAttempts || streamed || !providererr.IsRetryable(err) {
+			return "", nil, err
+		}
+
+		delay := policy.Delay(attempt, providererr.RetryAfter(err))
+		logger.Warnf("Provider '%s' 第 %d/%d 次请求失败，%v 后重试: %v", name, attempt, policy.MaxAttempts, delay, err)
+		s.emit("commit-retry", CommitRetryEvent{
+			SessionInfo: session.SessionInfo,
+			Index:       index,
+			Provider:    name,
+			Attempt:     attempt + 1,
+			MaxAttempts: policy.MaxAttempts,
+			DelayMs:     delay.Milliseconds(),
+			Error:       err.Error(),
+		})
+		if err := httpx.Sleep(session.ctx, delay); err != nil {
+			return "", nil, err
+		}
 	}
+}
 
-	// Build prompt
-	logger.Info("构建 Prompt...")
-	promptText := prompt.BuildCommitPrompt(diff, cfg.Language, "", "", "")
-	logger.Debugf("Prompt 长度: %d 字符", len(promptText))
+// outputMode 决定 generateWithProvider 调用 provider 的方式
+type outputMode int
 
-	// Stream commit message
-	if sc, ok := client.(ai.StreamingAIClient); ok {
-		logger.Info("使用流式生成模式")
-		go func() {
-			logger.Info("开始流式生成...")
-			final, err := sc.StreamCommitMessage(context.Background(), promptText, func(delta string) {
-				runtime.EventsEmit(s.ctx, "commit-delta", delta)
-			})
+const (
+	// outputStream 在 provider 支持时流式生成，并发送 commit-delta 事件
+	outputStream outputMode = iota
+	// outputText 非流式生成，不向前端输出内容
+	outputText
+	// outputStructured 按 ai.StructuredCommitSchema 生成 JSON，provider 支持时使用其原生的结构化输出能力
+	outputStructured
+)
 
-			if err != nil {
-				errMsg := fmt.Sprintf("生成失败: %v", err)
-				logger.Error(errMsg)
-				runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-			} else {
-				logger.Info("Commit 消息生成成功")
-				runtime.EventsEmit(s.ctx, "commit-complete", final)
+// generateWithProvider 使用单个 provider 生成消息，mode 为 outputStream 且 provider 支持流式时发送 commit-delta 事件。
+// streamed 表示是否已有内容发送到前端。
+// 返回 provider 报告的 token 用量（未报告时为 nil）。生成候选消息成功时记录本次耗时，用于按速度排列 provider；
+// 总结 diff 分块的请求 prompt 长度不同，不计入耗时。
+func (s *CommitService) generateWithProvider(session *generationSession, cfg *config.Config, name, promptText string, index int, mode outputMode) (msg string, usage *ai.Usage, streamed bool, err error) {
+	start := time.Now()
+	var usageMu sync.Mutex
+	ctx := ai.WithUsageRecorder(session.ctx, func(u ai.Usage) {
+		usageMu.Lock()
+		defer usageMu.Unlock()
+		if u.Provider == "" {
+			u.Provider = name
+		}
+		if u.Model == "" {
+			u.Model = cfg.Providers[name].Model
+		}
+		if usage == nil {
+			usage = &u
+			return
+		}
+		usage.PromptTokens += u.PromptTokens
+		usage.CompletionTokens += u.CompletionTokens
+	})
+	if mode == outputStream {
+		ctx = ai.WithReasoningHandler(ctx, func(delta string) {
+			if ctx.Err() != nil {
+				return
 			}
-		}()
-		return nil
+			s.emit("commit-reasoning", CommitReasoningEvent{SessionInfo: session.SessionInfo, Index: index, Delta: delta})
+		})
+	}
+	if timeout := cfg.Providers[name].Params.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	// Fallback: non-streaming
-	logger.Info("使用非流式生成模式")
-	msg, err := client.GetCommitMessage(context.Background(), promptText)
+	client, err := newProviderClient(ctx, cfg, name)
 	if err != nil {
-		errMsg := fmt.Sprintf("生成失败: %v", err)
-		logger.Error(errMsg)
-		runtime.EventsEmit(s.ctx, "commit-error", errMsg)
-		return err
+		return "", nil, false, err
 	}
 
-	logger.Info("Commit 消息生成成功")
-	runtime.EventsEmit(s.ctx, "commit-complete", msg)
-	return nil
+	sc, streaming := client.(ai.StreamingAIClient)
+	oc, structured := client.(ai.StructuredOutputClient)
+	switch {
+	case mode == outputStructured && structured:
+		logger.Info("使用结构化输出模式")
+		msg, err = oc.GenerateStructured(ctx, promptText, ai.StructuredCommitSchemaName, ai.StructuredCommitSchema())
+	case mode == outputStream && streaming:
+		logger.Info("使用流式生成模式")
+		msg, err = sc.StreamCommitMessage(ctx, promptText, func(delta string) {
+			// 已取消的会话不再向前端输出内容
+			if ctx.Err() != nil {
+				return
+			}
+			streamed = true
+			s.emit("commit-delta", CommitDeltaEvent{SessionInfo: session.SessionInfo, Index: index, Delta: delta})
+		})
+	default:
+		logger.Info("使用非流式生成模式")
+		msg, err = client.GetCommitMessage(ctx, promptText)
+	}
+	if err != nil {
+		return "", nil, streamed, err
+	}
+	if strings.TrimSpace(msg) == "" {
+		return "", nil, streamed, fmt.Errorf("provider %s 返回了空消息", name)
+	}
+
+	if index != summaryIndex {
+		providerLatencies.record(name, time.Since(start))
+	}
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	if usage != nil {
+		logger.Infof("Token 用量 - Model: %s, Prompt: %d, Completion: %d", usage.Model, usage.PromptTokens, usage.CompletionTokens)
+	}
+	return msg, usage, streamed, nil
 }
 
 // SaveHistory is a placeholder for history saving functionality
@@ -3,10 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	aicommitai "github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
 	"github.com/allanpk716/ai-commit-hub/tests/helpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMockAIClient_Basic(t *testing.T) {
@@ -53,7 +64,7 @@ func TestCommitService_GenerateCommit_EmptyDiff(t *testing.T) {
 	service := NewCommitService(context.Background())
 
 	// 没有暂存变更
-	err := service.GenerateCommit(repo.Path, "mock", "zh")
+	_, err := service.GenerateCommit(repo.Path, "mock", "zh", nil)
 
 	// 应该返回 nil（空 diff 不算错误）
 	assert.NoError(t, err)
@@ -78,3 +89,596 @@ func TestCommitService_GenerateCommit_WithMock(t *testing.T) {
 
 	t.Skip("需要 mock AI provider registry - 暂时跳过")
 }
+
+// recordedEvent 记录 CommitService 发送的事件
+type recordedEvent struct {
+	Name string
+	Data interface{}
+}
+
+// eventRecorder 并发安全地收集事件
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+func (r *eventRecorder) emit(ctx context.Context, eventName string, optionalData ...interface{}) {
+	var data interface{}
+	if len(optionalData) > 0 {
+		data = optionalData[0]
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recordedEvent{Name: eventName, Data: data})
+}
+
+func (r *eventRecorder) Events() []recordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedEvent(nil), r.events...)
+}
+
+func (r *eventRecorder) Names() []string {
+	var names []string
+	for _, e := range r.Events() {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func newRecordingCommitService() (*CommitService, *eventRecorder) {
+	recorder := &eventRecorder{}
+	svc := NewCommitService(context.Background())
+	svc.SetEmitter(recorder.emit)
+	return svc, recorder
+}
+
+func newTestSession(svc *CommitService, projectPath string) *generationSession {
+	return svc.startSession(SessionInfo{SessionID: newSessionID(), ProjectPath: projectPath})
+}
+
+func registerMockProvider(name string, client *MockAIClient) {
+	registry.Register(name, func(ctx context.Context, n string, ps aicommitconfig.ProviderSettings) (aicommitai.AIClient, error) {
+		client.Provider = n
+		return client, nil
+	})
+}
+
+func TestCommitService_BuildProviderChain(t *testing.T) {
+	registerMockProvider("mock-primary", NewMockAIClient("ok", nil))
+	registerMockProvider("mock-backup", NewMockAIClient("ok", nil))
+	registerMockProvider("mock-nokey", NewMockAIClient("ok", nil))
+	registry.SetRequiresAPIKey("mock-nokey", true)
+
+	svc := NewCommitService(context.Background())
+	cfg := &config.Config{
+		Provider: "mock-primary",
+		Providers: map[string]config.ProviderSettings{
+			"mock-primary": {},
+			"mock-backup":  {},
+			"mock-nokey":   {},
+		},
+	}
+
+	chain, err := svc.buildProviderChain(cfg, []string{"mock-nokey", "mock-primary", "mock-backup", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mock-primary", "mock-backup"}, chain)
+
+	cfg.Provider = "mock-nokey"
+	_, err = svc.buildProviderChain(cfg, []string{"mock-backup"})
+	assert.Error(t, err)
+}
+
+func TestCommitService_RunProviderChain_FallsBack(t *testing.T) {
+	registerMockProvider("mock-down", NewMockAIClientWithError(errors.New("503 service unavailable")))
+	registerMockProvider("mock-up", NewMockAIClient("", []string{"feat: ", "add fallback"}))
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	svc.runGeneration(session, &config.Config{}, []string{"mock-down", "mock-up"}, "prompt", 1)
+
+	assert.Equal(t, []string{"commit-fallback", "commit-delta", "commit-delta", "commit-complete"}, recorder.Names())
+
+	events := recorder.Events()
+	fallback := events[0].Data.(CommitFallbackEvent)
+	assert.Equal(t, session.SessionInfo, fallback.SessionInfo)
+	assert.Equal(t, "mock-down", fallback.FailedProvider)
+	assert.Equal(t, "mock-up", fallback.NextProvider)
+
+	result := events[len(events)-1].Data.(CommitResult)
+	assert.Equal(t, "feat: add fallback", result.Message)
+	assert.Equal(t, "mock-up", result.Provider)
+	assert.Equal(t, session.SessionInfo, result.SessionInfo)
+}
+
+func TestCommitService_RunProviderChain_AllFail(t *testing.T) {
+	registerMockProvider("mock-down-1", NewMockAIClientWithError(errors.New("429 rate limited")))
+	registerMockProvider("mock-empty", NewMockAIClient("   ", nil))
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	cfg := &config.Config{Retry: config.RetrySettings{BaseDelay: time.Millisecond}}
+	svc.runGeneration(session, cfg, []string{"mock-down-1", "mock-empty"}, "prompt", 1)
+
+	events := recorder.Events()
+	last := events[len(events)-1]
+	assert.Equal(t, "commit-error", last.Name)
+	assert.Equal(t, session.SessionInfo, last.Data.(CommitErrorEvent).SessionInfo)
+}
+
+func TestCommitService_RunProviderChain_RetriesTransientErrors(t *testing.T) {
+	flaky := &MockAIClient{Error: &httpx.StatusError{StatusCode: 429, Message: "rate limited"}, FailTimes: 2, Deltas: []string{"fix: ", "retry"}}
+	registerMockProvider("mock-flaky", flaky)
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	cfg := &config.Config{Retry: config.RetrySettings{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+	svc.runGeneration(session, cfg, []string{"mock-flaky"}, "prompt", 1)
+
+	assert.Equal(t, []string{"commit-retry", "commit-retry", "commit-delta", "commit-delta", "commit-complete"}, recorder.Names())
+	assert.Equal(t, 3, flaky.Calls())
+
+	events := recorder.Events()
+	retry := events[1].Data.(CommitRetryEvent)
+	assert.Equal(t, session.SessionInfo, retry.SessionInfo)
+	assert.Equal(t, "mock-flaky", retry.Provider)
+	assert.Equal(t, 3, retry.Attempt)
+	assert.Equal(t, 3, retry.MaxAttempts)
+
+	result := events[len(events)-1].Data.(CommitResult)
+	assert.Equal(t, "fix: retry", result.Message)
+}
+
+func TestCommitService_RunProviderChain_NoRetryAfterStreaming(t *testing.T) {
+	broken := &MockAIClient{Deltas: []string{"feat: "}, StreamError: &httpx.StatusError{StatusCode: 503, Message: "overloaded"}}
+	registerMockProvider("mock-broken-stream", broken)
+	registerMockProvider("mock-auth", NewMockAIClientWithError(&httpx.StatusError{StatusCode: 401, Message: "invalid key"}))
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	cfg := &config.Config{Retry: config.RetrySettings{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+	svc.runGeneration(session, cfg, []string{"mock-broken-stream", "mock-auth"}, "prompt", 1)
+
+	// 已输出内容的流不重试，直接回退；认证错误不可重试
+	assert.Equal(t, []string{"commit-delta", "commit-fallback", "commit-error"}, recorder.Names())
+	assert.Equal(t, 1, broken.Calls())
+}
+
+func TestCommitService_RunProviderChain_Reasoning(t *testing.T) {
+	thinker := &MockAIClient{Reasoning: []string{"改动新增了", "一个接口"}, Deltas: []string{"feat: ", "add api"}}
+	registerMockProvider("mock-reasoning", thinker)
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	svc.runGeneration(session, &config.Config{}, []string{"mock-reasoning"}, "prompt", 1)
+
+	// 思考过程通过单独的事件发送，不计入消息内容
+	assert.Equal(t, []string{"commit-reasoning", "commit-reasoning", "commit-delta", "commit-delta", "commit-complete"}, recorder.Names())
+	events := recorder.Events()
+	reasoning := events[0].Data.(CommitReasoningEvent)
+	assert.Equal(t, session.SessionInfo, reasoning.SessionInfo)
+	assert.Equal(t, "改动新增了", reasoning.Delta)
+	assert.Equal(t, "feat: add api", events[len(events)-1].Data.(CommitResult).Message)
+}
+
+func TestCommitService_RunProviderChain_Race(t *testing.T) {
+	providerLatencies.reset()
+	t.Cleanup(providerLatencies.reset)
+	slow := &MockAIClient{Response: "feat: slow", WaitForCancel: true}
+	fast := NewMockAIClient("feat: fast", nil)
+	unused := NewMockAIClient("feat: unused", nil)
+	registerMockProvider("mock-race-slow", slow)
+	registerMockProvider("mock-race-fast", fast)
+	registerMockProvider("mock-race-down", NewMockAIClientWithError(errors.New("503 service unavailable")))
+	registerMockProvider("mock-race-extra", NewMockAIClient("feat: extra", nil))
+	registerMockProvider("mock-race-unused", unused)
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	session.race = true
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.runGeneration(session, &config.Config{}, []string{"mock-race-slow", "mock-race-down", "mock-race-fast", "mock-race-unused"}, "prompt", 1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("竞速应在最快的 provider 返回后结束，不等待慢的 provider")
+	}
+
+	// 竞速时不流式输出，只发送最终结果
+	assert.Equal(t, []string{"commit-complete"}, recorder.Names())
+	result := recorder.Events()[0].Data.(CommitResult)
+	assert.Equal(t, "feat: fast", result.Message)
+	assert.Equal(t, "mock-race-fast", result.Provider)
+	assert.Equal(t, 0, unused.Calls(), "只同时请求前 MaxRaceProviders 个 provider")
+
+	latency, ok := providerLatencies.get("mock-race-fast")
+	require.True(t, ok)
+	assert.Equal(t, 1, latency.Samples)
+	// 被取消的请求以已等待的时间作为下限记录
+	require.Eventually(t, func() bool {
+		_, ok := providerLatencies.get("mock-race-slow")
+		return ok
+	}, time.Second, 10*time.Millisecond, "被取消的请求也记录耗时")
+	slowLatency, _ := providerLatencies.get("mock-race-slow")
+	assert.Equal(t, 1, slowLatency.Samples)
+}
+
+func TestCommitService_RunProviderChain_RaceFallsBack(t *testing.T) {
+	registerMockProvider("mock-race-down-1", NewMockAIClientWithError(errors.New("503 service unavailable")))
+	registerMockProvider("mock-race-down-2", NewMockAIClientWithError(errors.New("invalid key")))
+	registerMockProvider("mock-race-backup", NewMockAIClient("", []string{"fix: ", "backup"}))
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	session.race = true
+	chain := []string{"mock-race-down-1", "mock-race-down-2", "mock-race-down-1", "mock-race-backup"}
+	svc.runGeneration(session, &config.Config{}, chain, "prompt", 1)
+
+	// 竞速的 provider 全部失败后，依次尝试调用链中其余的 provider
+	assert.Equal(t, []string{"commit-fallback", "commit-delta", "commit-delta", "commit-complete"}, recorder.Names())
+	events := recorder.Events()
+	assert.Equal(t, "mock-race-backup", events[0].Data.(CommitFallbackEvent).NextProvider)
+	assert.Equal(t, "fix: backup", events[len(events)-1].Data.(CommitResult).Message)
+}
+
+func TestCommitService_RunProviderChain_Structured(t *testing.T) {
+	registerMockProvider("mock-prose", NewMockAIClient("Here is your commit: add login", nil))
+	registerMockProvider("mock-json", NewMockAIClient("```json\n"+`{"type":"feat","scope":"auth","subject":"add login","body":["- add form","validate input"],"breakingChange":"","footers":[{"token":"Refs","value":"#12"}]}`+"\n```", nil))
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	session.structured = true
+	svc.runGeneration(session, &config.Config{}, []string{"mock-prose", "mock-json"}, "prompt", 1)
+
+	// 无法解析为 JSON 的输出视为失败并回退；结构化模式不流式输出
+	assert.Equal(t, []string{"commit-fallback", "commit-complete"}, recorder.Names())
+
+	events := recorder.Events()
+	result := events[len(events)-1].Data.(CommitResult)
+	require.NotNil(t, result.Structured)
+	assert.Equal(t, "auth", result.Structured.Scope)
+	assert.Equal(t, []string{"add form", "validate input"}, result.Structured.Body)
+	assert.Equal(t, "feat(auth): add login\n\n- add form\n- validate input\n\nRefs: #12", result.Message)
+}
+
+func TestCommitService_GenerationCache_Structured(t *testing.T) {
+	svc, _ := newRecordingCommitService()
+	svc.SetCache(newMemoryGenerationCache())
+
+	cfg := &config.Config{Provider: "mock-cache", Language: "english"}
+	key := generationCacheKey(cfg, []string{"mock-cache"}, "template", prompt.CommitPromptData{}, "diff")
+	structured := &aicommitai.StructuredCommit{Type: "fix", Subject: "handle nil config", Body: []string{"guard LoadConfig"}}
+	svc.storeCache(cfg, key, &CommitResult{Message: structured.Render(), Provider: "mock-json", Structured: structured})
+
+	info := SessionInfo{SessionID: newSessionID(), ProjectPath: "/test/project"}
+	result := svc.lookupCache(info, cfg, key, 1, true)
+	require.NotNil(t, result)
+	assert.Equal(t, "fix: handle nil config\n\n- guard LoadConfig", result.Message)
+	require.NotNil(t, result.Structured)
+	assert.Equal(t, "fix", result.Structured.Type)
+}
+
+func TestCommitService_CancelGeneration(t *testing.T) {
+	registerMockProvider("mock-hang", &MockAIClient{Deltas: []string{"feat: "}, WaitForCancel: true})
+	registerMockProvider("mock-after-hang", NewMockAIClient("", []string{"should not run"}))
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer svc.endSession(session)
+		svc.runGeneration(session, &config.Config{}, []string{"mock-hang", "mock-after-hang"}, "prompt", 1)
+	}()
+
+	require.Eventually(t, func() bool { return len(recorder.Events()) > 0 }, time.Second, 10*time.Millisecond)
+	assert.True(t, svc.IsGenerating("/test/project"))
+
+	assert.True(t, svc.CancelGeneration("/test/project"))
+	<-done
+
+	// 取消后不回退、不报错，只有 commit-cancelled
+	assert.Equal(t, []string{"commit-delta", "commit-cancelled"}, recorder.Names())
+	assert.Equal(t, session.SessionInfo, recorder.Events()[1].Data)
+	assert.False(t, svc.IsGenerating("/test/project"))
+	assert.False(t, svc.CancelGeneration("/test/project"))
+}
+
+func TestCommitService_StartSessionReplacesPrevious(t *testing.T) {
+	svc, recorder := newRecordingCommitService()
+
+	first := newTestSession(svc, "/test/project")
+	assert.Empty(t, recorder.Events())
+	second := newTestSession(svc, "/test/project")
+
+	assert.Error(t, first.ctx.Err(), "旧会话应被取消")
+	assert.NoError(t, second.ctx.Err())
+	require.Equal(t, []string{"commit-cancelled"}, recorder.Names())
+	assert.Equal(t, first.SessionInfo, recorder.Events()[0].Data, "commit-cancelled 携带旧会话的 ID")
+
+	// 旧会话结束不影响新会话
+	svc.endSession(first)
+	assert.True(t, svc.IsGenerating("/test/project"))
+
+	svc.endSession(second)
+	assert.False(t, svc.IsGenerating("/test/project"))
+}
+
+func TestCommitService_ConcurrentSessionsAcrossProjects(t *testing.T) {
+	registerMockProvider("mock-project-a", NewMockAIClient("", []string{"feat: ", "a"}))
+	registerMockProvider("mock-project-b", NewMockAIClient("", []string{"fix: ", "b"}))
+
+	svc, recorder := newRecordingCommitService()
+	sessionA := newTestSession(svc, "/test/project-a")
+	sessionB := newTestSession(svc, "/test/project-b")
+	assert.NotEqual(t, sessionA.SessionID, sessionB.SessionID)
+	assert.True(t, svc.IsGenerating("/test/project-a"))
+	assert.True(t, svc.IsGenerating("/test/project-b"))
+
+	var wg sync.WaitGroup
+	for _, run := range []struct {
+		session  *generationSession
+		provider string
+	}{{sessionA, "mock-project-a"}, {sessionB, "mock-project-b"}} {
+		wg.Add(1)
+		go func(session *generationSession, provider string) {
+			defer wg.Done()
+			defer svc.endSession(session)
+			svc.runGeneration(session, &config.Config{}, []string{provider}, "prompt", 1)
+		}(run.session, run.provider)
+	}
+	wg.Wait()
+
+	// 按会话重组输出，互不串扰
+	messages := map[string]string{}
+	results := map[string]CommitResult{}
+	for _, e := range recorder.Events() {
+		switch data := e.Data.(type) {
+		case CommitDeltaEvent:
+			messages[data.SessionID] += data.Delta
+		case CommitResult:
+			results[data.SessionID] = data
+		}
+	}
+	assert.Equal(t, "feat: a", messages[sessionA.SessionID])
+	assert.Equal(t, "fix: b", messages[sessionB.SessionID])
+	assert.Equal(t, "/test/project-a", results[sessionA.SessionID].ProjectPath)
+	assert.Equal(t, "/test/project-b", results[sessionB.SessionID].ProjectPath)
+}
+
+func TestCommitService_RunGeneration_MultipleCandidates(t *testing.T) {
+	var calls atomic.Int32
+	registry.Register("mock-candidates", func(ctx context.Context, n string, ps aicommitconfig.ProviderSettings) (aicommitai.AIClient, error) {
+		// 第二次调用失败，其余成功
+		if calls.Add(1) == 2 {
+			return NewMockAIClientWithError(errors.New("503 service unavailable")), nil
+		}
+		return NewMockAIClient("", []string{"feat: candidate"}), nil
+	})
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	svc.runGeneration(session, &config.Config{}, []string{"mock-candidates"}, "prompt", 3)
+
+	var candidateEvents []CommitCandidateEvent
+	deltaIndexes := map[int]bool{}
+	var result *CommitResult
+	for _, e := range recorder.Events() {
+		switch data := e.Data.(type) {
+		case CommitDeltaEvent:
+			deltaIndexes[data.Index] = true
+		case CommitCandidateEvent:
+			candidateEvents = append(candidateEvents, data)
+		case CommitResult:
+			r := data
+			result = &r
+		}
+	}
+
+	assert.Len(t, candidateEvents, 3)
+	assert.Len(t, deltaIndexes, 2, "每个成功的候选以自己的 index 输出")
+
+	failed := 0
+	for _, e := range candidateEvents {
+		if e.Error != "" {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+
+	require.NotNil(t, result)
+	assert.Len(t, result.Candidates, 2)
+	assert.Equal(t, result.Candidates[0].Message, result.Message)
+	assert.Less(t, result.Candidates[0].Index, result.Candidates[1].Index)
+}
+
+func TestCommitService_RunGeneration_RecordsUsage(t *testing.T) {
+	client := NewMockAIClient("", []string{"feat: usage"})
+	client.Usage = &aicommitai.Usage{PromptTokens: 120, CompletionTokens: 30}
+	registerMockProvider("mock-usage", client)
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	cfg := &config.Config{Providers: map[string]config.ProviderSettings{"mock-usage": {Model: "mock-model"}}}
+	svc.runGeneration(session, cfg, []string{"mock-usage"}, "prompt", 2)
+
+	events := recorder.Events()
+	result := events[len(events)-1].Data.(CommitResult)
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, "mock-usage", result.Usage.Provider)
+	assert.Equal(t, "mock-model", result.Usage.Model)
+	assert.Equal(t, 240, result.Usage.PromptTokens, "多候选的用量应累加")
+	assert.Equal(t, 60, result.Usage.CompletionTokens)
+
+	for _, c := range result.Candidates {
+		require.NotNil(t, c.Usage)
+		assert.Equal(t, 150, c.Usage.TotalTokens())
+	}
+}
+
+func TestCommitService_GenerationParams(t *testing.T) {
+	var got aicommitconfig.GenerationParams
+	registry.Register("mock-params", func(ctx context.Context, n string, ps aicommitconfig.ProviderSettings) (aicommitai.AIClient, error) {
+		got = ps.Params
+		return NewMockAIClient("", []string{"feat: params"}), nil
+	})
+	registry.SetCapabilities("mock-params", registry.Capabilities{Params: registry.ParamLimits{MaxTemperature: 1, SystemPrompt: true}})
+	t.Cleanup(func() { registry.Unregister("mock-params") })
+
+	f := func(v float64) *float64 { return &v }
+	cfg := &config.Config{Providers: map[string]config.ProviderSettings{
+		"mock-params": {Params: aicommitconfig.GenerationParams{Temperature: f(0.2), SystemPrompt: "全局提示词"}},
+	}}
+	// 项目级参数只覆盖设置了的字段
+	overrideGenerationParams(cfg, []string{"mock-params"}, aicommitconfig.GenerationParams{Temperature: f(0.7), MaxOutputTokens: 300})
+
+	svc, recorder := newRecordingCommitService()
+	svc.runGeneration(newTestSession(svc, "/test/project"), cfg, []string{"mock-params"}, "prompt", 1)
+	assert.Equal(t, "commit-complete", recorder.Names()[len(recorder.Names())-1])
+	require.NotNil(t, got.Temperature)
+	assert.Equal(t, 0.7, *got.Temperature)
+	assert.Equal(t, 300, got.MaxOutputTokens)
+	assert.Equal(t, "全局提示词", got.SystemPrompt)
+
+	// 超出 provider 取值范围的参数在创建 client 前被拒绝
+	overrideGenerationParams(cfg, []string{"mock-params"}, aicommitconfig.GenerationParams{Temperature: f(1.5)})
+	svc, recorder = newRecordingCommitService()
+	svc.runGeneration(newTestSession(svc, "/test/project"), cfg, []string{"mock-params"}, "prompt", 1)
+	events := recorder.Events()
+	last := events[len(events)-1]
+	require.Equal(t, "commit-error", last.Name)
+	assert.Contains(t, last.Data.(CommitErrorEvent).Error, "temperature up to 1")
+}
+
+func TestCommitService_GenerationParams_Timeout(t *testing.T) {
+	registerMockProvider("mock-slow", &MockAIClient{WaitForCancel: true})
+
+	svc, recorder := newRecordingCommitService()
+	cfg := &config.Config{
+		Providers: map[string]config.ProviderSettings{"mock-slow": {Params: aicommitconfig.GenerationParams{Timeout: 20 * time.Millisecond}}},
+		Retry:     config.RetrySettings{MaxAttempts: 1},
+	}
+	done := make(chan struct{})
+	go func() {
+		svc.runGeneration(newTestSession(svc, "/test/project"), cfg, []string{"mock-slow"}, "prompt", 1)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("请求超时后生成应结束")
+	}
+	assert.Equal(t, []string{"commit-error"}, recorder.Names())
+}
+
+// memoryGenerationCache 是内存中的 GenerationCache 实现
+type memoryGenerationCache struct {
+	mu      sync.Mutex
+	entries map[string]*models.GenerationCache
+	evicts  int
+}
+
+func newMemoryGenerationCache() *memoryGenerationCache {
+	return &memoryGenerationCache{entries: make(map[string]*models.GenerationCache)}
+}
+
+func (c *memoryGenerationCache) Get(key string, notBefore time.Time) (*models.GenerationCache, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !entry.CreatedAt.After(notBefore) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (c *memoryGenerationCache) Put(entry *models.GenerationCache) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.CreatedAt = time.Now()
+	c.entries[entry.Key] = entry
+	return nil
+}
+
+func (c *memoryGenerationCache) Evict(notBefore time.Time, maxEntries int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evicts++
+	return nil
+}
+
+func TestCommitService_GenerationCache(t *testing.T) {
+	cache := newMemoryGenerationCache()
+	svc, recorder := newRecordingCommitService()
+	svc.SetCache(cache)
+
+	newConfig := func() *config.Config {
+		return &config.Config{Provider: "mock-cache", Language: "english", Providers: map[string]config.ProviderSettings{
+			"mock-cache":  {Model: "cache-model"},
+			"mock-backup": {Model: "backup-model"},
+		}}
+	}
+	chain := []string{"mock-cache", "mock-backup"}
+	keyWith := func(edit func(cfg *config.Config)) string {
+		cfg := newConfig()
+		edit(cfg)
+		return generationCacheKey(cfg, chain, "template", prompt.CommitPromptData{}, "diff")
+	}
+	withParams := func(name string, params aicommitconfig.GenerationParams) func(cfg *config.Config) {
+		return func(cfg *config.Config) {
+			ps := cfg.Providers[name]
+			ps.Params = params
+			cfg.Providers[name] = ps
+		}
+	}
+	cfg := newConfig()
+	key := generationCacheKey(cfg, chain, "template", prompt.CommitPromptData{}, "diff")
+	assert.NotEqual(t, key, keyWith(func(cfg *config.Config) { cfg.Language = "chinese" }))
+	assert.NotEqual(t, key, keyWith(withParams("mock-cache", aicommitconfig.GenerationParams{SystemPrompt: "简短"})))
+	assert.Equal(t, key, keyWith(withParams("mock-cache", aicommitconfig.GenerationParams{Timeout: time.Minute})), "超时不影响缓存键")
+	assert.NotEqual(t, key, keyWith(func(cfg *config.Config) {
+		cfg.Providers["mock-backup"] = config.ProviderSettings{Model: "backup-model-2"}
+	}), "回退 provider 的模型计入缓存键")
+	assert.NotEqual(t, key, generationCacheKey(cfg, chain[:1], "template", prompt.CommitPromptData{}, "diff"), "调用链计入缓存键")
+	assert.NotEqual(t, key, generationCacheKey(cfg, chain, "template", prompt.CommitPromptData{Branch: "feature/PROJ-1"}, "diff"), "分支等项目信息计入缓存键")
+
+	info := SessionInfo{SessionID: newSessionID(), ProjectPath: "/test/project"}
+	assert.Nil(t, svc.lookupCache(info, cfg, key, 1, false))
+
+	svc.storeCache(cfg, key, &CommitResult{
+		Message:  "feat: a",
+		Provider: "mock-backup",
+		Candidates: []CommitCandidate{
+			{Index: 0, Message: "feat: a", Provider: "mock-backup"},
+			{Index: 1, Message: "feat: b", Provider: "mock-backup"},
+		},
+	})
+	assert.Equal(t, 1, cache.evicts)
+	assert.Equal(t, "backup-model", cache.entries[key].Model, "记录实际生成消息的 provider 的模型")
+
+	result := svc.lookupCache(info, cfg, key, 2, false)
+	require.NotNil(t, result)
+	assert.True(t, result.Cached)
+	assert.Equal(t, info, result.SessionInfo)
+	assert.Equal(t, "feat: a", result.Message)
+	assert.Equal(t, "mock-backup", result.Provider)
+	assert.Len(t, result.Candidates, 2)
+
+	single := svc.lookupCache(info, cfg, key, 1, false)
+	require.NotNil(t, single)
+	assert.Empty(t, single.Candidates)
+
+	assert.Nil(t, svc.lookupCache(info, cfg, key, 3, false), "缓存的候选数量不足时视为未命中")
+
+	cfg.Cache.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, svc.lookupCache(info, cfg, key, 1, false), "过期缓存不应命中")
+
+	cfg.Cache = config.CacheSettings{Disabled: true}
+	assert.Nil(t, svc.lookupCache(info, cfg, key, 1, false))
+	assert.Empty(t, recorder.Events())
+}
@@ -0,0 +1,588 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// StoredAPIKey 是保存在加密存储中的 API Key 信息（不包含 key 本身）
+type StoredAPIKey struct {
+	Provider  string    `json:"provider"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// 加密存储在进程内只打开一次：解锁数据密钥较慢（DPAPI / PBKDF2），且所有写入都经过同一个实例
+var (
+	secretStoresMu sync.Mutex
+	secretStores   = map[string]*secret.Store{}
+)
+
+// appConfigDir 返回配置目录（~/.ai-commit-hub），不存在时创建
+func appConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	dir := filepath.Join(homeDir, ".ai-commit-hub")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return dir, nil
+}
+
+// openSecretStore 打开配置目录下的加密存储，设置了 secret.PassphraseEnv 时使用口令保护，否则绑定本机用户
+func openSecretStore(dir string) (*secret.Store, error) {
+	path := filepath.Join(dir, secret.FileName)
+	secretStoresMu.Lock()
+	defer secretStoresMu.Unlock()
+	if store, ok := secretStores[path]; ok {
+		return store, nil
+	}
+	protector := secret.DefaultProtector()
+	store, err := secret.Open(path, protector)
+	if errors.Is(err, secret.ErrProtectionMismatch) && protector.Name() != secret.Machine().Name() {
+		// 新设置了口令：用本机密钥打开原有的存储，再改为口令保护
+		if store, err = secret.Open(path, secret.Machine()); err == nil {
+			err = store.Rekey(protector)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	secretStores[path] = store
+	return store, nil
+}
+
+// customSecretID 是 customProviders 中自定义 provider 的 API Key 在加密存储中的 ID，
+// 与 providers 中同名条目的 ID 区分
+func customSecretID(name string) string {
+	return "custom/" + name
+}
+
+// apiKeySecretID 是 apiKeys 列表中第 index 个 key（从 0 开始）迁移到加密存储时优先使用的 ID
+func apiKeySecretID(provider string, index int) string {
+	return provider + "#" + strconv.Itoa(index+1)
+}
+
+// freeSecretID 返回不在 taken 中的 ID：id 已被使用时依次尝试 <provider>#1、<provider>#2 ……
+func freeSecretID(id string, taken map[string]bool) string {
+	if !taken[id] {
+		return id
+	}
+	base, _, _ := strings.Cut(id, "#")
+	for n := 0; ; n++ {
+		if candidate := apiKeySecretID(base, n); !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// isPlaintextSecret 判断配置中的值是否为需要迁移到加密存储的明文 key，
+// 加密存储、环境变量和文件引用都不需要迁移
+func isPlaintextSecret(value string) bool {
+	if value == "" || config.IsRef(value) {
+		return false
+	}
+	_, isRef := secret.ParseRef(value)
+	return !isRef
+}
+
+// resolveConfigRefs 解析 providers 和 customProviders 中所有字段的 ${ENV_VAR} 和 file: 引用。
+// 在解码为 config.Config 之前处理 YAML 节点，因此数值字段（如 params.temperature）同样可以使用引用。
+// 返回无法解析引用的 provider 及原因，这些值被置空。
+func resolveConfigRefs(dir string, doc *yaml.Node) map[string]string {
+	unresolved := make(map[string]string)
+	if len(doc.Content) == 0 {
+		return unresolved
+	}
+	root := doc.Content[0]
+	record := func(name string, err error) {
+		if err == nil {
+			return
+		}
+		logger.Warnf("Provider %s 的配置引用无法解析: %v", name, err)
+		if _, exists := unresolved[name]; !exists {
+			unresolved[name] = refErrorReason(err)
+		}
+	}
+
+	if providers := mappingValue(root, "providers"); providers != nil && providers.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(providers.Content); i += 2 {
+			record(providers.Content[i].Value, config.ResolveNodeRefs(providers.Content[i+1], dir))
+		}
+	}
+	if customs := mappingValue(root, "customProviders"); customs != nil && customs.Kind == yaml.SequenceNode {
+		for _, item := range customs.Content {
+			err := config.ResolveNodeRefs(item, dir)
+			if name := mappingValue(item, "name"); name != nil {
+				record(name.Value, err)
+			}
+		}
+	}
+	return unresolved
+}
+
+// refErrorReason 返回引用无法解析的原因，显示在 provider 的配置状态中
+func refErrorReason(err error) string {
+	var refErr *config.RefError
+	if errors.As(err, &refErr) {
+		if refErr.Env != "" {
+			return fmt.Sprintf("环境变量 %s 未设置", refErr.Env)
+		}
+		return fmt.Sprintf("无法读取文件 %s", refErr.File)
+	}
+	return err.Error()
+}
+
+// resolveSecretRefs 将配置中 secret:<id> 形式的 API Key 替换为加密存储中的值。
+// 无法解析时清空该 key 并在 cfg.UnresolvedRefs 中记录原因，不影响其它配置。
+func resolveSecretRefs(dir string, cfg *config.Config) {
+	var store *secret.Store
+	var storeErr error
+	resolve := func(name, value string) string {
+		id, ok := secret.ParseRef(value)
+		if !ok {
+			return value
+		}
+		if store == nil && storeErr == nil {
+			store, storeErr = openSecretStore(dir)
+		}
+		reason := ""
+		key := ""
+		if storeErr != nil {
+			logger.Warnf("无法打开 API Key 加密存储: %v", storeErr)
+			reason = "无法打开 API Key 加密存储"
+		} else if v, err := store.Get(id); err != nil {
+			logger.Warnf("无法读取 API Key %s: %v", id, err)
+			reason = fmt.Sprintf("加密存储中没有 API Key %s", id)
+		} else {
+			key = v
+		}
+		if reason != "" {
+			if cfg.UnresolvedRefs == nil {
+				cfg.UnresolvedRefs = make(map[string]string)
+			}
+			if _, exists := cfg.UnresolvedRefs[name]; !exists {
+				cfg.UnresolvedRefs[name] = reason
+			}
+		}
+		return key
+	}
+
+	for name, ps := range cfg.Providers {
+		ps.APIKey = resolve(name, ps.APIKey)
+		if len(ps.APIKeys) > 0 {
+			keys := make([]config.APIKeyEntry, len(ps.APIKeys))
+			for i, k := range ps.APIKeys {
+				k.Key = resolve(name, k.Key)
+				keys[i] = k
+			}
+			ps.APIKeys = keys
+		}
+		cfg.Providers[name] = ps
+	}
+	for i := range cfg.CustomProviders {
+		cfg.CustomProviders[i].APIKey = resolve(cfg.CustomProviders[i].Name, cfg.CustomProviders[i].APIKey)
+	}
+}
+
+// MigratePlaintextAPIKeys 将 config.yaml 中明文保存的 API Key 移入加密存储，
+// 并在配置文件中替换为 secret:<id> 引用。返回迁移的 key 数量，没有明文 key 时不修改文件。
+func (s *ConfigService) MigratePlaintextAPIKeys() (int, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	err = editConfigFile(dir, func(root *yaml.Node) (bool, error) {
+		var store *secret.Store
+		// 已被配置引用或已保存在存储中的 ID 不能再使用，否则会覆盖其它条目的 key
+		var taken map[string]bool
+		migrate := func(keyNode *yaml.Node, id string) error {
+			if keyNode == nil || !isPlaintextSecret(keyNode.Value) {
+				return nil
+			}
+			if store == nil {
+				var err error
+				if store, err = openSecretStore(dir); err != nil {
+					return err
+				}
+				taken = configSecretRefs(root)
+				for _, id := range store.IDs() {
+					taken[id] = true
+				}
+			}
+			id = freeSecretID(id, taken)
+			taken[id] = true
+			if err := store.Set(id, keyNode.Value); err != nil {
+				return err
+			}
+			keyNode.Value = secret.Ref(id)
+			keyNode.Style = 0
+			migrated++
+			return nil
+		}
+
+		if providers := mappingValue(root, "providers"); providers != nil && providers.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(providers.Content); i += 2 {
+				name := providers.Content[i].Value
+				if err := migrate(mappingValue(providers.Content[i+1], "apiKey"), name); err != nil {
+					return false, err
+				}
+				if keys := mappingValue(providers.Content[i+1], "apiKeys"); keys != nil && keys.Kind == yaml.SequenceNode {
+					for j, item := range keys.Content {
+						if err := migrate(mappingValue(item, "key"), apiKeySecretID(name, j)); err != nil {
+							return false, err
+						}
+					}
+				}
+			}
+		}
+		if customs := mappingValue(root, "customProviders"); customs != nil && customs.Kind == yaml.SequenceNode {
+			for _, item := range customs.Content {
+				name := mappingValue(item, "name")
+				if name == nil {
+					continue
+				}
+				if err := migrate(mappingValue(item, "apiKey"), customSecretID(name.Value)); err != nil {
+					return false, err
+				}
+			}
+		}
+		return migrated > 0, nil
+	})
+	if err != nil {
+		return migrated, fmt.Errorf("迁移 API Key 失败: %w", err)
+	}
+	if migrated > 0 {
+		logger.Infof("已将 %d 个明文 API Key 迁移到加密存储", migrated)
+	}
+	return migrated, nil
+}
+
+// SetAPIKey 将 provider 的 API Key 保存到加密存储，并在 config.yaml 中引用它。
+// provider 也可以是 apiKeys 中单个 key 的 ID（provider#N）或自定义 provider 的 ID（custom/<name>）。
+func (s *ConfigService) SetAPIKey(provider, apiKey string) error {
+	if provider == "" || apiKey == "" {
+		return errors.New("provider 和 API Key 不能为空")
+	}
+	if err := validateSecretID(provider); err != nil {
+		return err
+	}
+	dir, err := appConfigDir()
+	if err != nil {
+		return err
+	}
+	store, err := openSecretStore(dir)
+	if err != nil {
+		return fmt.Errorf("打开 API Key 加密存储失败: %w", err)
+	}
+	if err := store.Set(provider, apiKey); err != nil {
+		return fmt.Errorf("保存 API Key 失败: %w", err)
+	}
+	return setSecretRef(dir, provider)
+}
+
+// RotateAPIKey 用新的 key 替换已保存的 API Key，并使用新的数据密钥重新加密整个存储
+func (s *ConfigService) RotateAPIKey(provider, apiKey string) error {
+	if apiKey == "" {
+		return errors.New("API Key 不能为空")
+	}
+	dir, err := appConfigDir()
+	if err != nil {
+		return err
+	}
+	store, err := openSecretStore(dir)
+	if err != nil {
+		return fmt.Errorf("打开 API Key 加密存储失败: %w", err)
+	}
+	if _, ok := store.Entry(provider); !ok {
+		return fmt.Errorf("provider %s 尚未保存 API Key", provider)
+	}
+	if err := store.Set(provider, apiKey); err != nil {
+		return fmt.Errorf("保存 API Key 失败: %w", err)
+	}
+	if err := store.Rekey(secret.DefaultProtector()); err != nil {
+		return fmt.Errorf("重新加密 API Key 存储失败: %w", err)
+	}
+	return setSecretRef(dir, provider)
+}
+
+// DeleteAPIKey 从加密存储中删除 provider 的所有 API Key（apiKey 和 apiKeys 中的 key），
+// 并移除 config.yaml 中对它们的引用。provider 为 apiKeys 中单个 key 的 ID（provider#N）时只删除该 key，
+// 为 custom/<name> 时删除自定义 provider 的 key。
+func (s *ConfigService) DeleteAPIKey(provider string) error {
+	dir, err := appConfigDir()
+	if err != nil {
+		return err
+	}
+	store, err := openSecretStore(dir)
+	if err != nil {
+		return fmt.Errorf("打开 API Key 加密存储失败: %w", err)
+	}
+	refs := make(map[string]bool)
+	for _, id := range store.IDs() {
+		if id != provider && !strings.HasPrefix(id, provider+"#") {
+			continue
+		}
+		if err := store.Delete(id); err != nil {
+			return fmt.Errorf("删除 API Key 失败: %w", err)
+		}
+		refs[secret.Ref(id)] = true
+	}
+	// 配置文件中的引用即使在存储中已不存在也一并移除
+	refs[secret.Ref(provider)] = true
+	return removeSecretRefs(dir, refs)
+}
+
+// StoredAPIKeys 返回加密存储中保存了 API Key 的 provider
+func (s *ConfigService) StoredAPIKeys() ([]StoredAPIKey, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	store, err := openSecretStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("打开 API Key 加密存储失败: %w", err)
+	}
+	var keys []StoredAPIKey
+	for _, id := range store.IDs() {
+		entry, _ := store.Entry(id)
+		keys = append(keys, StoredAPIKey{Provider: id, UpdatedAt: entry.UpdatedAt})
+	}
+	return keys, nil
+}
+
+// validateSecretID 检查 provider#N 形式的 ID 中 N 是否为正整数
+func validateSecretID(id string) error {
+	if strings.HasPrefix(id, customSecretID("")) {
+		return nil
+	}
+	if _, index, ok := strings.Cut(id, "#"); ok {
+		if n, err := strconv.Atoi(index); err != nil || n < 1 {
+			return fmt.Errorf("无效的 API Key ID: %s", id)
+		}
+	}
+	return nil
+}
+
+// setSecretRef 在 config.yaml 中引用加密存储中的 id，配置中已引用该 id 时不修改文件：
+//   - provider 设置 providers.<provider>.apiKey
+//   - provider#N 设置 providers.<provider>.apiKeys 中第 N 个 key，N 比现有数量大 1 时追加
+//   - custom/<name> 设置 customProviders 中同名条目的 apiKey
+func setSecretRef(dir, id string) error {
+	ref := secret.Ref(id)
+	return editConfigFile(dir, func(root *yaml.Node) (bool, error) {
+		if configSecretRefs(root)[id] {
+			return false, nil
+		}
+		if name, ok := strings.CutPrefix(id, customSecretID("")); ok {
+			item := customProviderNode(root, name)
+			if item == nil {
+				return false, fmt.Errorf("自定义 provider %s 不存在", name)
+			}
+			return setScalar(item, "apiKey", ref), nil
+		}
+		name, index, isItem := strings.Cut(id, "#")
+		settings := ensureMapping(ensureMapping(root, "providers"), name)
+		if !isItem {
+			return setScalar(settings, "apiKey", ref), nil
+		}
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 1 {
+			return false, fmt.Errorf("无效的 API Key ID: %s", id)
+		}
+		keys := mappingValue(settings, "apiKeys")
+		if keys == nil || keys.Kind != yaml.SequenceNode {
+			removeMappingKey(settings, "apiKeys")
+			keys = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			settings.Content = append(settings.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiKeys"}, keys)
+		}
+		switch {
+		case n <= len(keys.Content):
+			return setScalar(keys.Content[n-1], "key", ref), nil
+		case n == len(keys.Content)+1:
+			item := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setScalar(item, "key", ref)
+			keys.Content = append(keys.Content, item)
+			return true, nil
+		default:
+			return false, fmt.Errorf("provider %s 的 apiKeys 只有 %d 个 key，无法设置 %s", name, len(keys.Content), id)
+		}
+	})
+}
+
+// removeSecretRefs 删除 config.yaml 中 providers 和 customProviders 下引用 refs 的 apiKey 和 apiKeys 条目，
+// 不删除用户填写的其它值。apiKeys 清空后删除该字段。
+func removeSecretRefs(dir string, refs map[string]bool) error {
+	return editConfigFile(dir, func(root *yaml.Node) (bool, error) {
+		changed := false
+		removeKey := func(node *yaml.Node) {
+			if key := mappingValue(node, "apiKey"); key != nil && refs[key.Value] {
+				removeMappingKey(node, "apiKey")
+				changed = true
+			}
+		}
+		if providers := mappingValue(root, "providers"); providers != nil && providers.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(providers.Content); i += 2 {
+				settings := providers.Content[i+1]
+				removeKey(settings)
+				keys := mappingValue(settings, "apiKeys")
+				if keys == nil || keys.Kind != yaml.SequenceNode {
+					continue
+				}
+				kept := keys.Content[:0]
+				for _, item := range keys.Content {
+					if key := mappingValue(item, "key"); key != nil && refs[key.Value] {
+						changed = true
+						continue
+					}
+					kept = append(kept, item)
+				}
+				keys.Content = kept
+				if len(kept) == 0 {
+					removeMappingKey(settings, "apiKeys")
+				}
+			}
+		}
+		if customs := mappingValue(root, "customProviders"); customs != nil && customs.Kind == yaml.SequenceNode {
+			for _, item := range customs.Content {
+				removeKey(item)
+			}
+		}
+		return changed, nil
+	})
+}
+
+// configSecretRefs 返回 config.yaml 中 apiKey 和 apiKeys 引用的加密存储 ID
+func configSecretRefs(root *yaml.Node) map[string]bool {
+	ids := make(map[string]bool)
+	add := func(node *yaml.Node) {
+		if node == nil {
+			return
+		}
+		if id, ok := secret.ParseRef(node.Value); ok {
+			ids[id] = true
+		}
+	}
+	if providers := mappingValue(root, "providers"); providers != nil && providers.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(providers.Content); i += 2 {
+			add(mappingValue(providers.Content[i+1], "apiKey"))
+			if keys := mappingValue(providers.Content[i+1], "apiKeys"); keys != nil && keys.Kind == yaml.SequenceNode {
+				for _, item := range keys.Content {
+					add(mappingValue(item, "key"))
+				}
+			}
+		}
+	}
+	if customs := mappingValue(root, "customProviders"); customs != nil && customs.Kind == yaml.SequenceNode {
+		for _, item := range customs.Content {
+			add(mappingValue(item, "apiKey"))
+		}
+	}
+	return ids
+}
+
+// customProviderNode 返回 customProviders 中名为 name 的条目，不存在时返回 nil
+func customProviderNode(root *yaml.Node, name string) *yaml.Node {
+	customs := mappingValue(root, "customProviders")
+	if customs == nil || customs.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, item := range customs.Content {
+		if n := mappingValue(item, "name"); n != nil && n.Value == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// setScalar 将映射节点 node 中 key 的值设置为 value，返回是否修改
+func setScalar(node *yaml.Node, key, value string) bool {
+	current := mappingValue(node, key)
+	if current == nil {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+		return true
+	}
+	if current.Kind != yaml.ScalarNode {
+		*current = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+	} else if current.Value == value {
+		return false
+	}
+	current.Value, current.Tag, current.Style = value, "!!str", 0
+	return true
+}
+
+// editConfigFile 以 yaml.Node 读取 config.yaml，由 edit 修改后写回，保留注释和字段顺序。
+// edit 返回 false 时不写入文件。
+func editConfigFile(dir string, edit func(root *yaml.Node) (bool, error)) error {
+	path := filepath.Join(dir, "config.yaml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	changed, err := edit(doc.Content[0])
+	if err != nil || !changed {
+		return err
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// mappingValue 返回 YAML 映射节点中 key 对应的值节点，不存在时返回 nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// ensureMapping 返回 node 中 key 对应的映射节点，不存在或为空值时创建
+func ensureMapping(node *yaml.Node, key string) *yaml.Node {
+	if value := mappingValue(node, key); value != nil {
+		if value.Kind != yaml.MappingNode {
+			*value = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		return value
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
+
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
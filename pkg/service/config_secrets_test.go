@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupConfigHome 在临时 HOME 下写入 config.yaml，返回配置文件路径
+func setupConfigHome(t *testing.T, configYAML string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	dir := filepath.Join(home, ".ai-commit-hub")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(configYAML), 0644))
+	return path
+}
+
+func TestConfigService_MigratePlaintextAPIKeys(t *testing.T) {
+	path := setupConfigHome(t, `provider: openai
+providers:
+    # 主力 provider
+    openai:
+        apiKey: sk-openai-plain
+        apiKeys:
+            - key: sk-openai-team
+              label: team
+        model: gpt-4o-mini
+    ollama:
+        baseURL: http://localhost:11434
+customProviders:
+    - name: vllm
+      baseURL: http://localhost:8000/v1
+      apiKey: sk-vllm-plain
+`)
+	svc := NewConfigService()
+
+	migrated, err := svc.MigratePlaintextAPIKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content := string(data)
+	assert.NotContains(t, content, "sk-openai-plain")
+	assert.NotContains(t, content, "sk-vllm-plain")
+	assert.NotContains(t, content, "sk-openai-team")
+	assert.Contains(t, content, "apiKey: secret:openai")
+	assert.Contains(t, content, "# 主力 provider", "迁移时保留配置文件中的注释")
+
+	cfg, err := svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sk-openai-plain", cfg.Providers["openai"].APIKey)
+	assert.Equal(t, []config.APIKeyEntry{{Key: "sk-openai-team", Label: "team"}}, cfg.Providers["openai"].APIKeys)
+	assert.Equal(t, "sk-vllm-plain", cfg.CustomProviders[0].APIKey)
+
+	// 已迁移的配置不再修改
+	migrated, err = svc.MigratePlaintextAPIKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
+
+func TestConfigService_MigratePlaintextAPIKeys_KeepsExistingSecrets(t *testing.T) {
+	path := setupConfigHome(t, `provider: openai
+providers:
+    openai:
+        apiKeys:
+            - key: sk-first
+`)
+	svc := NewConfigService()
+	migrated, err := svc.MigratePlaintextAPIKeys()
+	require.NoError(t, err)
+	require.Equal(t, 1, migrated)
+
+	// 在已迁移的 key 前后加入新的明文 key，按位置生成的 openai#1 已被占用
+	require.NoError(t, os.WriteFile(path, []byte(`provider: openai
+providers:
+    openai:
+        apiKey: sk-main
+        apiKeys:
+            - key: sk-new
+            - key: secret:openai#1
+            - key: sk-third
+`), 0644))
+	migrated, err = svc.MigratePlaintextAPIKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+
+	cfg, err := svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sk-main", cfg.Providers["openai"].APIKey)
+	assert.Equal(t, []config.APIKeyEntry{{Key: "sk-new"}, {Key: "sk-first"}, {Key: "sk-third"}}, cfg.Providers["openai"].APIKeys)
+	assert.Empty(t, cfg.UnresolvedRefs)
+
+	keys, err := svc.StoredAPIKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 4)
+}
+
+func TestConfigService_SetRotateDeleteAPIKey(t *testing.T) {
+	path := setupConfigHome(t, "provider: deepseek\n")
+	svc := NewConfigService()
+
+	assert.Error(t, svc.RotateAPIKey("deepseek", "sk-new"), "未保存过的 key 不能轮换")
+	require.NoError(t, svc.SetAPIKey("deepseek", "sk-first"))
+	require.NoError(t, svc.RotateAPIKey("deepseek", "sk-second"))
+
+	cfg, err := svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sk-second", cfg.Providers["deepseek"].APIKey)
+
+	keys, err := svc.StoredAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "deepseek", keys[0].Provider)
+
+	require.NoError(t, svc.DeleteAPIKey("deepseek"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret:deepseek")
+
+	cfg, err = svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Providers["deepseek"].APIKey)
+}
+
+func TestConfigService_SetAPIKey_ItemAndCustomIDs(t *testing.T) {
+	path := setupConfigHome(t, `provider: openai
+providers:
+    openai:
+        apiKey: sk-main
+        apiKeys:
+            - key: sk-team
+              label: team
+customProviders:
+    - name: vllm
+      baseURL: http://localhost:8000/v1
+`)
+	svc := NewConfigService()
+
+	// provider#N 对应 apiKeys 中第 N 个 key，N 比现有数量大 1 时追加
+	require.NoError(t, svc.SetAPIKey("openai#1", "sk-team-new"))
+	require.NoError(t, svc.SetAPIKey("openai#2", "sk-extra"))
+	require.NoError(t, svc.RotateAPIKey("openai#2", "sk-extra-rotated"))
+	assert.Error(t, svc.SetAPIKey("openai#5", "sk-gap"), "不能跳过 apiKeys 中的位置")
+	require.NoError(t, svc.SetAPIKey("custom/vllm", "sk-vllm"))
+	require.NoError(t, svc.RotateAPIKey("custom/vllm", "sk-vllm-rotated"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content := string(data)
+	assert.NotContains(t, content, `"openai#`, "不为 key ID 创建单独的 provider")
+	assert.NotContains(t, content, "custom/vllm:")
+	assert.Contains(t, content, "label: team", "保留 key 的其它字段")
+
+	cfg, err := svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sk-main", cfg.Providers["openai"].APIKey)
+	assert.Equal(t, []config.APIKeyEntry{{Key: "sk-team-new", Label: "team"}, {Key: "sk-extra-rotated"}}, cfg.Providers["openai"].APIKeys)
+	require.Len(t, cfg.CustomProviders, 1)
+	assert.Equal(t, "sk-vllm-rotated", cfg.CustomProviders[0].APIKey)
+	assert.Empty(t, cfg.UnresolvedRefs)
+
+	require.NoError(t, svc.DeleteAPIKey("custom/vllm"))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret:custom/vllm")
+	assert.Contains(t, string(data), "name: vllm", "只删除自定义 provider 的 key")
+	cfg, err = svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, cfg.CustomProviders[0].APIKey)
+	assert.Empty(t, cfg.UnresolvedRefs)
+}
+
+func TestConfigService_DeleteAPIKey_RemovesRotatedKeys(t *testing.T) {
+	path := setupConfigHome(t, `provider: openai
+providers:
+    openai:
+        apiKey: sk-main
+        apiKeys:
+            - key: sk-team
+              label: team
+            - key: ${OPENAI_EXTRA_KEY}
+        model: gpt-4o-mini
+    openai-proxy:
+        apiKey: sk-proxy
+`)
+	t.Setenv("OPENAI_EXTRA_KEY", "sk-extra")
+	svc := NewConfigService()
+	migrated, err := svc.MigratePlaintextAPIKeys()
+	require.NoError(t, err)
+	require.Equal(t, 3, migrated)
+
+	// 单独删除 apiKeys 中的一个 key
+	require.NoError(t, svc.DeleteAPIKey("openai#1"))
+	cfg, err := svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sk-main", cfg.Providers["openai"].APIKey)
+	assert.Equal(t, []config.APIKeyEntry{{Key: "sk-extra"}}, cfg.Providers["openai"].APIKeys)
+	assert.Empty(t, cfg.UnresolvedRefs)
+
+	require.NoError(t, svc.DeleteAPIKey("openai"))
+	keys, err := svc.StoredAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "openai#N 中轮换使用的 key 也被删除，不影响其它 provider")
+	assert.Equal(t, "openai-proxy", keys[0].Provider)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret:openai\n")
+	assert.NotContains(t, string(data), "secret:openai#")
+	assert.NotContains(t, string(data), "label: team")
+	assert.Contains(t, string(data), "${OPENAI_EXTRA_KEY}", "不是加密存储引用的 key 保留")
+	assert.Contains(t, string(data), "secret:openai-proxy")
+}
+
+func TestConfigService_LoadConfig_ResolvesReferences(t *testing.T) {
+	setupConfigHome(t, `provider: openai
+providers:
+    openai:
+        apiKey: ${TEST_REF_OPENAI_KEY}
+        params:
+            temperature: ${TEST_REF_TEMPERATURE}
+    azure:
+        apiKey: file:keys/azure.txt
+        baseURL: https://${TEST_REF_AZURE_RESOURCE}.openai.azure.com
+    deepseek:
+        apiKey: ${TEST_REF_MISSING}
+customProviders:
+    - name: ref-vllm
+      baseURL: http://localhost:8000/v1
+      apiKey: file:missing.txt
+      requiresAPIKey: true
+`)
+	t.Setenv("TEST_REF_OPENAI_KEY", "sk-from-env")
+	t.Setenv("TEST_REF_TEMPERATURE", "0.3")
+	t.Setenv("TEST_REF_AZURE_RESOURCE", "contoso")
+	home, _ := os.UserHomeDir()
+	keyPath := filepath.Join(home, ".ai-commit-hub", "keys", "azure.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(keyPath), 0755))
+	require.NoError(t, os.WriteFile(keyPath, []byte("sk-from-file\n"), 0600))
+
+	svc := NewConfigService()
+	cfg, err := svc.LoadConfig(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { svc.applyCustomProviders(&config.Config{}) })
+
+	assert.Equal(t, "sk-from-env", cfg.Providers["openai"].APIKey)
+	require.NotNil(t, cfg.Providers["openai"].Params.Temperature, "数值字段同样可以使用引用")
+	assert.Equal(t, 0.3, *cfg.Providers["openai"].Params.Temperature)
+	assert.Equal(t, "sk-from-file", cfg.Providers["azure"].APIKey)
+	assert.Equal(t, "https://contoso.openai.azure.com", cfg.Providers["azure"].BaseURL)
+	assert.Empty(t, cfg.Providers["deepseek"].APIKey)
+
+	infos := map[string]models.ProviderInfo{}
+	for _, info := range svc.GetConfiguredProviders(cfg) {
+		infos[info.Name] = info
+	}
+	assert.True(t, infos["openai"].Configured)
+	assert.False(t, infos["deepseek"].Configured)
+	assert.Equal(t, "环境变量 TEST_REF_MISSING 未设置", infos["deepseek"].Reason)
+	assert.Equal(t, "无法读取文件 missing.txt", infos["ref-vllm"].Reason)
+
+	// 引用不是明文 key，不迁移到加密存储
+	migrated, err := svc.MigratePlaintextAPIKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
@@ -6,10 +6,15 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/committypes"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 	"github.com/allanpk716/ai-commit-hub/pkg/config"
 	"github.com/allanpk716/ai-commit-hub/pkg/models"
 	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/anthropic"
+	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/azure"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/custom"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/deepseek"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/google"
 	_ "github.com/allanpk716/ai-commit-hub/pkg/provider/ollama"
@@ -26,15 +31,13 @@ func NewConfigService() *ConfigService {
 	return &ConfigService{}
 }
 
+// LoadConfig 读取 ~/.ai-commit-hub/config.yaml，不存在时创建默认配置。
+// provider 设置中的 ${ENV_VAR}、file: 引用和 secret:<id> 形式的 API Key 在此解析，
+// 无法解析的记录在 cfg.UnresolvedRefs 中。
 func (s *ConfigService) LoadConfig(ctx context.Context) (*config.Config, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := appConfigDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	configDir := filepath.Join(homeDir, ".ai-commit-hub")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+		return nil, err
 	}
 
 	configPath := filepath.Join(configDir, "config.yaml")
@@ -46,6 +49,7 @@ func (s *ConfigService) LoadConfig(ctx context.Context) (*config.Config, error)
 		if err := s.saveConfig(configPath, defaultCfg); err != nil {
 			return nil, fmt.Errorf("failed to create default config: %w", err)
 		}
+		applyCommitTypes(defaultCfg)
 		return defaultCfg, nil
 	}
 
@@ -55,14 +59,66 @@ func (s *ConfigService) LoadConfig(ctx context.Context) (*config.Config, error)
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg config.Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	unresolved := resolveConfigRefs(configDir, &doc)
 
+	var cfg config.Config
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if len(unresolved) > 0 {
+		cfg.UnresolvedRefs = unresolved
+	}
+
+	resolveSecretRefs(configDir, &cfg)
+	s.applyCustomProviders(&cfg)
+	applyCommitTypes(&cfg)
 	return &cfg, nil
 }
 
+// applyCommitTypes 将配置的 commit 类型同步到 committypes，用于校验和清理生成的消息。
+// 未配置时 committypes 使用内置的 Conventional Commits 类型。
+func applyCommitTypes(cfg *config.Config) {
+	types := make([]aicommitconfig.CommitTypeConfig, 0, len(cfg.CommitTypes))
+	for _, t := range cfg.CommitTypes {
+		types = append(types, aicommitconfig.CommitTypeConfig{Type: t.Type, Emoji: t.Emoji})
+	}
+	committypes.InitCommitTypes(types)
+}
+
+// applyCustomProviders 注册配置中声明的自定义 provider，并将其设置合并到 Providers，
+// 使其与内置 provider 一样参与配置检查和生成。Providers 中已填写的字段优先。
+func (s *ConfigService) applyCustomProviders(cfg *config.Config) {
+	if err := custom.Sync(cfg.CustomProviders); err != nil {
+		logger.Warnf("部分自定义 provider 注册失败: %v", err)
+	}
+
+	for _, p := range cfg.CustomProviders {
+		if !custom.IsCustom(p.Name) {
+			continue
+		}
+		if cfg.Providers == nil {
+			cfg.Providers = make(map[string]config.ProviderSettings)
+		}
+		ps := cfg.Providers[p.Name]
+		if ps.APIKey == "" {
+			ps.APIKey = p.APIKey
+		}
+		if ps.Model == "" {
+			ps.Model = p.Model
+		}
+		if ps.BaseURL == "" {
+			ps.BaseURL = p.BaseURL
+		}
+		cfg.Providers[p.Name] = ps
+	}
+}
+
 func (s *ConfigService) getDefaultConfig() *config.Config {
 	return &config.Config{
 		Provider:      "openai",
@@ -124,6 +180,12 @@ func (s *ConfigService) getDefaultConfig() *config.Config {
 				Model:   "phind-34b-v2",
 				BaseURL: "",
 			},
+			"azure": {
+				APIKey:     "",
+				Model:      "",
+				BaseURL:    "",
+				APIVersion: "2024-10-21",
+			},
 		},
 		Prompts: config.PromptFiles{
 			CommitMessage: "commit-message.txt",
@@ -160,7 +222,8 @@ func (s *ConfigService) ResolvePromptTemplate(configDir, configFile string) (str
 	return string(content), nil
 }
 
-// GetConfiguredProviders 返回所有支持的 providers 及其配置状态
+// GetConfiguredProviders 返回所有支持的 providers 及其配置状态。
+// 已生成过消息的 provider 附带耗时统计，并按平均耗时从快到慢排在前面。
 func (s *ConfigService) GetConfiguredProviders(cfg *config.Config) []models.ProviderInfo {
 	// 获取所有已注册的 providers
 	registeredProviders := registry.Names()
@@ -169,7 +232,11 @@ func (s *ConfigService) GetConfiguredProviders(cfg *config.Config) []models.Prov
 
 	for _, name := range registeredProviders {
 		info := models.ProviderInfo{
-			Name: name,
+			Name:         name,
+			Capabilities: providerCapabilities(name),
+		}
+		if latency, ok := providerLatencies.get(name); ok {
+			info.Latency = &latency
 		}
 
 		// 检查该 provider 是否在 config 中配置
@@ -188,6 +255,14 @@ func (s *ConfigService) GetConfiguredProviders(cfg *config.Config) []models.Prov
 			continue
 		}
 
+		// 环境变量、文件或加密存储中的引用无法解析
+		if reason, ok := cfg.UnresolvedRefs[name]; ok {
+			info.Configured = false
+			info.Reason = reason
+			result = append(result, info)
+			continue
+		}
+
 		// 检查是否需要 API Key
 		requiresKey := registry.RequiresAPIKey(name)
 
@@ -195,7 +270,7 @@ func (s *ConfigService) GetConfiguredProviders(cfg *config.Config) []models.Prov
 		var reason string
 		configured := true
 
-		if requiresKey && providerSettings.APIKey == "" {
+		if requiresKey && len(providerSettings.Keys()) == 0 {
 			configured = false
 			reason = "缺少 API Key"
 		}
@@ -208,5 +283,25 @@ func (s *ConfigService) GetConfiguredProviders(cfg *config.Config) []models.Prov
 		result = append(result, info)
 	}
 
+	rankByLatency(result)
 	return result
 }
+
+// providerCapabilities 返回 provider 在注册表中声明的能力
+func providerCapabilities(name string) models.ProviderCapabilities {
+	caps, _ := registry.GetCapabilities(name)
+	return models.ProviderCapabilities{
+		Streaming:     caps.Streaming,
+		ContextTokens: caps.ContextTokens,
+		JSONMode:      caps.JSONMode,
+		Reasoning:     caps.Reasoning,
+		Local:         caps.Local,
+		Models:        caps.Models,
+		Params: models.ProviderParamLimits{
+			MaxTemperature:  caps.Params.MaxTemperature,
+			TopP:            caps.Params.TopP,
+			MaxOutputTokens: caps.Params.MaxOutputTokens,
+			SystemPrompt:    caps.Params.SystemPrompt,
+		},
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,3 +79,35 @@ func TestConfigService_ResolvePromptTemplate_NotFound(t *testing.T) {
 	assert.Error(t, err)
 	assert.Empty(t, template)
 }
+
+func TestConfigService_ApplyCustomProviders(t *testing.T) {
+	service := NewConfigService()
+	cfg := &config.Config{
+		CustomProviders: []config.CustomProvider{
+			{Name: "litellm", BaseURL: "http://localhost:4000", Model: "gpt-4o-mini", RequiresAPIKey: true},
+			{Name: "lm-studio", BaseURL: "http://localhost:1234/v1", Model: "qwen2.5-coder"},
+		},
+		Providers: map[string]config.ProviderSettings{
+			"litellm": {Model: "claude-3-haiku"},
+		},
+	}
+	service.applyCustomProviders(cfg)
+	t.Cleanup(func() { service.applyCustomProviders(&config.Config{}) })
+
+	assert.Equal(t, "claude-3-haiku", cfg.Providers["litellm"].Model, "providers 中的设置优先")
+	assert.Equal(t, "http://localhost:4000", cfg.Providers["litellm"].BaseURL)
+
+	infos := map[string]models.ProviderInfo{}
+	for _, info := range service.GetConfiguredProviders(cfg) {
+		infos[info.Name] = info
+	}
+	assert.False(t, infos["litellm"].Configured, "需要 API Key 的自定义 provider 缺少 key 时未配置")
+	assert.True(t, infos["lm-studio"].Configured)
+	assert.Equal(t, models.ProviderCapabilities{
+		Streaming: true,
+		JSONMode:  true,
+		Local:     true,
+		Models:    []string{"qwen2.5-coder"},
+		Params:    models.ProviderParamLimits{MaxTemperature: 2, TopP: true, SystemPrompt: true},
+	}, infos["lm-studio"].Capabilities)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/git"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+)
+
+// CommitDiffTrimmedEvent 是 commit-diff-trimmed 事件的数据，说明按 lockFiles 和 limits 配置从 diff 中裁掉的内容
+type CommitDiffTrimmedEvent struct {
+	SessionInfo
+	git.DiffTrimReport
+}
+
+// applyDiffLimits 按配置裁剪 diff：先移除 lockFiles 中的文件（diff 只包含锁文件时保留），
+// 超出 Limits.Diff 时优先省略锁文件、生成文件和 vendor 目录等低价值文件；
+// 仍然超出时，trim 策略按文件公平分配预算裁剪，summarize 策略留给分块总结处理。
+func applyDiffLimits(cfg *config.Config, diff string) (string, git.DiffTrimReport) {
+	report := git.DiffTrimReport{OriginalChars: len(diff), FinalChars: len(diff)}
+
+	if len(cfg.LockFiles) > 0 {
+		files := git.SplitDiffByFile(diff)
+		var lockFiles []string
+		for _, f := range files {
+			if git.IsLockFile(f.Path, cfg.LockFiles) {
+				lockFiles = append(lockFiles, f.Path)
+			}
+		}
+		if len(lockFiles) > 0 && len(lockFiles) < len(files) {
+			diff = git.FilterLockFiles(diff, cfg.LockFiles)
+			report.OmittedFiles = lockFiles
+		}
+	}
+
+	limit := cfg.Limits.Diff
+	if limit.Enabled && limit.MaxChars > 0 && len(diff) > limit.MaxChars {
+		var r git.DiffTrimReport
+		if limit.Strategy == config.LimitStrategyTrim {
+			diff, r = git.TrimDiffToBudget(diff, limit.MaxChars)
+		} else {
+			diff, r = git.OmitLowSignalFiles(diff, limit.MaxChars)
+		}
+		report.OmittedFiles = append(report.OmittedFiles, r.OmittedFiles...)
+		report.TruncatedFiles = r.TruncatedFiles
+	}
+
+	report.FinalChars = len(diff)
+	return diff, report
+}
+
+// maxPromptTrimAttempts 是裁剪 diff 使 prompt 满足 Limits.Prompt 的最多尝试次数
+const maxPromptTrimAttempts = 3
+
+// buildLimitedPrompt 用 data 中的项目信息渲染 commit prompt 模板，超出 Limits.Prompt 时裁剪其中的 diff 使 prompt 不超过限制，
+// 裁剪内容合并到 report 中。模板不是有效的 text/template 时按旧方式替换占位符。
+// summarized 表示 diff 是分块总结的结果而不是原始 diff，此时按行截断，不按文件分配预算。
+func buildLimitedPrompt(cfg *config.Config, diff, promptTemplate string, summarized bool, data prompt.CommitPromptData, report git.DiffTrimReport) (string, git.DiffTrimReport) {
+	data.Language = cfg.Language
+	render := func(diff string) string {
+		data.Diff = diff
+		promptText, _ := prompt.ExpandCommitPrompt(promptTemplate, data)
+		return promptText
+	}
+	data.Diff = diff
+	promptText, err := prompt.ExpandCommitPrompt(promptTemplate, data)
+	if err != nil {
+		// 旧模板可能包含字面的 {{（例如 JSON 示例），此时已按旧方式替换 {DIFF} 等占位符
+		logger.Warnf("Prompt 模板不是有效的 text/template，按旧方式替换占位符: %v", err)
+	}
+	limit := cfg.Limits.Prompt
+	if !limit.Enabled || limit.MaxChars <= 0 || len(promptText) <= limit.MaxChars {
+		return promptText, report
+	}
+
+	// 模板可能多次引用 diff、不引用或只在条件中引用，用空 diff 渲染得到模板本身的长度，再估算 diff 出现的次数
+	overhead := len(render(""))
+	copies := 0
+	if len(diff) > 0 && len(promptText) > overhead {
+		copies = max((len(promptText)-overhead)/len(diff), 1)
+	}
+	budget := 0
+	if copies > 0 {
+		budget = (limit.MaxChars - overhead) / copies
+	}
+	if budget <= 0 {
+		logger.Warnf("Prompt 模板长度 %d 已超出限制 %d，无法通过裁剪 diff 满足限制", overhead, limit.MaxChars)
+		return promptText, report
+	}
+
+	logger.Infof("Prompt 长度 %d 超出限制 %d，裁剪 diff", len(promptText), limit.MaxChars)
+	var trimmed string
+	var r git.DiffTrimReport
+	for attempt := 1; ; attempt++ {
+		if summarized {
+			trimmed = git.TruncateText(diff, budget)
+		} else {
+			trimmed, r = git.TrimDiffToBudget(diff, budget)
+		}
+		promptText = render(trimmed)
+		excess := len(promptText) - limit.MaxChars
+		if excess <= 0 || attempt == maxPromptTrimAttempts {
+			break
+		}
+		// 条件中引用 diff 时估算偏小，按超出的长度继续缩小预算
+		budget -= (excess + copies - 1) / copies
+		if budget <= 0 {
+			break
+		}
+	}
+	if len(promptText) > limit.MaxChars {
+		logger.Warnf("裁剪 diff 后 prompt 长度 %d 仍超出限制 %d", len(promptText), limit.MaxChars)
+	}
+
+	if summarized {
+		logger.Infof("Diff 总结已截断: %d -> %d 字符", len(diff), len(trimmed))
+		return promptText, report
+	}
+	report.FinalChars -= r.OriginalChars - r.FinalChars
+	report.OmittedFiles = appendMissing(report.OmittedFiles, r.OmittedFiles...)
+	report.TruncatedFiles = appendMissing(report.TruncatedFiles, r.TruncatedFiles...)
+	return promptText, report
+}
+
+// appendMissing 将 list 中还没有的元素追加到 list
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		if !containsString(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/git"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDiffLimits_LockFiles(t *testing.T) {
+	diff := buildTestDiff(1, 5) + strings.Replace(buildTestDiff(1, 5), "pkg/file0.go", "go.sum", -1)
+	cfg := &config.Config{LockFiles: []string{"go.sum"}}
+
+	trimmed, report := applyDiffLimits(cfg, diff)
+	assert.NotContains(t, trimmed, "go.sum")
+	assert.Equal(t, []string{"go.sum"}, report.OmittedFiles)
+	assert.True(t, report.Trimmed())
+
+	// 只有锁文件变更时保留
+	lockOnly := strings.Replace(buildTestDiff(1, 5), "pkg/file0.go", "go.sum", -1)
+	trimmed, report = applyDiffLimits(cfg, lockOnly)
+	assert.Equal(t, lockOnly, trimmed)
+	assert.False(t, report.Trimmed())
+}
+
+func TestApplyDiffLimits_Strategy(t *testing.T) {
+	diff := buildTestDiff(4, 50)
+	cfg := &config.Config{Limits: config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 2000}}}
+
+	// summarize 策略（默认）不截断文件，留给分块总结
+	trimmed, report := applyDiffLimits(cfg, diff)
+	assert.Equal(t, diff, trimmed)
+	assert.Empty(t, report.TruncatedFiles)
+	assert.True(t, needsDiffSummary(cfg, trimmed, "mock"))
+
+	cfg.Limits.Diff.Strategy = config.LimitStrategyTrim
+	trimmed, report = applyDiffLimits(cfg, diff)
+	assert.LessOrEqual(t, len(trimmed), 2000)
+	assert.Len(t, report.TruncatedFiles, 4)
+	assert.False(t, needsDiffSummary(cfg, trimmed, "mock"))
+}
+
+func TestBuildLimitedPrompt(t *testing.T) {
+	diff := buildTestDiff(3, 40)
+	full := prompt.BuildCommitPrompt(diff, "english", "", "", prompt.DefaultPromptTemplate)
+
+	cfg := &config.Config{Language: "english"}
+	promptText, report := buildLimitedPrompt(cfg, diff, prompt.DefaultPromptTemplate, false, prompt.CommitPromptData{}, applyDiffLimitsReport(diff))
+	assert.Equal(t, full, promptText)
+	assert.False(t, report.Trimmed())
+
+	cfg.Limits.Prompt = config.LimitSettings{Enabled: true, MaxChars: len(full) / 2}
+	promptText, report = buildLimitedPrompt(cfg, diff, prompt.DefaultPromptTemplate, false, prompt.CommitPromptData{}, applyDiffLimitsReport(diff))
+	assert.LessOrEqual(t, len(promptText), len(full)/2)
+	assert.Len(t, report.TruncatedFiles, 3)
+	assert.Less(t, report.FinalChars, report.OriginalChars)
+}
+
+func TestBuildLimitedPrompt_TemplateDiffReferences(t *testing.T) {
+	diff := buildTestDiff(3, 40)
+	cfg := &config.Config{Limits: config.Limits{Prompt: config.LimitSettings{Enabled: true, MaxChars: len(diff)}}}
+
+	// 模板引用两次 diff 时每份只能使用一半预算
+	promptText, report := buildLimitedPrompt(cfg, diff, "A\n{{.Diff}}\nB\n{{.Diff}}", false, prompt.CommitPromptData{}, applyDiffLimitsReport(diff))
+	assert.LessOrEqual(t, len(promptText), len(diff))
+	assert.Len(t, report.TruncatedFiles, 3)
+
+	// 只在条件中引用 diff 时，条件中的文本也计入长度
+	promptText, _ = buildLimitedPrompt(cfg, diff, "{{if .Diff}}"+strings.Repeat("x", 200)+"\n{{.Diff}}{{end}}", false, prompt.CommitPromptData{}, applyDiffLimitsReport(diff))
+	assert.LessOrEqual(t, len(promptText), len(diff))
+	assert.Contains(t, promptText, "diff --git")
+
+	// 模板不引用 diff 时无法裁剪
+	long := strings.Repeat("y", len(diff)+1)
+	promptText, report = buildLimitedPrompt(cfg, diff, long, false, prompt.CommitPromptData{}, applyDiffLimitsReport(diff))
+	assert.Equal(t, long, promptText)
+	assert.False(t, report.Trimmed())
+}
+
+func TestBuildLimitedPrompt_Summary(t *testing.T) {
+	summary := strings.Repeat("- pkg/file.go: 调整了配置加载逻辑\n", 50)
+	cfg := &config.Config{Limits: config.Limits{Prompt: config.LimitSettings{Enabled: true, MaxChars: len(summary) / 2}}}
+
+	promptText, report := buildLimitedPrompt(cfg, summary, "Summary:\n{{.Diff}}", true, prompt.CommitPromptData{}, git.DiffTrimReport{OriginalChars: 10000, FinalChars: 10000})
+	assert.LessOrEqual(t, len(promptText), len(summary)/2)
+	assert.True(t, strings.HasPrefix(promptText, "Summary:\n- pkg/file.go"))
+	assert.Contains(t, promptText, "[... truncated ...]")
+	assert.Empty(t, report.TruncatedFiles, "总结不按文件裁剪")
+}
+
+func TestCommitService_GenerateFromDiff_ReportsTrimmedDiff(t *testing.T) {
+	client := NewMockAIClient("", []string{"feat: trimmed"})
+	registerMockProvider("mock-trim", client)
+
+	diff := buildTestDiff(3, 60)
+	cfg := &config.Config{
+		Limits:    config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 2000, Strategy: config.LimitStrategyTrim}},
+		Providers: map[string]config.ProviderSettings{"mock-trim": {}},
+	}
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	result := svc.generateFromDiff(session, cfg, []string{"mock-trim"}, diff, prompt.DefaultPromptTemplate, 1)
+	require.NotNil(t, result)
+
+	events := recorder.Events()
+	require.Equal(t, "commit-diff-trimmed", events[0].Name)
+	trimmed := events[0].Data.(CommitDiffTrimmedEvent)
+	assert.Equal(t, session.SessionInfo, trimmed.SessionInfo)
+	assert.Equal(t, len(diff), trimmed.OriginalChars)
+	assert.LessOrEqual(t, trimmed.FinalChars, 2000)
+	assert.Len(t, trimmed.TruncatedFiles, 3)
+}
+
+// applyDiffLimitsReport 返回未裁剪 diff 的初始报告
+func applyDiffLimitsReport(diff string) git.DiffTrimReport {
+	_, report := applyDiffLimits(&config.Config{}, diff)
+	return report
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/git"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+const (
+	// charsPerToken 是估算 token 数的字符换算比例
+	charsPerToken = 4
+	// minSummaryChunkChars 是分块大小下限，避免上下文配置过小时产生大量请求
+	minSummaryChunkChars = 2000
+	// summaryConcurrency 是并行总结分块的最大请求数
+	summaryConcurrency = 4
+)
+
+// CommitSummaryProgressEvent 是 commit-summarizing 事件的数据，表示超大 diff 分块总结的进度
+type CommitSummaryProgressEvent struct {
+	SessionInfo
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// diffSummaryChunk 是送给 provider 总结的一段 diff，包含一个或多个文件的 hunk
+type diffSummaryChunk struct {
+	Files []string
+	Text  string
+}
+
+// needsDiffSummary 判断 diff 是否需要分块总结（trim 策略下不总结）：
+// diff 超出 Limits.Diff，或超出 provider 上下文窗口可用于 diff 的部分（见 contextChars）。
+func needsDiffSummary(cfg *config.Config, diff, provider string) bool {
+	limit := cfg.Limits.Diff
+	if limit.Strategy == config.LimitStrategyTrim {
+		return false
+	}
+	if limit.Enabled && limit.MaxChars > 0 && len(diff) > limit.MaxChars {
+		return true
+	}
+	chars := contextChars(cfg, provider)
+	return chars > 0 && len(diff) > chars
+}
+
+// contextChars 按 provider 的上下文窗口估算可用于 diff 的字符数（预留一半给 prompt 模板和输出），未知时为 0。
+// 上下文窗口优先使用配置的 ContextTokens，其次使用注册表中声明的能力。
+func contextChars(cfg *config.Config, provider string) int {
+	tokens := cfg.Providers[provider].ContextTokens
+	if tokens <= 0 {
+		caps, _ := registry.GetCapabilities(provider)
+		tokens = caps.ContextTokens
+	}
+	return tokens * charsPerToken / 2
+}
+
+// summaryChunkChars 返回每个分块的字符预算。
+// 已知 provider 的上下文窗口时按其估算，否则使用 Limits.Diff.MaxChars。
+func summaryChunkChars(cfg *config.Config, provider string) int {
+	budget := cfg.Limits.Diff.MaxChars
+	if chars := contextChars(cfg, provider); chars > 0 {
+		budget = chars
+	}
+	if budget < minSummaryChunkChars {
+		budget = minSummaryChunkChars
+	}
+	return budget
+}
+
+// splitDiffForSummary 按文件和 hunk 将 diff 切分为不超过 maxChars 的分块。
+// 同一分块内可包含多个小文件；大文件按 hunk 拆到多个分块，单个超大 hunk 会被截断。
+func splitDiffForSummary(diff string, maxChars int) []diffSummaryChunk {
+	hunks, _ := git.ParseDiffToChunks(diff)
+
+	var chunks []diffSummaryChunk
+	var current diffSummaryChunk
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() == 0 {
+			return
+		}
+		current.Text = sb.String()
+		chunks = append(chunks, current)
+		current = diffSummaryChunk{}
+		sb.Reset()
+	}
+
+	for _, hunk := range hunks {
+		text := truncateHunk(hunk.HunkHeader+"\n"+strings.Join(hunk.Lines, "\n")+"\n", maxChars)
+		fileHeader := ""
+		if len(current.Files) == 0 || current.Files[len(current.Files)-1] != hunk.FilePath {
+			fileHeader = fmt.Sprintf("File: %s\n", hunk.FilePath)
+		}
+		if sb.Len() > 0 && sb.Len()+len(fileHeader)+len(text) > maxChars {
+			flush()
+			fileHeader = fmt.Sprintf("File: %s\n", hunk.FilePath)
+		}
+		if fileHeader != "" {
+			current.Files = append(current.Files, hunk.FilePath)
+			sb.WriteString(fileHeader)
+		}
+		sb.WriteString(text)
+	}
+	flush()
+	return chunks
+}
+
+// truncateHunk 将超出 maxChars 的 hunk 在行边界处截断
+func truncateHunk(text string, maxChars int) string {
+	if len(text) <= maxChars {
+		return text
+	}
+	cut := text[:maxChars]
+	if idx := strings.LastIndex(cut, "\n"); idx > 0 {
+		cut = cut[:idx+1]
+	}
+	return cut + "[... hunk truncated ...]\n"
+}
+
+// summarizeDiff 执行 map 阶段：将 diff 分块后由 provider 并行总结，发送 commit-summarizing 进度事件。
+// 每个分块依次尝试 chain 中的 provider；任一分块全部失败时返回错误。
+// 返回按原顺序拼接的总结文本和总结消耗的 token 用量。
+func (s *CommitService) summarizeDiff(session *generationSession, cfg *config.Config, chain []string, diff string) (string, *ai.Usage, error) {
+	chunks := splitDiffForSummary(diff, summaryChunkChars(cfg, chain[0]))
+	if len(chunks) == 0 {
+		return "", nil, fmt.Errorf("无法解析 diff 中的文件变更")
+	}
+	logger.Infof("Diff 已分为 %d 块进行总结", len(chunks))
+
+	total := len(chunks)
+	summaries := make([]string, total)
+	usages := make([]*ai.Usage, total)
+	errs := make([]error, total)
+
+	var mu sync.Mutex
+	done := 0
+	s.emit("commit-summarizing", CommitSummaryProgressEvent{SessionInfo: session.SessionInfo, Total: total})
+
+	sem := make(chan struct{}, summaryConcurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(index int, chunk diffSummaryChunk) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if session.ctx.Err() != nil {
+				return
+			}
+
+			summaryPrompt := prompt.BuildDiffChunkSummaryPrompt(chunk.Text, index, total)
+			summaries[index], usages[index], errs[index] = s.summarizeChunk(session, cfg, chain, summaryPrompt)
+			if errs[index] != nil || session.ctx.Err() != nil {
+				return
+			}
+
+			mu.Lock()
+			done++
+			progress := CommitSummaryProgressEvent{SessionInfo: session.SessionInfo, Done: done, Total: total}
+			mu.Unlock()
+			logger.Infof("分块 %d/%d 总结完成，文件: %v", index+1, total, chunk.Files)
+			s.emit("commit-summarizing", progress)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if err := session.ctx.Err(); err != nil {
+		return "", nil, err
+	}
+
+	var usage *ai.Usage
+	for i := range chunks {
+		if errs[i] != nil {
+			return "", nil, fmt.Errorf("分块 %d/%d: %w", i+1, total, errs[i])
+		}
+		usage = mergeUsage(usage, usages[i])
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[The full diff is too large and has been summarized per file]\n")
+	for _, summary := range summaries {
+		sb.WriteString("\n")
+		sb.WriteString(strings.TrimSpace(summary))
+		sb.WriteString("\n")
+	}
+	return sb.String(), usage, nil
+}
+
+// summaryIndex 是总结 diff 分块的请求使用的序号，与候选消息的序号（从 0 开始）区分
+const summaryIndex = -1
+
+// summarizeChunk 依次尝试 chain 中的 provider 总结一个分块（非流式，不输出到前端），并在启用时写入审计日志
+func (s *CommitService) summarizeChunk(session *generationSession, cfg *config.Config, chain []string, summaryPrompt string) (string, *ai.Usage, error) {
+	var lastErr error
+	for _, name := range chain {
+		start := time.Now()
+		summary, usage, err := s.generateWithRetry(session, cfg, name, summaryPrompt, summaryIndex, outputText)
+		s.recordAudit(session, cfg, name, summaryPrompt, summaryIndex, summary, summary, usage, err, time.Since(start))
+		if err == nil || session.ctx.Err() != nil {
+			return summary, usage, err
+		}
+		logger.Warnf("Provider '%s' 总结分块失败: %v", name, err)
+		lastErr = err
+	}
+	return "", nil, lastErr
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	aicommitai "github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/httpx"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestDiff 生成包含 files 个文件、每个文件一个 lines 行 hunk 的 diff
+func buildTestDiff(files, lines int) string {
+	var sb strings.Builder
+	for i := 0; i < files; i++ {
+		path := fmt.Sprintf("pkg/file%d.go", i)
+		fmt.Fprintf(&sb, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -1,%d +1,%d @@\n", path, path, path, path, lines, lines)
+		for j := 0; j < lines; j++ {
+			fmt.Fprintf(&sb, "+line %d of file %d\n", j, i)
+		}
+	}
+	return sb.String()
+}
+
+func TestNeedsDiffSummary(t *testing.T) {
+	diff := buildTestDiff(2, 10)
+	cfg := &config.Config{}
+	assert.False(t, needsDiffSummary(cfg, diff, "mock"), "limits disabled")
+
+	cfg.Limits.Diff = config.LimitSettings{Enabled: true, MaxChars: len(diff)}
+	assert.False(t, needsDiffSummary(cfg, diff, "mock"))
+
+	cfg.Limits.Diff.MaxChars = len(diff) - 1
+	assert.True(t, needsDiffSummary(cfg, diff, "mock"))
+}
+
+func TestNeedsDiffSummary_ContextWindow(t *testing.T) {
+	registry.SetCapabilities("mock-small-context", registry.Capabilities{ContextTokens: 100})
+	t.Cleanup(func() { registry.Unregister("mock-small-context") })
+
+	diff := buildTestDiff(2, 10)
+	require.Greater(t, len(diff), 100*charsPerToken/2)
+	cfg := &config.Config{}
+	assert.True(t, needsDiffSummary(cfg, diff, "mock-small-context"), "diff 超出注册的上下文窗口")
+	assert.Equal(t, 100*charsPerToken/2, contextChars(cfg, "mock-small-context"))
+
+	// 配置的 ContextTokens 优先于注册的能力
+	cfg.Providers = map[string]config.ProviderSettings{"mock-small-context": {ContextTokens: 100000}}
+	assert.False(t, needsDiffSummary(cfg, diff, "mock-small-context"))
+
+	cfg.Limits.Diff.Strategy = config.LimitStrategyTrim
+	cfg.Providers = nil
+	assert.False(t, needsDiffSummary(cfg, diff, "mock-small-context"), "trim 策略不总结")
+}
+
+func TestNeedsDiffSummary_Ollama(t *testing.T) {
+	diff := buildTestDiff(20, 20)
+	require.Greater(t, len(diff), 8*1024)
+
+	// 本地模型的上下文窗口未知，只按配置的 contextTokens 总结
+	cfg := &config.Config{Providers: map[string]config.ProviderSettings{"ollama": {}}}
+	assert.False(t, needsDiffSummary(cfg, diff, "ollama"))
+	cfg.Providers["ollama"] = config.ProviderSettings{ContextTokens: 1024}
+	assert.True(t, needsDiffSummary(cfg, diff, "ollama"))
+}
+
+func TestSummaryChunkChars(t *testing.T) {
+	cfg := &config.Config{
+		Limits:    config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 5000}},
+		Providers: map[string]config.ProviderSettings{"big": {ContextTokens: 128000}},
+	}
+	assert.Equal(t, 5000, summaryChunkChars(cfg, "small"))
+	assert.Equal(t, 128000*charsPerToken/2, summaryChunkChars(cfg, "big"))
+
+	cfg.Limits.Diff.MaxChars = 10
+	assert.Equal(t, minSummaryChunkChars, summaryChunkChars(cfg, "small"))
+}
+
+func TestSplitDiffForSummary(t *testing.T) {
+	diff := buildTestDiff(5, 20)
+	chunks := splitDiffForSummary(diff, 1000)
+	require.Greater(t, len(chunks), 1)
+
+	var files []string
+	for _, c := range chunks {
+		assert.LessOrEqual(t, len(c.Text), 1000)
+		for _, f := range c.Files {
+			assert.Contains(t, c.Text, "File: "+f+"\n")
+		}
+		files = append(files, c.Files...)
+	}
+	// 每个文件都出现在分块中，且按原顺序
+	assert.Equal(t, []string{"pkg/file0.go", "pkg/file1.go", "pkg/file2.go", "pkg/file3.go", "pkg/file4.go"}, files)
+
+	// 超过预算的单个 hunk 会被截断
+	huge := splitDiffForSummary(buildTestDiff(1, 500), 1000)
+	require.Len(t, huge, 1)
+	assert.Contains(t, huge[0].Text, "[... hunk truncated ...]")
+}
+
+func TestCommitService_GenerateFromDiff_SummarizesLargeDiff(t *testing.T) {
+	client := NewMockAIClient("- summarized change", []string{"feat: ", "large change"})
+	client.Usage = &aicommitai.Usage{Model: "mock-model", PromptTokens: 10, CompletionTokens: 2}
+	registerMockProvider("mock-summarizer", client)
+
+	diff := buildTestDiff(6, 80)
+	cfg := &config.Config{
+		Limits:    config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 2500}},
+		Providers: map[string]config.ProviderSettings{"mock-summarizer": {}},
+	}
+	chunks := len(splitDiffForSummary(diff, summaryChunkChars(cfg, "mock-summarizer")))
+	require.Greater(t, chunks, 1)
+
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	result := svc.generateFromDiff(session, cfg, []string{"mock-summarizer"}, diff, prompt.DefaultPromptTemplate, 1)
+	require.NotNil(t, result)
+	assert.Equal(t, "feat: large change", result.Message)
+
+	names := recorder.Names()
+	summarizing := 0
+	for _, name := range names {
+		if name == "commit-summarizing" {
+			summarizing++
+		}
+	}
+	// 开始时一次，每个分块完成时一次
+	assert.Equal(t, chunks+1, summarizing)
+	assert.Equal(t, "commit-complete", names[len(names)-1])
+
+	// map 阶段每块一次请求，最后一次请求使用各块总结而不是原始 diff
+	prompts := client.Prompts()
+	require.Len(t, prompts, chunks+1)
+	final := prompts[len(prompts)-1]
+	assert.Contains(t, final, "- summarized change")
+	assert.NotContains(t, final, "+line 0 of file 0")
+
+	// 总结消耗的用量计入结果
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, 10*(chunks+1), result.Usage.PromptTokens)
+	assert.Equal(t, 2*(chunks+1), result.Usage.CompletionTokens)
+}
+
+func TestCommitService_GenerateFromDiff_SummaryRetryIndex(t *testing.T) {
+	client := NewMockAIClient("- summarized change", []string{"feat: large change"})
+	client.Error = &httpx.StatusError{StatusCode: 429, Message: "rate limited"}
+	client.FailTimes = 1
+	registerMockProvider("mock-summary-retry", client)
+
+	cfg := &config.Config{
+		Limits:    config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 2500}},
+		Providers: map[string]config.ProviderSettings{"mock-summary-retry": {}},
+		Retry:     config.RetrySettings{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	}
+	svc, recorder := newRecordingCommitService()
+	session := newTestSession(svc, "/test/project")
+	result := svc.generateFromDiff(session, cfg, []string{"mock-summary-retry"}, buildTestDiff(6, 80), prompt.DefaultPromptTemplate, 1)
+	require.NotNil(t, result)
+
+	// 总结分块的重试不应显示为第 0 个候选的重试
+	var retries []CommitRetryEvent
+	for _, event := range recorder.Events() {
+		if retry, ok := event.Data.(CommitRetryEvent); ok {
+			retries = append(retries, retry)
+		}
+	}
+	require.Len(t, retries, 1)
+	assert.Equal(t, summaryIndex, retries[0].Index)
+}
+
+func TestCommitService_GenerateFromDiff_SummaryLatencyNotRecorded(t *testing.T) {
+	providerLatencies.reset()
+	t.Cleanup(providerLatencies.reset)
+	registerMockProvider("mock-summary-latency", NewMockAIClient("- summarized change", []string{"feat: large change"}))
+
+	cfg := &config.Config{
+		Limits:    config.Limits{Diff: config.LimitSettings{Enabled: true, MaxChars: 2500}},
+		Providers: map[string]config.ProviderSettings{"mock-summary-latency": {}},
+	}
+	svc, _ := newRecordingCommitService()
+	result := svc.generateFromDiff(newTestSession(svc, "/test/project"), cfg, []string{"mock-summary-latency"}, buildTestDiff(6, 80), prompt.DefaultPromptTemplate, 1)
+	require.NotNil(t, result)
+
+	// 只记录最终生成 commit 消息的请求
+	latency, ok := providerLatencies.get("mock-summary-latency")
+	require.True(t, ok)
+	assert.Equal(t, 1, latency.Samples)
+}
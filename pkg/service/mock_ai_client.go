@@ -2,35 +2,110 @@ package service
 
 import (
 	"context"
+	"sync"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
 )
 
 // MockAIClient 是用于测试的 Mock AI Client
 type MockAIClient struct {
+	ai.BaseAIClient
 	Response string
 	Error    error
 	Deltas   []string
+	// Reasoning 为流式输出前通过 ai.ReportReasoning 上报的思考过程（模拟推理模型）
+	Reasoning []string
+	// WaitForCancel 为 true 时输出 Deltas 后阻塞，直到 ctx 被取消（模拟卡住的流或很慢的 provider）
+	WaitForCancel bool
+	// Usage 非空时在成功返回前通过 ai.ReportUsage 上报
+	Usage *ai.Usage
+	// Models 为 ListModels 返回的模型列表
+	Models []string
+	// FailTimes 大于 0 时只有前 FailTimes 次调用返回 Error，之后正常返回（模拟暂时性错误）
+	FailTimes int
+	// StreamError 非空时在输出 Deltas 后返回该错误（模拟流式输出中途断开）
+	StreamError error
+
+	mu      sync.Mutex
+	calls   int
+	prompts []string
+}
+
+// Calls 返回生成方法被调用的次数
+func (m *MockAIClient) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// Prompts 返回各次调用收到的 prompt
+func (m *MockAIClient) Prompts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.prompts...)
+}
+
+// nextError 记录一次调用并返回本次调用应返回的错误
+func (m *MockAIClient) nextError(prompt string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	m.prompts = append(m.prompts, prompt)
+	if m.FailTimes > 0 && m.calls > m.FailTimes {
+		return nil
+	}
+	return m.Error
 }
 
 func (m *MockAIClient) GetCommitMessage(ctx context.Context, prompt string) (string, error) {
-	if m.Error != nil {
-		return "", m.Error
+	if err := m.nextError(prompt); err != nil {
+		return "", err
+	}
+	if m.WaitForCancel {
+		<-ctx.Done()
+		return "", ctx.Err()
 	}
+	m.reportUsage(ctx)
 	return m.Response, nil
 }
 
 func (m *MockAIClient) StreamCommitMessage(ctx context.Context, prompt string, deltaFunc func(string)) (string, error) {
-	if m.Error != nil {
-		return "", m.Error
+	if err := m.nextError(prompt); err != nil {
+		return "", err
 	}
 
+	for _, r := range m.Reasoning {
+		ai.ReportReasoning(ctx, r)
+	}
 	full := ""
 	for _, delta := range m.Deltas {
 		deltaFunc(delta)
 		full += delta
 	}
+	if m.WaitForCancel {
+		<-ctx.Done()
+		return full, ctx.Err()
+	}
+	if m.StreamError != nil {
+		return full, m.StreamError
+	}
+	m.reportUsage(ctx)
 	return full, nil
 }
 
+func (m *MockAIClient) ListModels(ctx context.Context) ([]string, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.Models, nil
+}
+
+func (m *MockAIClient) reportUsage(ctx context.Context) {
+	if m.Usage != nil {
+		ai.ReportUsage(ctx, *m.Usage)
+	}
+}
+
 // NewMockAIClient 创建新的 Mock AI Client
 func NewMockAIClient(response string, deltas []string) *MockAIClient {
 	return &MockAIClient{
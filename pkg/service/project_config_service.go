@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/WQGroup/logger"
+	aicommitconfig "github.com/allanpk716/ai-commit-hub/pkg/aicommit/config"
 	"github.com/allanpk716/ai-commit-hub/pkg/config"
 	"github.com/allanpk716/ai-commit-hub/pkg/models"
 )
@@ -14,6 +15,10 @@ type ProjectAIConfig struct {
 	Language  string
 	Model     string
 	IsDefault bool // 是否使用默认配置
+	// FallbackProviders 主 Provider 失败时依次尝试的 Provider 列表
+	FallbackProviders []string
+	// GenerationParams 项目级生成参数，nil 表示使用配置文件中各 provider 的 params
+	GenerationParams *aicommitconfig.GenerationParams
 }
 
 // GitProjectRepositoryInterface 定义项目存储库接口
@@ -85,12 +90,20 @@ func (s *ProjectConfigService) GetProjectAIConfig(projectID uint) (*ProjectAICon
 		result.IsDefault = false
 	}
 
+	// 项目未单独配置回退链时使用全局配置
+	if project.FallbackProviders != nil {
+		result.FallbackProviders = project.FallbackProviders
+	} else {
+		result.FallbackProviders = s.config.FallbackProviders
+	}
+	result.GenerationParams = project.GenerationParams
+
 	return result, nil
 }
 
 // isKnownProvider 检查是否是已知的 Provider
 func isKnownProvider(provider string) bool {
-	knownProviders := []string{"openai", "anthropic", "deepseek", "ollama", "google", "phind"}
+	knownProviders := []string{"openai", "anthropic", "deepseek", "ollama", "google", "phind", "azure"}
 	for _, p := range knownProviders {
 		if p == provider {
 			return true
@@ -164,6 +177,8 @@ func (s *ProjectConfigService) ResetProjectToDefaults(projectID uint) error {
 	project.Provider = nil
 	project.Language = nil
 	project.Model = nil
+	project.FallbackProviders = nil
+	project.GenerationParams = nil
 
 	return s.projectRepo.Update(project)
 }
@@ -151,3 +151,41 @@ func TestResetProjectToDefaults(t *testing.T) {
 	assert.Nil(t, updated.Provider)
 	assert.Nil(t, updated.Language)
 }
+
+func TestGetProjectAIConfig_FallbackProviders(t *testing.T) {
+	project := &models.GitProject{
+		ID:         1,
+		Path:       "/test/project",
+		Name:       "Test Project",
+		UseDefault: true,
+	}
+
+	mockRepo := &MockGitProjectRepository{
+		projects: map[uint]*models.GitProject{1: project},
+	}
+
+	cfg := &config.Config{
+		Provider:          "deepseek",
+		Language:          "chinese",
+		FallbackProviders: []string{"openai", "ollama"},
+	}
+
+	svc := NewProjectConfigService(mockRepo, cfg)
+
+	// 未设置项目级回退链时使用全局配置
+	result, err := svc.GetProjectAIConfig(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openai", "ollama"}, result.FallbackProviders)
+
+	// 项目级回退链优先（空列表表示不回退）
+	project.FallbackProviders = []string{}
+	result, err = svc.GetProjectAIConfig(1)
+	require.NoError(t, err)
+	assert.Empty(t, result.FallbackProviders)
+	assert.NotNil(t, result.FallbackProviders)
+
+	project.FallbackProviders = []string{"anthropic"}
+	result, err = svc.GetProjectAIConfig(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anthropic"}, result.FallbackProviders)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/git"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+)
+
+// recentCommitCount 是 prompt 模板中 RecentCommits 包含的最近提交数量
+const recentCommitCount = 5
+
+// commitPromptTemplate 返回 prompts 目录中配置的 commit prompt 模板（prompts.commitMessage），
+// 未配置或文件不存在时使用内置模板
+func (s *CommitService) commitPromptTemplate(cfg *config.Config) string {
+	if cfg.Prompts.CommitMessage == "" {
+		return prompt.DefaultPromptTemplate
+	}
+	dir, err := appConfigDir()
+	if err != nil {
+		logger.Warnf("无法获取配置目录，使用内置 prompt 模板: %v", err)
+		return prompt.DefaultPromptTemplate
+	}
+	tmpl, err := s.configService.ResolvePromptTemplate(dir, cfg.Prompts.CommitMessage)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("读取 prompt 模板失败，使用内置模板: %v", err)
+		}
+		return prompt.DefaultPromptTemplate
+	}
+	logger.Infof("使用 prompt 模板: %s", cfg.Prompts.CommitMessage)
+	return tmpl
+}
+
+// buildPromptData 收集渲染 commit prompt 模板所需的项目信息：项目名、分支、分支名中的 issue key、
+// 变更文件统计和最近的提交标题。读取失败的信息留空，不影响生成。
+func buildPromptData(ctx context.Context, projectPath, diff string) prompt.CommitPromptData {
+	data := prompt.CommitPromptData{ProjectName: filepath.Base(projectPath)}
+
+	if branch, err := git.GetCurrentBranch(ctx, projectPath); err != nil {
+		logger.Debugf("获取当前分支失败: %v", err)
+	} else {
+		data.Branch = branch
+		data.IssueKey = prompt.IssueKeyFromBranch(branch)
+	}
+
+	for _, stat := range git.DiffFileStats(diff) {
+		data.Files = append(data.Files, prompt.FileChange{Path: stat.Path, Status: stat.Status, Added: stat.Added, Deleted: stat.Deleted})
+	}
+
+	subjects, err := git.GetRecentCommitSubjects(ctx, projectPath, recentCommitCount)
+	if err != nil {
+		logger.Debugf("获取最近提交失败: %v", err)
+	}
+	data.RecentCommits = subjects
+	return data
+}
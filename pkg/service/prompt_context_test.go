@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/git"
+	"github.com/allanpk716/ai-commit-hub/pkg/prompt"
+	"github.com/allanpk716/ai-commit-hub/tests/helpers"
+)
+
+func TestBuildPromptData(t *testing.T) {
+	repo := helpers.SetupTestRepoNamed(t, "shop-api")
+	helpers.RunGitCmd(t, repo.Path, "checkout", "-b", "feature/SHOP-12-cart")
+	helpers.WriteFile(t, repo.Path, "cart.go", "package cart\n")
+	helpers.RunGitCmd(t, repo.Path, "add", ".")
+	helpers.RunGitCmd(t, repo.Path, "commit", "-m", "feat(cart): add cart\n\nbody")
+	repo.CreateStagedChange(t, "cart_test.go", "package cart\n\nfunc TestCart() {}\n")
+
+	diff, err := git.GetStagedDiff(context.Background(), repo.Path)
+	require.NoError(t, err)
+	data := buildPromptData(context.Background(), repo.Path, diff)
+
+	assert.Equal(t, "shop-api", data.ProjectName)
+	assert.Equal(t, "feature/SHOP-12-cart", data.Branch)
+	assert.Equal(t, "SHOP-12", data.IssueKey)
+	assert.Equal(t, prompt.FileChanges{{Path: "cart_test.go", Status: "added", Added: 3}}, data.Files)
+	assert.Equal(t, prompt.Lines{"feat(cart): add cart", "init"}, data.RecentCommits)
+}
+
+func TestCommitService_CommitPromptTemplate(t *testing.T) {
+	path := setupConfigHome(t, "provider: openai\n")
+	svc := NewCommitService(context.Background())
+	cfg := &config.Config{Prompts: config.PromptFiles{CommitMessage: "commit-message.txt"}}
+
+	// 文件不存在时使用内置模板
+	assert.Equal(t, prompt.DefaultPromptTemplate, svc.commitPromptTemplate(cfg))
+
+	promptsDir := filepath.Join(filepath.Dir(path), "prompts")
+	require.NoError(t, os.MkdirAll(promptsDir, 0755))
+	legacy := "Write in {LANGUAGE}.{{if .IssueKey}} Refs: {{.IssueKey}}{{end}}\n{DIFF}"
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "commit-message.txt"), []byte(legacy), 0644))
+	tmpl := svc.commitPromptTemplate(cfg)
+	assert.Equal(t, legacy, tmpl)
+
+	cfg.Language = "english"
+	promptText, _ := buildLimitedPrompt(cfg, "+x", tmpl, false, prompt.CommitPromptData{IssueKey: "SHOP-12"}, git.DiffTrimReport{})
+	assert.Equal(t, "Write in english. Refs: SHOP-12\n+x", promptText)
+}
+
+func TestCommitService_GenerateCommit_LegacyTemplateWithBraces(t *testing.T) {
+	client := NewMockAIClient("", []string{"feat: add cart"})
+	registerMockProvider("mock-legacy-template", client)
+	path := setupConfigHome(t, `provider: mock-legacy-template
+providers:
+    mock-legacy-template: {}
+prompts:
+    commitMessage: legacy.txt
+`)
+	// 旧模板中的 {{ 不是 text/template 语法，应按旧方式替换占位符
+	legacy := "Write in {LANGUAGE}, e.g. {{ \"type\": \"feat\" }}\n{DIFF}"
+	promptsDir := filepath.Join(filepath.Dir(path), "prompts")
+	require.NoError(t, os.MkdirAll(promptsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "legacy.txt"), []byte(legacy), 0644))
+
+	repo := helpers.SetupTestRepo(t)
+	repo.CreateStagedChange(t, "cart.go", "package cart\n")
+	svc, recorder := newRecordingCommitService()
+	_, err := svc.GenerateCommitWithOptions(repo.Path, GenerateOptions{Language: "english"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		names := recorder.Names()
+		return len(names) > 0 && (names[len(names)-1] == "commit-complete" || names[len(names)-1] == "commit-error")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	events := recorder.Events()
+	require.Equal(t, "commit-complete", events[len(events)-1].Name, events[len(events)-1].Data)
+	assert.Equal(t, "feat: add cart", events[len(events)-1].Data.(CommitResult).Message)
+	require.Len(t, client.Prompts(), 1)
+	assert.True(t, strings.HasPrefix(client.Prompts()[0], "Write in english, e.g. {{ \"type\": \"feat\" }}\ndiff --git a/cart.go b/cart.go"), client.Prompts()[0])
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+)
+
+// latencyWeight 是新样本在移动平均中的权重
+const latencyWeight = 0.3
+
+// latencyTracker 记录各 provider 成功生成消息的耗时（仅保存在内存中，重启后重新统计）。
+// 竞速中被取消的 provider 以取消前已等待的时间作为耗时下限记录，避免只有获胜者积累样本。
+type latencyTracker struct {
+	mu    sync.Mutex
+	stats map[string]models.ProviderLatency
+}
+
+// providerLatencies 由 CommitService 写入、ConfigService 读取，两者可能是不同的实例，因此使用包级变量
+var providerLatencies = &latencyTracker{stats: make(map[string]models.ProviderLatency)}
+
+func (t *latencyTracker) record(provider string, d time.Duration) {
+	ms := d.Milliseconds()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stat := t.stats[provider]
+	if stat.Samples == 0 {
+		stat.AvgMs = ms
+	} else {
+		stat.AvgMs = int64(float64(stat.AvgMs)*(1-latencyWeight) + float64(ms)*latencyWeight)
+	}
+	stat.LastMs = ms
+	stat.Samples++
+	t.stats[provider] = stat
+}
+
+// recordAtLeast 记录一次只知道下限的耗时（请求在完成前被取消）。
+// 已有的平均耗时不低于 d 时不更新，避免下限拉低真实的统计
+func (t *latencyTracker) recordAtLeast(provider string, d time.Duration) {
+	t.mu.Lock()
+	stat, ok := t.stats[provider]
+	t.mu.Unlock()
+	if ok && stat.AvgMs >= d.Milliseconds() {
+		return
+	}
+	t.record(provider, d)
+}
+
+func (t *latencyTracker) get(provider string) (models.ProviderLatency, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stat, ok := t.stats[provider]
+	return stat, ok
+}
+
+func (t *latencyTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats = make(map[string]models.ProviderLatency)
+}
+
+// rankByLatency 按观测到的平均耗时从快到慢排列有统计数据的 provider，
+// 没有统计数据的 provider 排在其后并保持原有顺序
+func rankByLatency(infos []models.ProviderInfo) {
+	sort.SliceStable(infos, func(i, j int) bool {
+		a, b := infos[i].Latency, infos[j].Latency
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.AvgMs < b.AvgMs
+	})
+}
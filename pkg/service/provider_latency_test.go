@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+)
+
+func TestLatencyTracker_Record(t *testing.T) {
+	tracker := &latencyTracker{stats: make(map[string]models.ProviderLatency)}
+	tracker.record("openai", time.Second)
+	tracker.record("openai", 2*time.Second)
+
+	stat, ok := tracker.get("openai")
+	require.True(t, ok)
+	assert.Equal(t, 2, stat.Samples)
+	assert.Equal(t, int64(2000), stat.LastMs)
+	assert.Equal(t, int64(1300), stat.AvgMs, "新样本按 latencyWeight 计入移动平均")
+
+	_, ok = tracker.get("ollama")
+	assert.False(t, ok)
+}
+
+func TestLatencyTracker_RecordAtLeast(t *testing.T) {
+	tracker := &latencyTracker{stats: make(map[string]models.ProviderLatency)}
+	tracker.recordAtLeast("ollama", 500*time.Millisecond)
+	stat, ok := tracker.get("ollama")
+	require.True(t, ok)
+	assert.Equal(t, int64(500), stat.AvgMs, "没有样本时记录下限")
+
+	tracker.record("ollama", 3*time.Second)
+	tracker.recordAtLeast("ollama", time.Second)
+	stat, _ = tracker.get("ollama")
+	assert.Equal(t, 2, stat.Samples, "平均耗时已超过下限时不更新")
+}
+
+func TestRankByLatency(t *testing.T) {
+	infos := []models.ProviderInfo{
+		{Name: "anthropic"},
+		{Name: "deepseek", Latency: &models.ProviderLatency{AvgMs: 3000}},
+		{Name: "google"},
+		{Name: "openai", Latency: &models.ProviderLatency{AvgMs: 800}},
+	}
+	rankByLatency(infos)
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"openai", "deepseek", "anthropic", "google"}, names)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/WQGroup/logger"
+	"github.com/allanpk716/ai-commit-hub/pkg/aicommit/ai"
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/providererr"
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/registry"
+)
+
+// providerTestPrompt 是连接测试使用的最小请求
+const providerTestPrompt = "Reply with the single word OK."
+
+// providerRequestTimeout 是连接测试和获取模型列表的超时时间
+const providerRequestTimeout = 30 * time.Second
+
+// ProviderTestResult 是 provider 连接测试的结果
+// 失败时 ErrorKind 为 auth / network / model_not_found / quota / server / unknown
+type ProviderTestResult struct {
+	Provider  string `json:"provider"`
+	Success   bool   `json:"success"`
+	LatencyMs int64  `json:"latencyMs"`
+	ErrorKind string `json:"errorKind,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ProviderService 提供 provider 连接测试和模型列表查询
+type ProviderService struct {
+	configService *ConfigService
+}
+
+func NewProviderService() *ProviderService {
+	return &ProviderService{
+		configService: NewConfigService(),
+	}
+}
+
+// TestProvider 使用配置中的设置向 provider 发送一次最小请求，返回耗时或分类后的错误
+func (s *ProviderService) TestProvider(ctx context.Context, name string) (*ProviderTestResult, error) {
+	cfg, err := s.configService.LoadConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	return testProvider(ctx, cfg, name), nil
+}
+
+func testProvider(ctx context.Context, cfg *config.Config, name string) *ProviderTestResult {
+	result := &ProviderTestResult{Provider: name}
+
+	ctx, cancel := context.WithTimeout(ctx, providerRequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	client, err := newProviderClient(ctx, cfg, name)
+	if err == nil {
+		_, err = client.GetCommitMessage(ctx, providerTestPrompt)
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.ErrorKind = string(providererr.Classify(err))
+		result.Error = err.Error()
+		logger.Warnf("Provider '%s' 连接测试失败 (%s): %v", name, result.ErrorKind, err)
+		return result
+	}
+
+	result.Success = true
+	logger.Infof("Provider '%s' 连接测试成功，耗时 %dms", name, result.LatencyMs)
+	return result
+}
+
+// ListModels 查询 provider 可用的模型列表（按名称排序）
+func (s *ProviderService) ListModels(ctx context.Context, name string) ([]string, error) {
+	cfg, err := s.configService.LoadConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	return listModels(ctx, cfg, name)
+}
+
+func listModels(ctx context.Context, cfg *config.Config, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerRequestTimeout)
+	defer cancel()
+
+	client, err := newProviderClient(ctx, cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := client.(ai.ModelLister)
+	if !ok {
+		// 不支持查询模型列表的 provider 返回注册时声明的常用模型
+		if caps, _ := registry.GetCapabilities(name); len(caps.Models) > 0 {
+			return append([]string(nil), caps.Models...), nil
+		}
+		return nil, fmt.Errorf("provider %s 不支持获取模型列表", name)
+	}
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取模型列表失败 (%s): %w", providererr.Classify(err), err)
+	}
+	sort.Strings(models)
+	return models, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestProvider(t *testing.T) {
+	registerMockProvider("mock-test-ok", NewMockAIClient("OK", nil))
+	registerMockProvider("mock-test-auth", NewMockAIClientWithError(errors.New("401 Unauthorized: invalid api key")))
+	cfg := &config.Config{}
+
+	result := testProvider(context.Background(), cfg, "mock-test-ok")
+	assert.True(t, result.Success)
+	assert.Equal(t, "mock-test-ok", result.Provider)
+	assert.Empty(t, result.ErrorKind)
+	assert.GreaterOrEqual(t, result.LatencyMs, int64(0))
+
+	result = testProvider(context.Background(), cfg, "mock-test-auth")
+	assert.False(t, result.Success)
+	assert.Equal(t, "auth", result.ErrorKind)
+	assert.Contains(t, result.Error, "Unauthorized")
+
+	result = testProvider(context.Background(), cfg, "missing-provider")
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "未知的 provider")
+}
+
+func TestListModels(t *testing.T) {
+	client := NewMockAIClient("", nil)
+	client.Models = []string{"model-b", "model-a"}
+	registerMockProvider("mock-models", client)
+
+	models, err := listModels(context.Background(), &config.Config{}, "mock-models")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model-a", "model-b"}, models)
+
+	registerMockProvider("mock-models-quota", NewMockAIClientWithError(errors.New("429 rate limit exceeded")))
+	_, err = listModels(context.Background(), &config.Config{}, "mock-models-quota")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quota")
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+)
+
+// UsageCostSummary 是按项目、provider 和月份汇总的 token 用量与费用
+type UsageCostSummary struct {
+	ProjectID        uint    `json:"projectId"`
+	ProjectName      string  `json:"projectName"`
+	Provider         string  `json:"provider"`
+	Month            string  `json:"month"` // 格式: 2006-01
+	Generations      int     `json:"generations"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	Cost             float64 `json:"cost"` // USD
+	// UnpricedModels 列出价格表中缺失的模型，这些用量未计入 Cost
+	UnpricedModels []string `json:"unpricedModels,omitempty"`
+}
+
+// LookupModelPrice 在价格表中查找模型价格。
+// 先按完整模型名匹配，再尝试去掉前缀（如 "openai/gpt-4o" -> "gpt-4o"、"models/gemini-2.5-flash" -> "gemini-2.5-flash"）。
+func LookupModelPrice(pricing map[string]config.ModelPrice, model string) (config.ModelPrice, bool) {
+	if price, ok := pricing[model]; ok {
+		return price, true
+	}
+	if idx := strings.LastIndex(model, "/"); idx >= 0 {
+		price, ok := pricing[model[idx+1:]]
+		return price, ok
+	}
+	return config.ModelPrice{}, false
+}
+
+// CalculateCost 按价格表（USD / 百万 token）计算费用，模型不在价格表中时返回 false
+func CalculateCost(pricing map[string]config.ModelPrice, model string, promptTokens, completionTokens int) (float64, bool) {
+	price, ok := LookupModelPrice(pricing, model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1_000_000, true
+}
+
+// SummarizeUsage 将带用量的历史记录按项目、provider、月份汇总并计算费用。
+// 费用使用当前价格表计算，修改价格表后历史费用会随之更新。
+// 结果按月份倒序、项目 ID、provider 排序。
+func SummarizeUsage(histories []models.CommitHistory, pricing map[string]config.ModelPrice) []UsageCostSummary {
+	type key struct {
+		projectID uint
+		provider  string
+		month     string
+	}
+
+	summaries := make(map[key]*UsageCostSummary)
+	for _, h := range histories {
+		if h.PromptTokens == 0 && h.CompletionTokens == 0 {
+			continue
+		}
+
+		k := key{projectID: h.ProjectID, provider: h.Provider, month: h.CreatedAt.Format("2006-01")}
+		summary, ok := summaries[k]
+		if !ok {
+			summary = &UsageCostSummary{
+				ProjectID:   h.ProjectID,
+				ProjectName: h.Project.Name,
+				Provider:    h.Provider,
+				Month:       k.month,
+			}
+			summaries[k] = summary
+		}
+
+		summary.Generations++
+		summary.PromptTokens += h.PromptTokens
+		summary.CompletionTokens += h.CompletionTokens
+
+		if cost, ok := CalculateCost(pricing, h.Model, h.PromptTokens, h.CompletionTokens); ok {
+			summary.Cost += cost
+		} else if !containsString(summary.UnpricedModels, h.Model) {
+			summary.UnpricedModels = append(summary.UnpricedModels, h.Model)
+		}
+	}
+
+	result := make([]UsageCostSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Month != result[j].Month {
+			return result[i].Month > result[j].Month
+		}
+		if result[i].ProjectID != result[j].ProjectID {
+			return result[i].ProjectID < result[j].ProjectID
+		}
+		return result[i].Provider < result[j].Provider
+	})
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
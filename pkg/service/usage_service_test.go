@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/config"
+	"github.com/allanpk716/ai-commit-hub/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateCost(t *testing.T) {
+	pricing := map[string]config.ModelPrice{
+		"gpt-4o": {Input: 2.5, Output: 10},
+	}
+
+	cost, ok := CalculateCost(pricing, "gpt-4o", 1_000_000, 500_000)
+	assert.True(t, ok)
+	assert.InDelta(t, 7.5, cost, 1e-9)
+
+	cost, ok = CalculateCost(pricing, "openai/gpt-4o", 1000, 0)
+	assert.True(t, ok, "带前缀的模型名应回退匹配")
+	assert.InDelta(t, 0.0025, cost, 1e-9)
+
+	_, ok = CalculateCost(pricing, "unknown-model", 1000, 1000)
+	assert.False(t, ok)
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	pricing := map[string]config.ModelPrice{
+		"gpt-4o-mini": {Input: 0.15, Output: 0.6},
+	}
+	jan := time.Date(2026, 1, 10, 0, 0, 0, 0, time.Local)
+	feb := time.Date(2026, 2, 3, 0, 0, 0, 0, time.Local)
+	project := models.GitProject{Name: "demo"}
+
+	histories := []models.CommitHistory{
+		{ProjectID: 1, Project: project, Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 1_000_000, CompletionTokens: 0, CreatedAt: jan},
+		{ProjectID: 1, Project: project, Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 0, CompletionTokens: 1_000_000, CreatedAt: jan},
+		{ProjectID: 1, Project: project, Provider: "ollama", Model: "llama3", PromptTokens: 500, CompletionTokens: 50, CreatedAt: jan},
+		{ProjectID: 1, Project: project, Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 2_000_000, CreatedAt: feb},
+		{ProjectID: 2, Provider: "openai", Model: "gpt-4o-mini", CreatedAt: feb}, // 无用量，不计入
+	}
+
+	summaries := SummarizeUsage(histories, pricing)
+	require.Len(t, summaries, 3)
+
+	assert.Equal(t, "2026-02", summaries[0].Month)
+	assert.InDelta(t, 0.3, summaries[0].Cost, 1e-9)
+
+	assert.Equal(t, "2026-01", summaries[1].Month)
+	assert.Equal(t, "ollama", summaries[1].Provider)
+	assert.Equal(t, []string{"llama3"}, summaries[1].UnpricedModels)
+	assert.Zero(t, summaries[1].Cost)
+
+	assert.Equal(t, "openai", summaries[2].Provider)
+	assert.Equal(t, "demo", summaries[2].ProjectName)
+	assert.Equal(t, 2, summaries[2].Generations)
+	assert.InDelta(t, 0.75, summaries[2].Cost, 1e-9)
+}
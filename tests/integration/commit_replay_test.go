@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allanpk716/ai-commit-hub/pkg/provider/replay"
+	"github.com/allanpk716/ai-commit-hub/pkg/service"
+	"github.com/allanpk716/ai-commit-hub/tests/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitEvents 记录 CommitService 发送的事件
+type commitEvents struct {
+	mu     sync.Mutex
+	names  []string
+	deltas []string
+	result *service.CommitResult
+}
+
+func (e *commitEvents) emit(ctx context.Context, eventName string, data ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.names = append(e.names, eventName)
+	switch ev := data[0].(type) {
+	case service.CommitDeltaEvent:
+		e.deltas = append(e.deltas, ev.Delta)
+	case service.CommitResult:
+		e.result = &ev
+	}
+}
+
+func (e *commitEvents) snapshot() ([]string, []string, *service.CommitResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.names...), append([]string(nil), e.deltas...), e.result
+}
+
+// setupReplayConfig 在临时 HOME 下写入使用 replay provider 的配置，回放指定的录制文件
+func setupReplayConfig(t *testing.T, cassette string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv(replay.ModeEnv, "")
+	replay.Reset()
+	t.Cleanup(replay.Reset)
+
+	cassettePath, err := filepath.Abs(filepath.Join("testdata", "cassettes", cassette))
+	require.NoError(t, err)
+
+	configDir := filepath.Join(home, ".ai-commit-hub")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	configYAML := fmt.Sprintf(`provider: replay
+language: english
+providers:
+  replay:
+    baseURL: %q
+retry:
+  maxAttempts: 2
+  baseDelay: 10ms
+`, filepath.ToSlash(cassettePath))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(configYAML), 0644))
+}
+
+// generateWithReplay 在有暂存变更的测试仓库上生成 commit 消息，等待生成结束
+func generateWithReplay(t *testing.T) *commitEvents {
+	t.Helper()
+	repo := helpers.SetupTestRepo(t)
+	repo.CreateStagedChange(t, "api.go", "package api\n\nfunc Replay() {}\n")
+
+	events := &commitEvents{}
+	svc := service.NewCommitService(context.Background())
+	svc.SetEmitter(events.emit)
+
+	_, err := svc.GenerateCommitWithOptions(repo.Path, service.GenerateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return !svc.IsGenerating(repo.Path) }, 5*time.Second, 10*time.Millisecond)
+	return events
+}
+
+func TestCommitService_ReplayStreaming(t *testing.T) {
+	setupReplayConfig(t, "commit_stream.json")
+
+	names, deltas, result := generateWithReplay(t).snapshot()
+	assert.Equal(t, []string{"commit-started", "commit-delta", "commit-delta", "commit-delta", "commit-complete"}, names)
+	assert.Equal(t, []string{"feat(api): ", "add replay ", "provider"}, deltas)
+
+	require.NotNil(t, result)
+	assert.Equal(t, "feat(api): add replay provider", result.Message)
+	assert.Equal(t, "replay", result.Provider)
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, 120, result.Usage.PromptTokens)
+}
+
+func TestCommitService_ReplayRetry(t *testing.T) {
+	setupReplayConfig(t, "commit_retry.json")
+
+	names, deltas, result := generateWithReplay(t).snapshot()
+	assert.Equal(t, "commit-retry", names[1], "录制的 429 错误应触发重试: %v", names)
+	assert.Equal(t, "fix: retry after rate limit", strings.Join(deltas, ""))
+	require.NotNil(t, result)
+	assert.Equal(t, "fix: retry after rate limit", result.Message)
+}